@@ -225,6 +225,18 @@ func (c *Child) Reload() error {
 	return c.reload()
 }
 
+// ReloadWithSignal sends the given signal to the child process, instead of the
+// configured reload signal, after the splay. Unlike Reload, it never restarts
+// the process.
+func (c *Child) ReloadWithSignal(s os.Signal) error {
+	c.logger.Printf("[INFO] (child) reloading process with %s", s)
+
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.reloadWith(s)
+}
+
 // Kill sends the kill signal to the child process and waits for successful
 // termination. If no kill signal is defined, the process is killed with the
 // most aggressive kill signal. If the process does not gracefully stop within
@@ -387,12 +399,16 @@ func (c *Child) signal(s os.Signal) error {
 }
 
 func (c *Child) reload() error {
+	return c.reloadWith(c.reloadSignal)
+}
+
+func (c *Child) reloadWith(s os.Signal) error {
 	select {
 	case <-c.stopCh:
 	case <-c.randomSplay():
 	}
 
-	return c.signal(c.reloadSignal)
+	return c.signal(s)
 }
 
 // kill sends the signal to kill the process using the configured signal
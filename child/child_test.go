@@ -304,6 +304,37 @@ func TestReload_noSignal(t *testing.T) {
 	}
 }
 
+func TestReloadWithSignal(t *testing.T) {
+
+	c := testChild(t)
+	c.command = "sh"
+	c.args = []string{"-c", "trap 'echo one' USR1; trap 'echo two; exit' USR2; while true; do sleep 0.2; done"}
+	c.reloadSignal = syscall.SIGUSR1
+
+	out := gatedio.NewByteBuffer()
+	c.stdout = out
+
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	// For some reason bash doesn't start immediately
+	time.Sleep(fileWaitSleepDelay)
+
+	if err := c.ReloadWithSignal(syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give time for the file to flush
+	time.Sleep(fileWaitSleepDelay)
+
+	expected := "two\n"
+	if out.String() != expected {
+		t.Errorf("expected %q to be %q", out.String(), expected)
+	}
+}
+
 func TestReload_noProcess(t *testing.T) {
 
 	c := testChild(t)
@@ -108,6 +108,7 @@ func (cli *CLI) Run(args []string) int {
 	if err != nil {
 		return logError(err, ExitCodeRunnerError)
 	}
+	logging.SetRedact(runner.Redact)
 	go runner.Start()
 
 	// Listen for signals
@@ -154,6 +155,7 @@ func (cli *CLI) Run(args []string) int {
 				if err != nil {
 					return logError(err, ExitCodeRunnerError)
 				}
+				logging.SetRedact(runner.Redact)
 				go runner.Start()
 			case *config.KillSignal:
 				fmt.Fprintf(cli.errStream, "Cleaning up...\n")
@@ -342,6 +344,11 @@ func (cli *CLI) ParseFlags(args []string) (
 		return nil
 	}), "default-right-delimiter", "")
 
+	flags.Var((funcBoolVar)(func(b bool) error {
+		c.RenderDiff.Dry = config.Bool(b)
+		return nil
+	}), "diff", "")
+
 	flags.BoolVar(&dry, "dry", false, "")
 
 	flags.Var((funcVar)(func(s string) error {
@@ -392,6 +399,11 @@ func (cli *CLI) ParseFlags(args []string) (
 		return nil
 	}), "log-level", "")
 
+	flags.Var((funcBoolVar)(func(b bool) error {
+		c.RenderDiff.Log = config.Bool(b)
+		return nil
+	}), "log-diff", "")
+
 	flags.Var((funcVar)(func(s string) error {
 		c.FileLog.LogFilePath = config.String(s)
 		return nil
@@ -422,6 +434,11 @@ func (cli *CLI) ParseFlags(args []string) (
 		return nil
 	}), "once", "")
 
+	flags.Var((funcDurationVar)(func(d time.Duration) error {
+		c.OnceTimeout = d
+		return nil
+	}), "once-timeout", "")
+
 	flags.Var((funcBoolVar)(func(b bool) error {
 		c.ParseOnly = *(config.Bool(b))
 		return nil
@@ -748,6 +765,10 @@ Options:
   -default-right-delimiter
       The default right delimiter for templating
 
+  -diff
+      Print a unified diff against the existing destination instead of the
+      whole generated template in dry mode
+
   -dry
       Print generated templates to stdout instead of rendering
 
@@ -771,6 +792,9 @@ Options:
   -kill-signal=<signal>
       Signal to listen to gracefully terminate the process
 
+  -log-diff
+      Log a unified diff of each rendered template, with secrets redacted
+
   -log-level=<level>
       Set the logging level - values are "debug", "info", "warn", and "err"
 
@@ -781,6 +805,11 @@ Options:
   -once
       Do not run the process as a daemon. This disables wait/quiescence timers.
 
+  -once-timeout=<duration>
+      In once mode, the maximum amount of time to wait for all templates to
+      render. On expiry, the templates that did not render and their missing
+      dependencies are printed as JSON and the process exits non-zero
+
   -parse-only
       Do not process templates. Parse them for structure.
 
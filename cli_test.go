@@ -298,6 +298,26 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"diff",
+			[]string{"-diff"},
+			&config.Config{
+				RenderDiff: &config.DiffConfig{
+					Dry: config.Bool(true),
+				},
+			},
+			false,
+		},
+		{
+			"log_diff",
+			[]string{"-log-diff"},
+			&config.Config{
+				RenderDiff: &config.DiffConfig{
+					Log: config.Bool(true),
+				},
+			},
+			false,
+		},
 		{
 			"exec",
 			[]string{"-exec", "command"},
@@ -758,6 +778,18 @@ func TestCLI_ParseFlags(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"once-timeout",
+			[]string{"-once", "-once-timeout", "30s"},
+			&config.Config{
+				Wait: &config.WaitConfig{
+					Enabled: config.Bool(false),
+				},
+				Once:        true,
+				OnceTimeout: 30 * time.Second,
+			},
+			false,
+		},
 		{
 			"parse-only",
 			[]string{"-parse-only"},
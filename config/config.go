@@ -54,12 +54,27 @@ type Config struct {
 	// DefaultDelims is used to configure the default delimiters for templates
 	DefaultDelims *DefaultDelims `mapstructure:"default_delimiters"`
 
+	// RenderDiff is the configuration for showing diffs of rendered
+	// templates.
+	RenderDiff *DiffConfig `mapstructure:"diff"`
+
 	// Exec is the configuration for exec/supervise mode.
 	Exec *ExecConfig `mapstructure:"exec"`
 
+	// Execs are the named child processes to supervise in exec mode. They are
+	// parsed from multiple or named "exec" blocks.
+	Execs *ExecConfigs `mapstructure:"-"`
+
+	// HTTP is the configuration of the requests of the http template
+	// function.
+	HTTP *HTTPConfig `mapstructure:"http"`
+
 	// KillSignal is the signal to listen for a graceful terminate event.
 	KillSignal *os.Signal `mapstructure:"kill_signal"`
 
+	// Kubernetes is the configuration for connecting to the Kubernetes API.
+	Kubernetes *KubernetesConfig `mapstructure:"kubernetes"`
+
 	// LogLevel is the level with which to log for this config.
 	LogLevel *string `mapstructure:"log_level"`
 
@@ -75,6 +90,9 @@ type Config struct {
 	// this processes PID.
 	PidFile *string `mapstructure:"pid_file"`
 
+	// Plugins are the long-running plugins templates can call.
+	Plugins *PluginConfigs `mapstructure:"plugin"`
+
 	// ReloadSignal is the signal to listen for a reload event.
 	ReloadSignal *os.Signal `mapstructure:"reload_signal"`
 
@@ -98,6 +116,10 @@ type Config struct {
 	// Run once, executing each template exactly once, and exit
 	Once bool
 
+	// OnceTimeout is the maximum amount of time to wait for all templates to
+	// render in once mode. Zero means wait forever.
+	OnceTimeout time.Duration
+
 	// ParseOnly prevents any rendering and only loads the templates for
 	// checking well formedness.
 	ParseOnly bool
@@ -128,18 +150,38 @@ func (c *Config) Copy() *Config {
 		o.DefaultDelims = c.DefaultDelims.Copy()
 	}
 
+	if c.RenderDiff != nil {
+		o.RenderDiff = c.RenderDiff.Copy()
+	}
+
 	if c.Exec != nil {
 		o.Exec = c.Exec.Copy()
 	}
 
+	if c.Execs != nil {
+		o.Execs = c.Execs.Copy()
+	}
+
+	if c.HTTP != nil {
+		o.HTTP = c.HTTP.Copy()
+	}
+
 	o.KillSignal = c.KillSignal
 
+	if c.Kubernetes != nil {
+		o.Kubernetes = c.Kubernetes.Copy()
+	}
+
 	o.LogLevel = c.LogLevel
 
 	o.MaxStale = c.MaxStale
 
 	o.PidFile = c.PidFile
 
+	if c.Plugins != nil {
+		o.Plugins = c.Plugins.Copy()
+	}
+
 	o.ReloadSignal = c.ReloadSignal
 
 	if c.FileLog != nil {
@@ -167,6 +209,7 @@ func (c *Config) Copy() *Config {
 	}
 
 	o.Once = c.Once
+	o.OnceTimeout = c.OnceTimeout
 	o.ParseOnly = c.ParseOnly
 
 	o.BlockQueryWaitTime = c.BlockQueryWaitTime
@@ -202,14 +245,30 @@ func (c *Config) Merge(o *Config) *Config {
 		r.DefaultDelims = r.DefaultDelims.Merge(o.DefaultDelims)
 	}
 
+	if o.RenderDiff != nil {
+		r.RenderDiff = r.RenderDiff.Merge(o.RenderDiff)
+	}
+
 	if o.Exec != nil {
 		r.Exec = r.Exec.Merge(o.Exec)
 	}
 
+	if o.Execs != nil {
+		r.Execs = r.Execs.Merge(o.Execs)
+	}
+
+	if o.HTTP != nil {
+		r.HTTP = r.HTTP.Merge(o.HTTP)
+	}
+
 	if o.KillSignal != nil {
 		r.KillSignal = o.KillSignal
 	}
 
+	if o.Kubernetes != nil {
+		r.Kubernetes = r.Kubernetes.Merge(o.Kubernetes)
+	}
+
 	if o.LogLevel != nil {
 		r.LogLevel = o.LogLevel
 	}
@@ -222,6 +281,10 @@ func (c *Config) Merge(o *Config) *Config {
 		r.PidFile = o.PidFile
 	}
 
+	if o.Plugins != nil {
+		r.Plugins = r.Plugins.Merge(o.Plugins)
+	}
+
 	if o.ReloadSignal != nil {
 		r.ReloadSignal = o.ReloadSignal
 	}
@@ -255,6 +318,7 @@ func (c *Config) Merge(o *Config) *Config {
 	}
 
 	r.Once = o.Once
+	r.OnceTimeout = o.OnceTimeout
 	r.ParseOnly = o.ParseOnly
 
 	return r
@@ -273,6 +337,21 @@ func Parse(s string) (*Config, error) {
 		return nil, errors.New("error converting config")
 	}
 
+	// Multiple or named exec blocks configure named child processes. They are
+	// decoded separately below, since "exec" otherwise flattens to one block.
+	var execs []map[string]interface{}
+	if list, ok := parsed["exec"].([]map[string]interface{}); ok && isExecList(list) {
+		execs = list
+		delete(parsed, "exec")
+		for _, e := range execs {
+			flattenKeys(e, []string{
+				"env",
+				"readiness",
+				"restart",
+			})
+		}
+	}
+
 	flattenKeys(parsed, []string{
 		"auth",
 		"consul",
@@ -282,9 +361,16 @@ func Parse(s string) (*Config, error) {
 		"consul.transport",
 		"deduplicate",
 		"default_delimiters",
+		"diff",
 		"env",
 		"exec",
 		"exec.env",
+		"exec.readiness",
+		"exec.restart",
+		"http",
+		"http.headers",
+		"http.ssl",
+		"kubernetes",
 		"log_file",
 		"ssl",
 		"syslog",
@@ -303,6 +389,9 @@ func Parse(s string) (*Config, error) {
 				"env",
 				"exec",
 				"exec.env",
+				"kubernetes",
+				"kubernetes.annotations",
+				"kubernetes.labels",
 				"wait",
 			})
 		}
@@ -333,6 +422,25 @@ func Parse(s string) (*Config, error) {
 		return nil, errors.Wrap(err, "mapstructure decode failed")
 	}
 
+	if execs != nil {
+		c.Execs = &ExecConfigs{}
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				signals.StringToSignalFunc(),
+				mapstructure.StringToSliceHookFunc(","),
+				mapstructure.StringToTimeDurationHookFunc(),
+			),
+			ErrorUnused: true,
+			Result:      c.Execs,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "mapstructure decoder creation failed")
+		}
+		if err := decoder.Decode(execs); err != nil {
+			return nil, errors.Wrap(err, "mapstructure decode failed")
+		}
+	}
+
 	return &c, nil
 }
 
@@ -438,11 +546,16 @@ func (c *Config) GoString() string {
 		"Consul:%#v, "+
 		"Dedup:%#v, "+
 		"DefaultDelims:%#v, "+
+		"RenderDiff:%#v, "+
 		"Exec:%#v, "+
+		"Execs:%#v, "+
+		"HTTP:%#v, "+
 		"KillSignal:%s, "+
+		"Kubernetes:%#v, "+
 		"LogLevel:%s, "+
 		"MaxStale:%s, "+
 		"PidFile:%s, "+
+		"Plugins:%#v, "+
 		"ReloadSignal:%s, "+
 		"FileLog:%#v, "+
 		"Syslog:%#v, "+
@@ -451,16 +564,22 @@ func (c *Config) GoString() string {
 		"Vault:%#v, "+
 		"Wait:%#v, "+
 		"Once:%#v, "+
+		"OnceTimeout:%s, "+
 		"BlockQueryWaitTime:%#v"+
 		"}",
 		c.Consul,
 		c.Dedup,
 		c.DefaultDelims,
+		c.RenderDiff,
 		c.Exec,
+		c.Execs,
+		c.HTTP,
 		SignalGoString(c.KillSignal),
+		c.Kubernetes,
 		StringGoString(c.LogLevel),
 		TimeDurationGoString(c.MaxStale),
 		StringGoString(c.PidFile),
+		c.Plugins,
 		SignalGoString(c.ReloadSignal),
 		c.FileLog,
 		c.Syslog,
@@ -469,6 +588,7 @@ func (c *Config) GoString() string {
 		c.Vault,
 		c.Wait,
 		c.Once,
+		c.OnceTimeout,
 		TimeDurationGoString(c.BlockQueryWaitTime),
 	)
 }
@@ -507,8 +627,13 @@ func DefaultConfig() *Config {
 		Consul:        DefaultConsulConfig(),
 		Dedup:         DefaultDedupConfig(),
 		DefaultDelims: DefaultDefaultDelims(),
+		RenderDiff:    DefaultDiffConfig(),
 		Exec:          DefaultExecConfig(),
+		Execs:         DefaultExecConfigs(),
 		FileLog:       DefaultLogFileConfig(),
+		HTTP:          DefaultHTTPConfig(),
+		Kubernetes:    DefaultKubernetesConfig(),
+		Plugins:       DefaultPluginConfigs(),
 		Syslog:        DefaultSyslogConfig(),
 		Templates:     DefaultTemplateConfigs(),
 		Vault:         DefaultVaultConfig(),
@@ -539,15 +664,35 @@ func (c *Config) Finalize() {
 		c.DefaultDelims = DefaultDefaultDelims()
 	}
 
+	if c.RenderDiff == nil {
+		c.RenderDiff = DefaultDiffConfig()
+	}
+	c.RenderDiff.Finalize()
+
 	if c.Exec == nil {
 		c.Exec = DefaultExecConfig()
 	}
 	c.Exec.Finalize()
 
+	if c.Execs == nil {
+		c.Execs = DefaultExecConfigs()
+	}
+	c.Execs.Finalize()
+
+	if c.HTTP == nil {
+		c.HTTP = DefaultHTTPConfig()
+	}
+	c.HTTP.Finalize()
+
 	if c.KillSignal == nil {
 		c.KillSignal = Signal(DefaultKillSignal)
 	}
 
+	if c.Kubernetes == nil {
+		c.Kubernetes = DefaultKubernetesConfig()
+	}
+	c.Kubernetes.Finalize()
+
 	if c.LogLevel == nil {
 		c.LogLevel = stringFromEnv([]string{
 			"CT_LOG",
@@ -564,6 +709,11 @@ func (c *Config) Finalize() {
 		c.PidFile = String("")
 	}
 
+	if c.Plugins == nil {
+		c.Plugins = DefaultPluginConfigs()
+	}
+	c.Plugins.Finalize()
+
 	if c.ReloadSignal == nil {
 		c.ReloadSignal = Signal(DefaultReloadSignal)
 	}
@@ -652,6 +802,20 @@ func boolFromEnv(list []string, def bool) *bool {
 	return Bool(def)
 }
 
+// isExecList returns true if the given exec blocks configure named child
+// processes rather than the single exec child.
+func isExecList(list []map[string]interface{}) bool {
+	if len(list) > 1 {
+		return true
+	}
+	for _, e := range list {
+		if _, ok := e["name"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // flattenKeys is a function that takes a map[string]interface{} and recursively
 // flattens any keys that are a []map[string]interface{} where the key is in the
 // given list of keys.
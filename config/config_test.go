@@ -331,6 +331,48 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"deduplicate_file_backend",
+			`deduplicate {
+				backend = "file"
+				path    = "/mnt/shared"
+			}`,
+			&Config{
+				Dedup: &DedupConfig{
+					Backend: String("file"),
+					Path:    String("/mnt/shared"),
+				},
+			},
+			false,
+		},
+		{
+			"deduplicate_encryption",
+			`deduplicate {
+				encryption_key_file = "/etc/ct/dedup.key"
+				vault_transit_key   = "ct-dedup"
+				vault_transit_mount = "ct-transit"
+			}`,
+			&Config{
+				Dedup: &DedupConfig{
+					EncryptionKeyFile: String("/etc/ct/dedup.key"),
+					VaultTransitKey:   String("ct-dedup"),
+					VaultTransitMount: String("ct-transit"),
+				},
+			},
+			false,
+		},
+		{
+			"deduplicate_status_file",
+			`deduplicate {
+				status_file = "/var/run/ct-dedup.json"
+			}`,
+			&Config{
+				Dedup: &DedupConfig{
+					StatusFile: String("/var/run/ct-dedup.json"),
+				},
+			},
+			false,
+		},
 		{
 			"default_left_delimiter",
 			`default_delimiters {
@@ -355,6 +397,24 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"diff",
+			`diff {
+				context = 5
+				dry = true
+				log = true
+				redact = false
+			}`,
+			&Config{
+				RenderDiff: &DiffConfig{
+					Context: Int(5),
+					Dry:     Bool(true),
+					Log:     Bool(true),
+					Redact:  Bool(false),
+				},
+			},
+			false,
+		},
 		{
 			"exec",
 			`exec {}`,
@@ -523,6 +583,108 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"exec_readiness",
+			`exec {
+				readiness {
+					http = "http://127.0.0.1:8080/health"
+					timeout = "10s"
+					restore_on_failure = true
+				}
+			}`,
+			&Config{
+				Exec: &ExecConfig{
+					Readiness: &ReadinessConfig{
+						HTTP:             String("http://127.0.0.1:8080/health"),
+						Timeout:          TimeDuration(10 * time.Second),
+						RestoreOnFailure: Bool(true),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"exec_restart",
+			`exec {
+				restart {
+					policy = "on-failure"
+					attempts = 3
+					window = "10m"
+					backoff = "2s"
+					max_backoff = "30s"
+				}
+			}`,
+			&Config{
+				Exec: &ExecConfig{
+					Restart: &RestartConfig{
+						Policy:     String(RestartPolicyOnFailure),
+						Attempts:   Int(3),
+						Window:     TimeDuration(10 * time.Minute),
+						Backoff:    TimeDuration(2 * time.Second),
+						MaxBackoff: TimeDuration(30 * time.Second),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"exec_named",
+			`exec {
+				name = "web"
+				command = "web"
+				templates = ["/tmp/web.conf"]
+				env {
+					custom = ["a=b"]
+				}
+			}
+			exec {
+				name = "worker"
+				command = "worker"
+				critical = false
+			}`,
+			&Config{
+				Execs: &ExecConfigs{
+					&ExecConfig{
+						Name:      String("web"),
+						Command:   []string{"web"},
+						Templates: []string{"/tmp/web.conf"},
+						Env: &EnvConfig{
+							Custom: []string{"a=b"},
+						},
+					},
+					&ExecConfig{
+						Name:     String("worker"),
+						Command:  []string{"worker"},
+						Critical: Bool(false),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"http",
+			`http {
+				poll_interval = "30s"
+				timeout = "5s"
+				headers {
+					Authorization = "Bearer abcd"
+				}
+				ssl {
+					ca_cert = "ca.pem"
+				}
+			}`,
+			&Config{
+				HTTP: &HTTPConfig{
+					Headers:      map[string]string{"Authorization": "Bearer abcd"},
+					PollInterval: TimeDuration(30 * time.Second),
+					SSL: &SSLConfig{
+						CaCert: String("ca.pem"),
+					},
+					Timeout: TimeDuration(5 * time.Second),
+				},
+			},
+			false,
+		},
 		{
 			"kill_signal",
 			`kill_signal = "SIGUSR1"`,
@@ -531,6 +693,22 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"kubernetes",
+			`kubernetes {
+				kubeconfig = "/home/ct/.kube/config"
+				context = "prod"
+				namespace = "ct"
+			}`,
+			&Config{
+				Kubernetes: &KubernetesConfig{
+					Kubeconfig: String("/home/ct/.kube/config"),
+					Context:    String("prod"),
+					Namespace:  String("ct"),
+				},
+			},
+			false,
+		},
 		{
 			"log_level",
 			`log_level = "WARN"`,
@@ -631,6 +809,36 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"plugin",
+			`plugin {
+				name = "lookup"
+				command = ["lookup", "-serve"]
+				functions = ["lookupValue", "lookupList"]
+				timeout = "5s"
+				cache_ttl = "1m"
+			}
+			plugin {
+				name = "other"
+				command = "other-plugin"
+			}`,
+			&Config{
+				Plugins: &PluginConfigs{
+					&PluginConfig{
+						Name:      String("lookup"),
+						Command:   []string{"lookup", "-serve"},
+						Functions: []string{"lookupValue", "lookupList"},
+						Timeout:   TimeDuration(5 * time.Second),
+						CacheTTL:  TimeDuration(1 * time.Minute),
+					},
+					&PluginConfig{
+						Name:    String("other"),
+						Command: []string{"other-plugin"},
+					},
+				},
+			},
+			false,
+		},
 		{
 			"reload_signal",
 			`reload_signal = "SIGUSR1"`,
@@ -775,6 +983,20 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"template_destination_type",
+			`template {
+				destination_type = "consul-kv"
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						DestinationType: String(DestinationTypeConsulKV),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"template_error_on_missing_key",
 			`template {
@@ -1043,6 +1265,90 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"template_exec_reload_signal_per_template",
+			`template {
+				exec_reload_signal = "SIGUSR2"
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						ExecReloadSignal: Signal(syscall.SIGUSR2),
+					},
+				},
+			},
+			false,
+		},
+		{
+			"exec_restart_strategy",
+			`exec {
+				restart_strategy = "overlap"
+			}`,
+			&Config{
+				Exec: &ExecConfig{
+					RestartStrategy: String(ExecRestartStrategyOverlap),
+				},
+			},
+			false,
+		},
+		{
+			"template_kubernetes",
+			`template {
+				destination_type = "kubernetes-secret"
+				kubernetes {
+					key = "app.conf"
+					labels {
+						app = "web"
+					}
+					annotations {
+						team = "infra"
+					}
+					owner_reference {
+						api_version = "apps/v1"
+						kind = "Deployment"
+						name = "web"
+						uid = "1234"
+						controller = true
+					}
+				}
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						DestinationType: String(DestinationTypeKubernetesSecret),
+						Kubernetes: &KubernetesObjectConfig{
+							Key:         String("app.conf"),
+							Labels:      map[string]string{"app": "web"},
+							Annotations: map[string]string{"team": "infra"},
+							OwnerReferences: []*KubernetesOwnerReference{
+								{
+									APIVersion: String("apps/v1"),
+									Kind:       String("Deployment"),
+									Name:       String("web"),
+									UID:        String("1234"),
+									Controller: Bool(true),
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_exec_diff_file",
+			`template {
+				exec_diff_file = true
+			 }`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						ExecDiffFile: Bool(true),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"template_exec_splay",
 			`template {
@@ -1094,6 +1400,34 @@ func TestParse(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"template_function_denylist_empty",
+			`template {
+				function_denylist = []
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						FunctionDenylist: []string{},
+					},
+				},
+			},
+			false,
+		},
+		{
+			"template_sprig",
+			`template {
+				sprig = true
+			}`,
+			&Config{
+				Templates: &TemplateConfigs{
+					&TemplateConfig{
+						Sprig: Bool(true),
+					},
+				},
+			},
+			false,
+		},
 		{
 			"template_uid",
 			`template {
@@ -2052,6 +2386,30 @@ func TestConfig_Merge(t *testing.T) {
 				PidFile: String("pid_file-diff"),
 			},
 		},
+		{
+			"plugins",
+			&Config{
+				Plugins: &PluginConfigs{
+					&PluginConfig{Name: String("a"), Command: []string{"a"}},
+				},
+			},
+			&Config{
+				Plugins: &PluginConfigs{
+					&PluginConfig{Name: String("a"), Timeout: TimeDuration(5 * time.Second)},
+					&PluginConfig{Name: String("b")},
+				},
+			},
+			&Config{
+				Plugins: &PluginConfigs{
+					&PluginConfig{
+						Name:    String("a"),
+						Command: []string{"a"},
+						Timeout: TimeDuration(5 * time.Second),
+					},
+					&PluginConfig{Name: String("b")},
+				},
+			},
+		},
 		{
 			"reload_signal",
 			&Config{
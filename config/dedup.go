@@ -6,6 +6,17 @@ import (
 )
 
 const (
+	// DedupBackendConsul is the de-duplication backend that uses Consul
+	// sessions for leader election and the Consul KV store for sharing data.
+	DedupBackendConsul = "consul"
+
+	// DedupBackendFile is the de-duplication backend that uses lock and data
+	// files on a filesystem shared between all instances.
+	DedupBackendFile = "file"
+
+	// DefaultDedupBackend is the default backend for deduplication mode.
+	DefaultDedupBackend = DedupBackendConsul
+
 	// DefaultDedupPrefix is the default prefix used for deduplication mode.
 	DefaultDedupPrefix = "consul-template/dedup/"
 
@@ -18,24 +29,54 @@ const (
 
 	// DefaultDedupBlockQueryWaitTime is the default amount of time to do a blocking query for the deduplication
 	DefaultDedupBlockQueryWaitTime = 60 * time.Second
+
+	// DefaultDedupVaultTransitMount is the default mount path of the Vault
+	// Transit secrets engine used to encrypt shared data.
+	DefaultDedupVaultTransitMount = "transit"
 )
 
 // DedupConfig is used to enable the de-duplication mode, which depends
 // on electing a leader per-template and watching of a key. This is used
 // to reduce the cost of many instances of CT running the same template.
 type DedupConfig struct {
+	// Backend is the storage used for leader election and for sharing data
+	// between instances. Defaults to DefaultDedupBackend.
+	Backend *string `mapstructure:"backend"`
+
 	// Controls if deduplication mode is enabled
 	Enabled *bool `mapstructure:"enabled"`
 
+	// EncryptionKeyFile is the path to a file holding a 32-byte key, raw or
+	// base64 encoded, used to encrypt the shared data with AES-256-GCM. Every
+	// instance must use the same key.
+	EncryptionKeyFile *string `mapstructure:"encryption_key_file"`
+
 	// MaxStale is the maximum amount of time to allow for stale queries.
 	MaxStale *time.Duration `mapstructure:"max_stale"`
 
+	// Path is the directory on a shared filesystem where the file backend
+	// keeps its lock and data files. It is only used by the file backend.
+	Path *string `mapstructure:"path"`
+
 	// Controls the KV prefix used. Defaults to defaultDedupPrefix
 	Prefix *string `mapstructure:"prefix"`
 
+	// StatusFile is the path to a file where the leadership state, last data
+	// update and lock failures of each template are written as JSON whenever
+	// they change.
+	StatusFile *string `mapstructure:"status_file"`
+
 	// TTL is the Session TTL used for lock acquisition, defaults to 15 seconds.
 	TTL *time.Duration `mapstructure:"ttl"`
 
+	// VaultTransitKey is the name of a Vault Transit key used to encrypt the
+	// shared data. It cannot be combined with EncryptionKeyFile.
+	VaultTransitKey *string `mapstructure:"vault_transit_key"`
+
+	// VaultTransitMount is the mount path of the Vault Transit secrets engine.
+	// Defaults to DefaultDedupVaultTransitMount.
+	VaultTransitMount *string `mapstructure:"vault_transit_mount"`
+
 	// BlockQueryWaitTime is amount of time to do a blocking query for, defaults to 60 seconds.
 	BlockQueryWaitTime *time.Duration `mapstructure:"block_query_wait"`
 }
@@ -53,10 +94,16 @@ func (c *DedupConfig) Copy() *DedupConfig {
 	}
 
 	var o DedupConfig
+	o.Backend = c.Backend
 	o.Enabled = c.Enabled
+	o.EncryptionKeyFile = c.EncryptionKeyFile
 	o.MaxStale = c.MaxStale
+	o.Path = c.Path
 	o.Prefix = c.Prefix
+	o.StatusFile = c.StatusFile
 	o.TTL = c.TTL
+	o.VaultTransitKey = c.VaultTransitKey
+	o.VaultTransitMount = c.VaultTransitMount
 	o.BlockQueryWaitTime = c.BlockQueryWaitTime
 	return &o
 }
@@ -79,22 +126,46 @@ func (c *DedupConfig) Merge(o *DedupConfig) *DedupConfig {
 
 	r := c.Copy()
 
+	if o.Backend != nil {
+		r.Backend = o.Backend
+	}
+
 	if o.Enabled != nil {
 		r.Enabled = o.Enabled
 	}
 
+	if o.EncryptionKeyFile != nil {
+		r.EncryptionKeyFile = o.EncryptionKeyFile
+	}
+
 	if o.MaxStale != nil {
 		r.MaxStale = o.MaxStale
 	}
 
+	if o.Path != nil {
+		r.Path = o.Path
+	}
+
 	if o.Prefix != nil {
 		r.Prefix = o.Prefix
 	}
 
+	if o.StatusFile != nil {
+		r.StatusFile = o.StatusFile
+	}
+
 	if o.TTL != nil {
 		r.TTL = o.TTL
 	}
 
+	if o.VaultTransitKey != nil {
+		r.VaultTransitKey = o.VaultTransitKey
+	}
+
+	if o.VaultTransitMount != nil {
+		r.VaultTransitMount = o.VaultTransitMount
+	}
+
 	if o.BlockQueryWaitTime != nil {
 		r.BlockQueryWaitTime = o.BlockQueryWaitTime
 	}
@@ -106,24 +177,53 @@ func (c *DedupConfig) Merge(o *DedupConfig) *DedupConfig {
 func (c *DedupConfig) Finalize() {
 	if c.Enabled == nil {
 		c.Enabled = Bool(false ||
+			StringPresent(c.Backend) ||
+			StringPresent(c.EncryptionKeyFile) ||
 			TimeDurationPresent(c.MaxStale) ||
+			StringPresent(c.Path) ||
 			StringPresent(c.Prefix) ||
+			StringPresent(c.StatusFile) ||
 			TimeDurationPresent(c.TTL) ||
+			StringPresent(c.VaultTransitKey) ||
 			TimeDurationPresent(c.BlockQueryWaitTime))
 	}
 
+	if c.Backend == nil {
+		c.Backend = String(DefaultDedupBackend)
+	}
+
+	if c.EncryptionKeyFile == nil {
+		c.EncryptionKeyFile = String("")
+	}
+
 	if c.MaxStale == nil {
 		c.MaxStale = TimeDuration(DefaultDedupMaxStale)
 	}
 
+	if c.Path == nil {
+		c.Path = String("")
+	}
+
 	if c.Prefix == nil {
 		c.Prefix = String(DefaultDedupPrefix)
 	}
 
+	if c.StatusFile == nil {
+		c.StatusFile = String("")
+	}
+
 	if c.TTL == nil {
 		c.TTL = TimeDuration(DefaultDedupTTL)
 	}
 
+	if c.VaultTransitKey == nil {
+		c.VaultTransitKey = String("")
+	}
+
+	if c.VaultTransitMount == nil {
+		c.VaultTransitMount = String(DefaultDedupVaultTransitMount)
+	}
+
 	if c.BlockQueryWaitTime == nil {
 		c.BlockQueryWaitTime = TimeDuration(DefaultDedupBlockQueryWaitTime)
 	}
@@ -135,16 +235,28 @@ func (c *DedupConfig) GoString() string {
 		return "(*DedupConfig)(nil)"
 	}
 	return fmt.Sprintf("&DedupConfig{"+
+		"Backend:%s, "+
 		"Enabled:%s, "+
+		"EncryptionKeyFile:%s, "+
 		"MaxStale:%s, "+
+		"Path:%s, "+
 		"Prefix:%s, "+
+		"StatusFile:%s, "+
 		"TTL:%s, "+
+		"VaultTransitKey:%s, "+
+		"VaultTransitMount:%s, "+
 		"BlockQueryWaitTime:%s"+
 		"}",
+		StringGoString(c.Backend),
 		BoolGoString(c.Enabled),
+		StringGoString(c.EncryptionKeyFile),
 		TimeDurationGoString(c.MaxStale),
+		StringGoString(c.Path),
 		StringGoString(c.Prefix),
+		StringGoString(c.StatusFile),
 		TimeDurationGoString(c.TTL),
+		StringGoString(c.VaultTransitKey),
+		StringGoString(c.VaultTransitMount),
 		TimeDurationGoString(c.BlockQueryWaitTime),
 	)
 }
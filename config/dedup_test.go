@@ -24,10 +24,16 @@ func TestDedupConfig_Copy(t *testing.T) {
 		{
 			"copy",
 			&DedupConfig{
-				Enabled:  Bool(true),
-				MaxStale: TimeDuration(30 * time.Second),
-				Prefix:   String("prefix"),
-				TTL:      TimeDuration(10 * time.Second),
+				Backend:           String(DedupBackendFile),
+				Enabled:           Bool(true),
+				EncryptionKeyFile: String("/etc/ct/dedup.key"),
+				MaxStale:          TimeDuration(30 * time.Second),
+				Path:              String("/mnt/shared"),
+				Prefix:            String("prefix"),
+				StatusFile:        String("/tmp/dedup.json"),
+				TTL:               TimeDuration(10 * time.Second),
+				VaultTransitKey:   String("ct-dedup"),
+				VaultTransitMount: String("transit"),
 			},
 		},
 	}
@@ -74,6 +80,24 @@ func TestDedupConfig_Merge(t *testing.T) {
 			&DedupConfig{},
 			&DedupConfig{},
 		},
+		{
+			"backend_overrides",
+			&DedupConfig{Backend: String(DedupBackendConsul)},
+			&DedupConfig{Backend: String(DedupBackendFile)},
+			&DedupConfig{Backend: String(DedupBackendFile)},
+		},
+		{
+			"backend_empty_one",
+			&DedupConfig{Backend: String(DedupBackendFile)},
+			&DedupConfig{},
+			&DedupConfig{Backend: String(DedupBackendFile)},
+		},
+		{
+			"backend_empty_two",
+			&DedupConfig{},
+			&DedupConfig{Backend: String(DedupBackendFile)},
+			&DedupConfig{Backend: String(DedupBackendFile)},
+		},
 		{
 			"enabled_overrides",
 			&DedupConfig{Enabled: Bool(true)},
@@ -98,6 +122,24 @@ func TestDedupConfig_Merge(t *testing.T) {
 			&DedupConfig{Enabled: Bool(true)},
 			&DedupConfig{Enabled: Bool(true)},
 		},
+		{
+			"encryption_key_file_overrides",
+			&DedupConfig{EncryptionKeyFile: String("/a")},
+			&DedupConfig{EncryptionKeyFile: String("/b")},
+			&DedupConfig{EncryptionKeyFile: String("/b")},
+		},
+		{
+			"encryption_key_file_empty_one",
+			&DedupConfig{EncryptionKeyFile: String("/a")},
+			&DedupConfig{},
+			&DedupConfig{EncryptionKeyFile: String("/a")},
+		},
+		{
+			"encryption_key_file_empty_two",
+			&DedupConfig{},
+			&DedupConfig{EncryptionKeyFile: String("/a")},
+			&DedupConfig{EncryptionKeyFile: String("/a")},
+		},
 		{
 			"max_stale_overrides",
 			&DedupConfig{MaxStale: TimeDuration(10 * time.Second)},
@@ -122,6 +164,24 @@ func TestDedupConfig_Merge(t *testing.T) {
 			&DedupConfig{MaxStale: TimeDuration(10 * time.Second)},
 			&DedupConfig{MaxStale: TimeDuration(10 * time.Second)},
 		},
+		{
+			"path_overrides",
+			&DedupConfig{Path: String("/a")},
+			&DedupConfig{Path: String("/b")},
+			&DedupConfig{Path: String("/b")},
+		},
+		{
+			"path_empty_one",
+			&DedupConfig{Path: String("/a")},
+			&DedupConfig{},
+			&DedupConfig{Path: String("/a")},
+		},
+		{
+			"path_empty_two",
+			&DedupConfig{},
+			&DedupConfig{Path: String("/a")},
+			&DedupConfig{Path: String("/a")},
+		},
 		{
 			"prefix_overrides",
 			&DedupConfig{Prefix: String("prefix")},
@@ -146,6 +206,24 @@ func TestDedupConfig_Merge(t *testing.T) {
 			&DedupConfig{Prefix: String("prefix")},
 			&DedupConfig{Prefix: String("prefix")},
 		},
+		{
+			"status_file_overrides",
+			&DedupConfig{StatusFile: String("/a")},
+			&DedupConfig{StatusFile: String("/b")},
+			&DedupConfig{StatusFile: String("/b")},
+		},
+		{
+			"status_file_empty_one",
+			&DedupConfig{StatusFile: String("/a")},
+			&DedupConfig{},
+			&DedupConfig{StatusFile: String("/a")},
+		},
+		{
+			"status_file_empty_two",
+			&DedupConfig{},
+			&DedupConfig{StatusFile: String("/a")},
+			&DedupConfig{StatusFile: String("/a")},
+		},
 		{
 			"ttl_overrides",
 			&DedupConfig{TTL: TimeDuration(10 * time.Second)},
@@ -170,6 +248,42 @@ func TestDedupConfig_Merge(t *testing.T) {
 			&DedupConfig{TTL: TimeDuration(10 * time.Second)},
 			&DedupConfig{TTL: TimeDuration(10 * time.Second)},
 		},
+		{
+			"vault_transit_key_overrides",
+			&DedupConfig{VaultTransitKey: String("a")},
+			&DedupConfig{VaultTransitKey: String("b")},
+			&DedupConfig{VaultTransitKey: String("b")},
+		},
+		{
+			"vault_transit_key_empty_one",
+			&DedupConfig{VaultTransitKey: String("a")},
+			&DedupConfig{},
+			&DedupConfig{VaultTransitKey: String("a")},
+		},
+		{
+			"vault_transit_key_empty_two",
+			&DedupConfig{},
+			&DedupConfig{VaultTransitKey: String("a")},
+			&DedupConfig{VaultTransitKey: String("a")},
+		},
+		{
+			"vault_transit_mount_overrides",
+			&DedupConfig{VaultTransitMount: String("a")},
+			&DedupConfig{VaultTransitMount: String("b")},
+			&DedupConfig{VaultTransitMount: String("b")},
+		},
+		{
+			"vault_transit_mount_empty_one",
+			&DedupConfig{VaultTransitMount: String("a")},
+			&DedupConfig{},
+			&DedupConfig{VaultTransitMount: String("a")},
+		},
+		{
+			"vault_transit_mount_empty_two",
+			&DedupConfig{},
+			&DedupConfig{VaultTransitMount: String("a")},
+			&DedupConfig{VaultTransitMount: String("a")},
+		},
 	}
 
 	for i, tc := range cases {
@@ -193,10 +307,16 @@ func TestDedupConfig_Finalize(t *testing.T) {
 			"empty",
 			&DedupConfig{},
 			&DedupConfig{
+				Backend:            String(DefaultDedupBackend),
 				Enabled:            Bool(false),
+				EncryptionKeyFile:  String(""),
 				MaxStale:           TimeDuration(DefaultDedupMaxStale),
+				Path:               String(""),
 				Prefix:             String(DefaultDedupPrefix),
+				StatusFile:         String(""),
 				TTL:                TimeDuration(DefaultDedupTTL),
+				VaultTransitKey:    String(""),
+				VaultTransitMount:  String(DefaultDedupVaultTransitMount),
 				BlockQueryWaitTime: TimeDuration(DefaultDedupBlockQueryWaitTime),
 			},
 		},
@@ -206,10 +326,16 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				MaxStale: TimeDuration(10 * time.Second),
 			},
 			&DedupConfig{
+				Backend:            String(DefaultDedupBackend),
 				Enabled:            Bool(true),
+				EncryptionKeyFile:  String(""),
 				MaxStale:           TimeDuration(10 * time.Second),
+				Path:               String(""),
 				Prefix:             String(DefaultDedupPrefix),
+				StatusFile:         String(""),
 				TTL:                TimeDuration(DefaultDedupTTL),
+				VaultTransitKey:    String(""),
+				VaultTransitMount:  String(DefaultDedupVaultTransitMount),
 				BlockQueryWaitTime: TimeDuration(DefaultDedupBlockQueryWaitTime),
 			},
 		},
@@ -219,10 +345,16 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				Prefix: String("prefix"),
 			},
 			&DedupConfig{
+				Backend:            String(DefaultDedupBackend),
 				Enabled:            Bool(true),
+				EncryptionKeyFile:  String(""),
 				MaxStale:           TimeDuration(DefaultDedupMaxStale),
+				Path:               String(""),
 				Prefix:             String("prefix"),
+				StatusFile:         String(""),
 				TTL:                TimeDuration(DefaultDedupTTL),
+				VaultTransitKey:    String(""),
+				VaultTransitMount:  String(DefaultDedupVaultTransitMount),
 				BlockQueryWaitTime: TimeDuration(DefaultDedupBlockQueryWaitTime),
 			},
 		},
@@ -232,10 +364,74 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				TTL: TimeDuration(10 * time.Second),
 			},
 			&DedupConfig{
+				Backend:            String(DefaultDedupBackend),
 				Enabled:            Bool(true),
+				EncryptionKeyFile:  String(""),
 				MaxStale:           TimeDuration(DefaultDedupMaxStale),
+				Path:               String(""),
 				Prefix:             String(DefaultDedupPrefix),
+				StatusFile:         String(""),
 				TTL:                TimeDuration(10 * time.Second),
+				VaultTransitKey:    String(""),
+				VaultTransitMount:  String(DefaultDedupVaultTransitMount),
+				BlockQueryWaitTime: TimeDuration(DefaultDedupBlockQueryWaitTime),
+			},
+		},
+		{
+			"with_backend",
+			&DedupConfig{
+				Backend: String(DedupBackendFile),
+				Path:    String("/mnt/shared"),
+			},
+			&DedupConfig{
+				Backend:            String(DedupBackendFile),
+				Enabled:            Bool(true),
+				EncryptionKeyFile:  String(""),
+				MaxStale:           TimeDuration(DefaultDedupMaxStale),
+				Path:               String("/mnt/shared"),
+				Prefix:             String(DefaultDedupPrefix),
+				StatusFile:         String(""),
+				TTL:                TimeDuration(DefaultDedupTTL),
+				VaultTransitKey:    String(""),
+				VaultTransitMount:  String(DefaultDedupVaultTransitMount),
+				BlockQueryWaitTime: TimeDuration(DefaultDedupBlockQueryWaitTime),
+			},
+		},
+		{
+			"with_status_file",
+			&DedupConfig{
+				StatusFile: String("/tmp/dedup.json"),
+			},
+			&DedupConfig{
+				Backend:            String(DefaultDedupBackend),
+				Enabled:            Bool(true),
+				EncryptionKeyFile:  String(""),
+				MaxStale:           TimeDuration(DefaultDedupMaxStale),
+				Path:               String(""),
+				Prefix:             String(DefaultDedupPrefix),
+				StatusFile:         String("/tmp/dedup.json"),
+				TTL:                TimeDuration(DefaultDedupTTL),
+				VaultTransitKey:    String(""),
+				VaultTransitMount:  String(DefaultDedupVaultTransitMount),
+				BlockQueryWaitTime: TimeDuration(DefaultDedupBlockQueryWaitTime),
+			},
+		},
+		{
+			"with_vault_transit_key",
+			&DedupConfig{
+				VaultTransitKey: String("ct-dedup"),
+			},
+			&DedupConfig{
+				Backend:            String(DefaultDedupBackend),
+				Enabled:            Bool(true),
+				EncryptionKeyFile:  String(""),
+				MaxStale:           TimeDuration(DefaultDedupMaxStale),
+				Path:               String(""),
+				Prefix:             String(DefaultDedupPrefix),
+				StatusFile:         String(""),
+				TTL:                TimeDuration(DefaultDedupTTL),
+				VaultTransitKey:    String("ct-dedup"),
+				VaultTransitMount:  String(DefaultDedupVaultTransitMount),
 				BlockQueryWaitTime: TimeDuration(DefaultDedupBlockQueryWaitTime),
 			},
 		},
@@ -245,10 +441,16 @@ func TestDedupConfig_Finalize(t *testing.T) {
 				BlockQueryWaitTime: TimeDuration(60 * time.Second),
 			},
 			&DedupConfig{
+				Backend:            String(DefaultDedupBackend),
 				Enabled:            Bool(true),
+				EncryptionKeyFile:  String(""),
 				MaxStale:           TimeDuration(DefaultDedupMaxStale),
+				Path:               String(""),
 				Prefix:             String(DefaultDedupPrefix),
+				StatusFile:         String(""),
 				TTL:                TimeDuration(DefaultDedupTTL),
+				VaultTransitKey:    String(""),
+				VaultTransitMount:  String(DefaultDedupVaultTransitMount),
 				BlockQueryWaitTime: TimeDuration(60 * time.Second),
 			},
 		},
@@ -0,0 +1,126 @@
+package config
+
+import "fmt"
+
+const (
+	// DefaultDiffContext is the default number of unchanged lines shown around
+	// each change in a diff.
+	DefaultDiffContext = 3
+)
+
+// DiffConfig is the configuration for showing unified diffs of the changes to
+// rendered templates.
+type DiffConfig struct {
+	// Context is the number of unchanged lines shown around each change.
+	Context *int `mapstructure:"context"`
+
+	// Dry prints a diff against the existing destination, instead of the whole
+	// rendered template, in dry mode.
+	Dry *bool `mapstructure:"dry"`
+
+	// Log logs a diff of the destination each time a template is rendered.
+	Log *bool `mapstructure:"log"`
+
+	// Redact masks the values of secrets in logged diffs.
+	Redact *bool `mapstructure:"redact"`
+}
+
+// DefaultDiffConfig returns a configuration that is populated with the
+// default values.
+func DefaultDiffConfig() *DiffConfig {
+	return &DiffConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *DiffConfig) Copy() *DiffConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o DiffConfig
+
+	o.Context = c.Context
+
+	o.Dry = c.Dry
+
+	o.Log = c.Log
+
+	o.Redact = c.Redact
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *DiffConfig) Merge(o *DiffConfig) *DiffConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Context != nil {
+		r.Context = o.Context
+	}
+
+	if o.Dry != nil {
+		r.Dry = o.Dry
+	}
+
+	if o.Log != nil {
+		r.Log = o.Log
+	}
+
+	if o.Redact != nil {
+		r.Redact = o.Redact
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *DiffConfig) Finalize() {
+	if c.Context == nil {
+		c.Context = Int(DefaultDiffContext)
+	}
+
+	if c.Dry == nil {
+		c.Dry = Bool(false)
+	}
+
+	if c.Log == nil {
+		c.Log = Bool(false)
+	}
+
+	if c.Redact == nil {
+		c.Redact = Bool(true)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *DiffConfig) GoString() string {
+	if c == nil {
+		return "(*DiffConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&DiffConfig{"+
+		"Context:%s, "+
+		"Dry:%s, "+
+		"Log:%s, "+
+		"Redact:%s"+
+		"}",
+		IntGoString(c.Context),
+		BoolGoString(c.Dry),
+		BoolGoString(c.Log),
+		BoolGoString(c.Redact),
+	)
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDiffConfig_Copy(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *DiffConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&DiffConfig{},
+		},
+		{
+			"same_enabled",
+			&DiffConfig{
+				Context: Int(5),
+				Dry:     Bool(true),
+				Log:     Bool(true),
+				Redact:  Bool(false),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestDiffConfig_Merge(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *DiffConfig
+		b    *DiffConfig
+		r    *DiffConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&DiffConfig{},
+			&DiffConfig{},
+		},
+		{
+			"nil_b",
+			&DiffConfig{},
+			nil,
+			&DiffConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"context_overrides",
+			&DiffConfig{Context: Int(3)},
+			&DiffConfig{Context: Int(0)},
+			&DiffConfig{Context: Int(0)},
+		},
+		{
+			"dry_empty_one",
+			&DiffConfig{Dry: Bool(true)},
+			&DiffConfig{},
+			&DiffConfig{Dry: Bool(true)},
+		},
+		{
+			"log_empty_two",
+			&DiffConfig{},
+			&DiffConfig{Log: Bool(true)},
+			&DiffConfig{Log: Bool(true)},
+		},
+		{
+			"redact_overrides",
+			&DiffConfig{Redact: Bool(true)},
+			&DiffConfig{Redact: Bool(false)},
+			&DiffConfig{Redact: Bool(false)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestDiffConfig_Finalize(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    *DiffConfig
+		r    *DiffConfig
+	}{
+		{
+			"empty",
+			&DiffConfig{},
+			&DiffConfig{
+				Context: Int(DefaultDiffContext),
+				Dry:     Bool(false),
+				Log:     Bool(false),
+				Redact:  Bool(true),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -20,6 +21,18 @@ const (
 	// command to exit. By default, this is disabled, which means the command
 	// is allowed to run for an infinite amount of time.
 	DefaultExecTimeout = 0 * time.Second
+
+	// ExecRestartStrategyStopStart stops the child process and then starts a
+	// new one when it must be restarted to reload its configuration.
+	ExecRestartStrategyStopStart = "stop-start"
+
+	// ExecRestartStrategyOverlap starts a new child process and waits for it
+	// to become ready before stopping the old one when it must be restarted to
+	// reload its configuration.
+	ExecRestartStrategyOverlap = "overlap"
+
+	// DefaultExecRestartStrategy is the default restart strategy.
+	DefaultExecRestartStrategy = ExecRestartStrategyStopStart
 )
 
 var (
@@ -34,6 +47,10 @@ type ExecConfig struct {
 	// Command is the command to execute and watch as a child process.
 	Command commandList `mapstructure:"command"`
 
+	// Critical determines if Consul Template exits when this child process
+	// exits. Defaults to true.
+	Critical *bool `mapstructure:"critical"`
+
 	// Enabled controls if this exec is enabled.
 	Enabled *bool `mapstructure:"enabled"`
 
@@ -47,15 +64,33 @@ type ExecConfig struct {
 	// hard-killing it.
 	KillTimeout *time.Duration `mapstructure:"kill_timeout"`
 
+	// Name identifies this child process when multiple are configured.
+	Name *string `mapstructure:"name"`
+
+	// Readiness is the probe that checks the child process is ready after it
+	// is started or reloaded.
+	Readiness *ReadinessConfig `mapstructure:"readiness"`
+
 	// ReloadSignal is the signal to send to the child process when a template
 	// changes. This tells the child process that templates have
 	ReloadSignal *os.Signal `mapstructure:"reload_signal"`
 
+	// Restart is the policy for restarting the child process when it exits.
+	Restart *RestartConfig `mapstructure:"restart"`
+
+	// RestartStrategy is how the child process is restarted when a template
+	// changes and there is no reload signal: "stop-start" or "overlap".
+	RestartStrategy *string `mapstructure:"restart_strategy"`
+
 	// Splay is the maximum amount of random time to wait to signal or kill the
 	// process. By default this is disabled, but it can be set to low values to
 	// reduce the "thundering herd" problem where all tasks are restarted at once.
 	Splay *time.Duration `mapstructure:"splay"`
 
+	// Templates is the list of template destinations that, when rendered,
+	// reload this child process. By default, any rendered template reloads it.
+	Templates []string `mapstructure:"templates"`
+
 	// Timeout is the maximum amount of time to wait for a command to complete.
 	// By default, this is 0, which means "wait forever".
 	Timeout *time.Duration `mapstructure:"timeout"`
@@ -86,6 +121,8 @@ func (c *ExecConfig) Copy() *ExecConfig {
 
 	o.Command = c.Command
 
+	o.Critical = c.Critical
+
 	o.Enabled = c.Enabled
 
 	if c.Env != nil {
@@ -96,10 +133,26 @@ func (c *ExecConfig) Copy() *ExecConfig {
 
 	o.KillTimeout = c.KillTimeout
 
+	o.Name = c.Name
+
+	if c.Readiness != nil {
+		o.Readiness = c.Readiness.Copy()
+	}
+
 	o.ReloadSignal = c.ReloadSignal
 
+	if c.Restart != nil {
+		o.Restart = c.Restart.Copy()
+	}
+
+	o.RestartStrategy = c.RestartStrategy
+
 	o.Splay = c.Splay
 
+	if c.Templates != nil {
+		o.Templates = append([]string{}, c.Templates...)
+	}
+
 	o.Timeout = c.Timeout
 
 	return &o
@@ -127,6 +180,10 @@ func (c *ExecConfig) Merge(o *ExecConfig) *ExecConfig {
 		r.Command = o.Command
 	}
 
+	if o.Critical != nil {
+		r.Critical = o.Critical
+	}
+
 	if o.Enabled != nil {
 		r.Enabled = o.Enabled
 	}
@@ -143,14 +200,34 @@ func (c *ExecConfig) Merge(o *ExecConfig) *ExecConfig {
 		r.KillTimeout = o.KillTimeout
 	}
 
+	if o.Name != nil {
+		r.Name = o.Name
+	}
+
+	if o.Readiness != nil {
+		r.Readiness = r.Readiness.Merge(o.Readiness)
+	}
+
 	if o.ReloadSignal != nil {
 		r.ReloadSignal = o.ReloadSignal
 	}
 
+	if o.Restart != nil {
+		r.Restart = r.Restart.Merge(o.Restart)
+	}
+
+	if o.RestartStrategy != nil {
+		r.RestartStrategy = o.RestartStrategy
+	}
+
 	if o.Splay != nil {
 		r.Splay = o.Splay
 	}
 
+	if o.Templates != nil {
+		r.Templates = append([]string{}, o.Templates...)
+	}
+
 	if o.Timeout != nil {
 		r.Timeout = o.Timeout
 	}
@@ -168,6 +245,10 @@ func (c *ExecConfig) Finalize() {
 		c.Command = []string{}
 	}
 
+	if c.Critical == nil {
+		c.Critical = Bool(true)
+	}
+
 	if c.Env == nil {
 		c.Env = DefaultEnvConfig()
 	}
@@ -181,14 +262,36 @@ func (c *ExecConfig) Finalize() {
 		c.KillTimeout = TimeDuration(DefaultExecKillTimeout)
 	}
 
+	if c.Name == nil {
+		c.Name = String("")
+	}
+
+	if c.Readiness == nil {
+		c.Readiness = DefaultReadinessConfig()
+	}
+	c.Readiness.Finalize()
+
 	if c.ReloadSignal == nil {
 		c.ReloadSignal = Signal(DefaultExecReloadSignal)
 	}
 
+	if c.Restart == nil {
+		c.Restart = DefaultRestartConfig()
+	}
+	c.Restart.Finalize()
+
+	if c.RestartStrategy == nil {
+		c.RestartStrategy = String(DefaultExecRestartStrategy)
+	}
+
 	if c.Splay == nil {
 		c.Splay = TimeDuration(0 * time.Second)
 	}
 
+	if c.Templates == nil {
+		c.Templates = []string{}
+	}
+
 	if c.Timeout == nil {
 		c.Timeout = TimeDuration(DefaultExecTimeout)
 	}
@@ -202,21 +305,110 @@ func (c *ExecConfig) GoString() string {
 
 	return fmt.Sprintf("&ExecConfig{"+
 		"Command:%s, "+
+		"Critical:%s, "+
 		"Enabled:%s, "+
 		"Env:%#v, "+
 		"KillSignal:%s, "+
 		"KillTimeout:%s, "+
+		"Name:%s, "+
+		"Readiness:%#v, "+
 		"ReloadSignal:%s, "+
+		"Restart:%#v, "+
+		"RestartStrategy:%s, "+
 		"Splay:%s, "+
+		"Templates:%v, "+
 		"Timeout:%s"+
 		"}",
 		c.Command,
+		BoolGoString(c.Critical),
 		BoolGoString(c.Enabled),
 		c.Env,
 		SignalGoString(c.KillSignal),
 		TimeDurationGoString(c.KillTimeout),
+		StringGoString(c.Name),
+		c.Readiness,
 		SignalGoString(c.ReloadSignal),
+		c.Restart,
+		StringGoString(c.RestartStrategy),
 		TimeDurationGoString(c.Splay),
+		c.Templates,
 		TimeDurationGoString(c.Timeout),
 	)
 }
+
+// ExecConfigs is a collection of named ExecConfigs, one per child process.
+type ExecConfigs []*ExecConfig
+
+// DefaultExecConfigs returns a configuration that is populated with the
+// default values.
+func DefaultExecConfigs() *ExecConfigs {
+	return &ExecConfigs{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ExecConfigs) Copy() *ExecConfigs {
+	if c == nil {
+		return nil
+	}
+
+	o := make(ExecConfigs, len(*c))
+	for i, e := range *c {
+		o[i] = e.Copy()
+	}
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Child processes with the same name are merged, others are appended.
+func (c *ExecConfigs) Merge(o *ExecConfigs) *ExecConfigs {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+OUTER:
+	for _, oe := range *o {
+		if name := StringVal(oe.Name); name != "" {
+			for i, re := range *r {
+				if StringVal(re.Name) == name {
+					(*r)[i] = re.Merge(oe)
+					continue OUTER
+				}
+			}
+		}
+		*r = append(*r, oe.Copy())
+	}
+
+	return r
+}
+
+// Finalize ensures the configuration has no nil pointers and sets default
+// values.
+func (c *ExecConfigs) Finalize() {
+	for _, e := range *c {
+		e.Finalize()
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ExecConfigs) GoString() string {
+	if c == nil {
+		return "(*ExecConfigs)(nil)"
+	}
+
+	s := make([]string, len(*c))
+	for i, e := range *c {
+		s[i] = e.GoString()
+	}
+
+	return "{" + strings.Join(s, ", ") + "}"
+}
@@ -26,12 +26,15 @@ func TestExecConfig_Copy(t *testing.T) {
 			"copy",
 			&ExecConfig{
 				Command:      []string{"command"},
+				Critical:     Bool(false),
 				Enabled:      Bool(true),
 				Env:          &EnvConfig{Pristine: Bool(true)},
 				KillSignal:   Signal(syscall.SIGINT),
 				KillTimeout:  TimeDuration(10 * time.Second),
+				Name:         String("web"),
 				ReloadSignal: Signal(syscall.SIGINT),
 				Splay:        TimeDuration(10 * time.Second),
+				Templates:    []string{"/tmp/foo"},
 				Timeout:      TimeDuration(10 * time.Second),
 			},
 		},
@@ -175,6 +178,42 @@ func TestExecConfig_Merge(t *testing.T) {
 			&ExecConfig{KillSignal: Signal(syscall.SIGINT)},
 			&ExecConfig{KillSignal: Signal(syscall.SIGINT)},
 		},
+		{
+			"critical_overrides",
+			&ExecConfig{Critical: Bool(true)},
+			&ExecConfig{Critical: Bool(false)},
+			&ExecConfig{Critical: Bool(false)},
+		},
+		{
+			"critical_empty_one",
+			&ExecConfig{Critical: Bool(false)},
+			&ExecConfig{},
+			&ExecConfig{Critical: Bool(false)},
+		},
+		{
+			"name_overrides",
+			&ExecConfig{Name: String("web")},
+			&ExecConfig{Name: String("worker")},
+			&ExecConfig{Name: String("worker")},
+		},
+		{
+			"name_empty_one",
+			&ExecConfig{Name: String("web")},
+			&ExecConfig{},
+			&ExecConfig{Name: String("web")},
+		},
+		{
+			"templates_overrides",
+			&ExecConfig{Templates: []string{"/tmp/foo"}},
+			&ExecConfig{Templates: []string{"/tmp/bar"}},
+			&ExecConfig{Templates: []string{"/tmp/bar"}},
+		},
+		{
+			"templates_empty_one",
+			&ExecConfig{Templates: []string{"/tmp/foo"}},
+			&ExecConfig{},
+			&ExecConfig{Templates: []string{"/tmp/foo"}},
+		},
 		{
 			"kill_timeout_overrides",
 			&ExecConfig{KillTimeout: TimeDuration(10 * time.Second)},
@@ -223,6 +262,18 @@ func TestExecConfig_Merge(t *testing.T) {
 			&ExecConfig{ReloadSignal: Signal(syscall.SIGINT)},
 			&ExecConfig{ReloadSignal: Signal(syscall.SIGINT)},
 		},
+		{
+			"restart_strategy_overrides",
+			&ExecConfig{RestartStrategy: String(ExecRestartStrategyStopStart)},
+			&ExecConfig{RestartStrategy: String(ExecRestartStrategyOverlap)},
+			&ExecConfig{RestartStrategy: String(ExecRestartStrategyOverlap)},
+		},
+		{
+			"restart_strategy_empty_one",
+			&ExecConfig{RestartStrategy: String(ExecRestartStrategyOverlap)},
+			&ExecConfig{},
+			&ExecConfig{RestartStrategy: String(ExecRestartStrategyOverlap)},
+		},
 		{
 			"splay_overrides",
 			&ExecConfig{Splay: TimeDuration(10 * time.Second)},
@@ -294,8 +345,9 @@ func TestExecConfig_Finalize(t *testing.T) {
 			"empty",
 			&ExecConfig{},
 			&ExecConfig{
-				Command: []string{},
-				Enabled: Bool(false),
+				Command:  []string{},
+				Critical: Bool(true),
+				Enabled:  Bool(false),
 				Env: &EnvConfig{
 					Allowlist:           []string{},
 					AllowlistDeprecated: []string{},
@@ -304,11 +356,30 @@ func TestExecConfig_Finalize(t *testing.T) {
 					Denylist:            []string{},
 					DenylistDeprecated:  []string{},
 				},
-				KillSignal:   Signal(DefaultExecKillSignal),
-				KillTimeout:  TimeDuration(DefaultExecKillTimeout),
+				KillSignal:  Signal(DefaultExecKillSignal),
+				KillTimeout: TimeDuration(DefaultExecKillTimeout),
+				Name:        String(""),
+				Readiness: &ReadinessConfig{
+					Command:          []string{},
+					Enabled:          Bool(false),
+					HTTP:             String(""),
+					Interval:         TimeDuration(DefaultReadinessInterval),
+					RestoreOnFailure: Bool(false),
+					TCP:              String(""),
+					Timeout:          TimeDuration(DefaultReadinessTimeout),
+				},
 				ReloadSignal: Signal(DefaultExecReloadSignal),
-				Splay:        TimeDuration(0 * time.Second),
-				Timeout:      TimeDuration(DefaultExecTimeout),
+				Restart: &RestartConfig{
+					Policy:     String(DefaultRestartPolicy),
+					Attempts:   Int(DefaultRestartAttempts),
+					Window:     TimeDuration(DefaultRestartWindow),
+					Backoff:    TimeDuration(DefaultRestartBackoff),
+					MaxBackoff: TimeDuration(DefaultRestartMaxBackoff),
+				},
+				RestartStrategy: String(DefaultExecRestartStrategy),
+				Splay:           TimeDuration(0 * time.Second),
+				Templates:       []string{},
+				Timeout:         TimeDuration(DefaultExecTimeout),
 			},
 		},
 		{
@@ -317,8 +388,9 @@ func TestExecConfig_Finalize(t *testing.T) {
 				Command: []string{"command"},
 			},
 			&ExecConfig{
-				Command: []string{"command"},
-				Enabled: Bool(true),
+				Command:  []string{"command"},
+				Critical: Bool(true),
+				Enabled:  Bool(true),
 				Env: &EnvConfig{
 					Denylist:            []string{},
 					DenylistDeprecated:  []string{},
@@ -327,11 +399,30 @@ func TestExecConfig_Finalize(t *testing.T) {
 					Allowlist:           []string{},
 					AllowlistDeprecated: []string{},
 				},
-				KillSignal:   Signal(DefaultExecKillSignal),
-				KillTimeout:  TimeDuration(DefaultExecKillTimeout),
+				KillSignal:  Signal(DefaultExecKillSignal),
+				KillTimeout: TimeDuration(DefaultExecKillTimeout),
+				Name:        String(""),
+				Readiness: &ReadinessConfig{
+					Command:          []string{},
+					Enabled:          Bool(false),
+					HTTP:             String(""),
+					Interval:         TimeDuration(DefaultReadinessInterval),
+					RestoreOnFailure: Bool(false),
+					TCP:              String(""),
+					Timeout:          TimeDuration(DefaultReadinessTimeout),
+				},
 				ReloadSignal: Signal(DefaultExecReloadSignal),
-				Splay:        TimeDuration(0 * time.Second),
-				Timeout:      TimeDuration(DefaultExecTimeout),
+				Restart: &RestartConfig{
+					Policy:     String(DefaultRestartPolicy),
+					Attempts:   Int(DefaultRestartAttempts),
+					Window:     TimeDuration(DefaultRestartWindow),
+					Backoff:    TimeDuration(DefaultRestartBackoff),
+					MaxBackoff: TimeDuration(DefaultRestartMaxBackoff),
+				},
+				RestartStrategy: String(DefaultExecRestartStrategy),
+				Splay:           TimeDuration(0 * time.Second),
+				Templates:       []string{},
+				Timeout:         TimeDuration(DefaultExecTimeout),
 			},
 		},
 		{
@@ -340,8 +431,9 @@ func TestExecConfig_Finalize(t *testing.T) {
 				Command: []string{"command", "argument1", "argument2"},
 			},
 			&ExecConfig{
-				Command: []string{"command", "argument1", "argument2"},
-				Enabled: Bool(true),
+				Command:  []string{"command", "argument1", "argument2"},
+				Critical: Bool(true),
+				Enabled:  Bool(true),
 				Env: &EnvConfig{
 					Denylist:            []string{},
 					DenylistDeprecated:  []string{},
@@ -350,11 +442,30 @@ func TestExecConfig_Finalize(t *testing.T) {
 					Allowlist:           []string{},
 					AllowlistDeprecated: []string{},
 				},
-				KillSignal:   Signal(DefaultExecKillSignal),
-				KillTimeout:  TimeDuration(DefaultExecKillTimeout),
+				KillSignal:  Signal(DefaultExecKillSignal),
+				KillTimeout: TimeDuration(DefaultExecKillTimeout),
+				Name:        String(""),
+				Readiness: &ReadinessConfig{
+					Command:          []string{},
+					Enabled:          Bool(false),
+					HTTP:             String(""),
+					Interval:         TimeDuration(DefaultReadinessInterval),
+					RestoreOnFailure: Bool(false),
+					TCP:              String(""),
+					Timeout:          TimeDuration(DefaultReadinessTimeout),
+				},
 				ReloadSignal: Signal(DefaultExecReloadSignal),
-				Splay:        TimeDuration(0 * time.Second),
-				Timeout:      TimeDuration(DefaultExecTimeout),
+				Restart: &RestartConfig{
+					Policy:     String(DefaultRestartPolicy),
+					Attempts:   Int(DefaultRestartAttempts),
+					Window:     TimeDuration(DefaultRestartWindow),
+					Backoff:    TimeDuration(DefaultRestartBackoff),
+					MaxBackoff: TimeDuration(DefaultRestartMaxBackoff),
+				},
+				RestartStrategy: String(DefaultExecRestartStrategy),
+				Splay:           TimeDuration(0 * time.Second),
+				Templates:       []string{},
+				Timeout:         TimeDuration(DefaultExecTimeout),
 			},
 		},
 		{
@@ -363,8 +474,9 @@ func TestExecConfig_Finalize(t *testing.T) {
 				Command: []string{"command | pipe && command"},
 			},
 			&ExecConfig{
-				Command: []string{"command | pipe && command"},
-				Enabled: Bool(true),
+				Command:  []string{"command | pipe && command"},
+				Critical: Bool(true),
+				Enabled:  Bool(true),
 				Env: &EnvConfig{
 					Denylist:            []string{},
 					DenylistDeprecated:  []string{},
@@ -373,11 +485,30 @@ func TestExecConfig_Finalize(t *testing.T) {
 					Allowlist:           []string{},
 					AllowlistDeprecated: []string{},
 				},
-				KillSignal:   Signal(DefaultExecKillSignal),
-				KillTimeout:  TimeDuration(DefaultExecKillTimeout),
+				KillSignal:  Signal(DefaultExecKillSignal),
+				KillTimeout: TimeDuration(DefaultExecKillTimeout),
+				Name:        String(""),
+				Readiness: &ReadinessConfig{
+					Command:          []string{},
+					Enabled:          Bool(false),
+					HTTP:             String(""),
+					Interval:         TimeDuration(DefaultReadinessInterval),
+					RestoreOnFailure: Bool(false),
+					TCP:              String(""),
+					Timeout:          TimeDuration(DefaultReadinessTimeout),
+				},
 				ReloadSignal: Signal(DefaultExecReloadSignal),
-				Splay:        TimeDuration(0 * time.Second),
-				Timeout:      TimeDuration(DefaultExecTimeout),
+				Restart: &RestartConfig{
+					Policy:     String(DefaultRestartPolicy),
+					Attempts:   Int(DefaultRestartAttempts),
+					Window:     TimeDuration(DefaultRestartWindow),
+					Backoff:    TimeDuration(DefaultRestartBackoff),
+					MaxBackoff: TimeDuration(DefaultRestartMaxBackoff),
+				},
+				RestartStrategy: String(DefaultExecRestartStrategy),
+				Splay:           TimeDuration(0 * time.Second),
+				Templates:       []string{},
+				Timeout:         TimeDuration(DefaultExecTimeout),
 			},
 		},
 	}
@@ -391,3 +522,59 @@ func TestExecConfig_Finalize(t *testing.T) {
 		})
 	}
 }
+
+func TestExecConfigs_Merge(t *testing.T) {
+	cases := []struct {
+		name string
+		a    *ExecConfigs
+		b    *ExecConfigs
+		r    *ExecConfigs
+	}{
+		{
+			"nils",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&ExecConfigs{},
+			&ExecConfigs{},
+			&ExecConfigs{},
+		},
+		{
+			"appends",
+			&ExecConfigs{&ExecConfig{Name: String("web")}},
+			&ExecConfigs{&ExecConfig{Name: String("worker")}},
+			&ExecConfigs{
+				&ExecConfig{Name: String("web")},
+				&ExecConfig{Name: String("worker")},
+			},
+		},
+		{
+			"merges_same_name",
+			&ExecConfigs{&ExecConfig{
+				Name:    String("web"),
+				Command: []string{"web"},
+			}},
+			&ExecConfigs{&ExecConfig{
+				Name:     String("web"),
+				Critical: Bool(false),
+			}},
+			&ExecConfigs{&ExecConfig{
+				Name:     String("web"),
+				Command:  []string{"web"},
+				Critical: Bool(false),
+			}},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
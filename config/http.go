@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultHTTPPollInterval is the default amount of time between requests
+	// to the endpoints of the http template function.
+	DefaultHTTPPollInterval = 1 * time.Minute
+
+	// DefaultHTTPTimeout is the default timeout of a request to the endpoints
+	// of the http template function.
+	DefaultHTTPTimeout = 30 * time.Second
+)
+
+// HTTPConfig is the configuration of the requests of the http template
+// function.
+type HTTPConfig struct {
+	// Headers are added to every request, such as an Authorization header.
+	// Headers given to the template function take precedence. Since they may
+	// hold credentials, only their names are printed.
+	Headers map[string]string `mapstructure:"headers" json:"-"`
+
+	// PollInterval is the amount of time between requests to an endpoint. It
+	// can be overridden by the template function.
+	PollInterval *time.Duration `mapstructure:"poll_interval"`
+
+	// SSL is the TLS configuration of the requests to HTTPS endpoints.
+	SSL *SSLConfig `mapstructure:"ssl"`
+
+	// Timeout is the timeout of a request, including reading the response.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultHTTPConfig returns a configuration that is populated with the
+// default values.
+func DefaultHTTPConfig() *HTTPConfig {
+	return &HTTPConfig{
+		SSL: DefaultSSLConfig(),
+	}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *HTTPConfig) Copy() *HTTPConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o HTTPConfig
+
+	if c.Headers != nil {
+		o.Headers = make(map[string]string, len(c.Headers))
+		for k, v := range c.Headers {
+			o.Headers[k] = v
+		}
+	}
+
+	o.PollInterval = c.PollInterval
+
+	if c.SSL != nil {
+		o.SSL = c.SSL.Copy()
+	}
+
+	o.Timeout = c.Timeout
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *HTTPConfig) Merge(o *HTTPConfig) *HTTPConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Headers != nil {
+		if r.Headers == nil {
+			r.Headers = make(map[string]string, len(o.Headers))
+		}
+		for k, v := range o.Headers {
+			r.Headers[k] = v
+		}
+	}
+
+	if o.PollInterval != nil {
+		r.PollInterval = o.PollInterval
+	}
+
+	if o.SSL != nil {
+		r.SSL = r.SSL.Merge(o.SSL)
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *HTTPConfig) Finalize() {
+	if c.Headers == nil {
+		c.Headers = map[string]string{}
+	}
+
+	if c.PollInterval == nil {
+		c.PollInterval = TimeDuration(DefaultHTTPPollInterval)
+	}
+
+	if c.SSL == nil {
+		c.SSL = DefaultSSLConfig()
+	}
+	c.SSL.Finalize()
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultHTTPTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct. Only the names of the
+// headers are printed.
+func (c *HTTPConfig) GoString() string {
+	if c == nil {
+		return "(*HTTPConfig)(nil)"
+	}
+
+	var headers []string
+	for k := range c.Headers {
+		headers = append(headers, k)
+	}
+	sort.Strings(headers)
+
+	return fmt.Sprintf("&HTTPConfig{"+
+		"Headers:%#v, "+
+		"PollInterval:%s, "+
+		"SSL:%#v, "+
+		"Timeout:%s"+
+		"}",
+		headers,
+		TimeDurationGoString(c.PollInterval),
+		c.SSL,
+		TimeDurationGoString(c.Timeout),
+	)
+}
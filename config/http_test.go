@@ -0,0 +1,193 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPConfig_Copy(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *HTTPConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&HTTPConfig{},
+		},
+		{
+			"same_enabled",
+			&HTTPConfig{
+				Headers:      map[string]string{"Authorization": "Bearer abcd"},
+				PollInterval: TimeDuration(30 * time.Second),
+				SSL: &SSLConfig{
+					CaCert: String("ca.pem"),
+				},
+				Timeout: TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestHTTPConfig_Merge(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *HTTPConfig
+		b    *HTTPConfig
+		r    *HTTPConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&HTTPConfig{},
+			&HTTPConfig{},
+		},
+		{
+			"nil_b",
+			&HTTPConfig{},
+			nil,
+			&HTTPConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"headers_merge",
+			&HTTPConfig{Headers: map[string]string{"a": "1", "b": "2"}},
+			&HTTPConfig{Headers: map[string]string{"b": "3"}},
+			&HTTPConfig{Headers: map[string]string{"a": "1", "b": "3"}},
+		},
+		{
+			"poll_interval_overrides",
+			&HTTPConfig{PollInterval: TimeDuration(10 * time.Second)},
+			&HTTPConfig{PollInterval: TimeDuration(20 * time.Second)},
+			&HTTPConfig{PollInterval: TimeDuration(20 * time.Second)},
+		},
+		{
+			"ssl_merges",
+			&HTTPConfig{SSL: &SSLConfig{CaCert: String("ca.pem")}},
+			&HTTPConfig{SSL: &SSLConfig{Verify: Bool(false)}},
+			&HTTPConfig{SSL: &SSLConfig{CaCert: String("ca.pem"), Verify: Bool(false)}},
+		},
+		{
+			"timeout_empty_one",
+			&HTTPConfig{Timeout: TimeDuration(10 * time.Second)},
+			&HTTPConfig{},
+			&HTTPConfig{Timeout: TimeDuration(10 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestHTTPConfig_Finalize(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    *HTTPConfig
+		r    *HTTPConfig
+	}{
+		{
+			"empty",
+			&HTTPConfig{},
+			&HTTPConfig{
+				Headers:      map[string]string{},
+				PollInterval: TimeDuration(DefaultHTTPPollInterval),
+				SSL: &SSLConfig{
+					CaCert:     String(""),
+					CaPath:     String(""),
+					Cert:       String(""),
+					Enabled:    Bool(false),
+					Key:        String(""),
+					ServerName: String(""),
+					Verify:     Bool(DefaultSSLVerify),
+				},
+				Timeout: TimeDuration(DefaultHTTPTimeout),
+			},
+		},
+		{
+			"ssl_enabled",
+			&HTTPConfig{
+				SSL: &SSLConfig{
+					CaCert: String("ca.pem"),
+				},
+			},
+			&HTTPConfig{
+				Headers:      map[string]string{},
+				PollInterval: TimeDuration(DefaultHTTPPollInterval),
+				SSL: &SSLConfig{
+					CaCert:     String("ca.pem"),
+					CaPath:     String(""),
+					Cert:       String(""),
+					Enabled:    Bool(true),
+					Key:        String(""),
+					ServerName: String(""),
+					Verify:     Bool(DefaultSSLVerify),
+				},
+				Timeout: TimeDuration(DefaultHTTPTimeout),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
+
+func TestHTTPConfig_GoString(t *testing.T) {
+	c := &HTTPConfig{
+		Headers: map[string]string{
+			"X-Token":       "secret-token",
+			"Authorization": "Bearer abcd",
+		},
+	}
+
+	s := fmt.Sprintf("%#v", c)
+	if !strings.Contains(s, `Headers:[]string{"Authorization", "X-Token"}`) {
+		t.Errorf("expected header names in %s", s)
+	}
+	if strings.Contains(s, "secret-token") || strings.Contains(s, "abcd") {
+		t.Errorf("expected header values to be hidden in %s", s)
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "secret-token") {
+		t.Errorf("expected headers not to be marshaled in %s", b)
+	}
+}
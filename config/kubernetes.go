@@ -0,0 +1,346 @@
+package config
+
+import "fmt"
+
+const (
+	// DestinationTypeKubernetesSecret renders the template to a key of a
+	// Kubernetes Secret.
+	DestinationTypeKubernetesSecret = "kubernetes-secret"
+
+	// DestinationTypeKubernetesConfigMap renders the template to a key of a
+	// Kubernetes ConfigMap.
+	DestinationTypeKubernetesConfigMap = "kubernetes-configmap"
+
+	// DefaultKubernetesKey is the default key of the Secret or ConfigMap that
+	// templates are rendered to.
+	DefaultKubernetesKey = "contents"
+)
+
+// KubernetesConfig is the configuration for connecting to the Kubernetes API,
+// used by templates that render to Secrets or ConfigMaps.
+type KubernetesConfig struct {
+	// Kubeconfig is the path to the kubeconfig file to use. If it is empty,
+	// the in-cluster configuration of the pod's service account is used.
+	Kubeconfig *string `mapstructure:"kubeconfig"`
+
+	// Context is the kubeconfig context to use instead of its current context.
+	Context *string `mapstructure:"context"`
+
+	// Namespace is the namespace of Secrets and ConfigMaps whose destination
+	// does not include one. If it is empty, the namespace of the kubeconfig
+	// context or of the pod's service account is used.
+	Namespace *string `mapstructure:"namespace"`
+}
+
+// DefaultKubernetesConfig returns a configuration that is populated with the
+// default values.
+func DefaultKubernetesConfig() *KubernetesConfig {
+	return &KubernetesConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *KubernetesConfig) Copy() *KubernetesConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o KubernetesConfig
+
+	o.Kubeconfig = c.Kubeconfig
+
+	o.Context = c.Context
+
+	o.Namespace = c.Namespace
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *KubernetesConfig) Merge(o *KubernetesConfig) *KubernetesConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Kubeconfig != nil {
+		r.Kubeconfig = o.Kubeconfig
+	}
+
+	if o.Context != nil {
+		r.Context = o.Context
+	}
+
+	if o.Namespace != nil {
+		r.Namespace = o.Namespace
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *KubernetesConfig) Finalize() {
+	if c.Kubeconfig == nil {
+		c.Kubeconfig = stringFromEnv([]string{"KUBECONFIG"}, "")
+	}
+
+	if c.Context == nil {
+		c.Context = String("")
+	}
+
+	if c.Namespace == nil {
+		c.Namespace = String("")
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *KubernetesConfig) GoString() string {
+	if c == nil {
+		return "(*KubernetesConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&KubernetesConfig{"+
+		"Kubeconfig:%s, "+
+		"Context:%s, "+
+		"Namespace:%s"+
+		"}",
+		StringGoString(c.Kubeconfig),
+		StringGoString(c.Context),
+		StringGoString(c.Namespace),
+	)
+}
+
+// KubernetesObjectConfig is the configuration of the Secret or ConfigMap that a
+// template renders to.
+type KubernetesObjectConfig struct {
+	// Key is the key of the Secret or ConfigMap data that the template renders
+	// to. Other keys are left alone.
+	Key *string `mapstructure:"key"`
+
+	// Labels and Annotations are set on the Secret or ConfigMap. Other labels
+	// and annotations are left alone.
+	Labels      map[string]string `mapstructure:"labels"`
+	Annotations map[string]string `mapstructure:"annotations"`
+
+	// OwnerReferences replace the owner references of the Secret or ConfigMap
+	// if any are given, so it is garbage collected with its owners.
+	OwnerReferences []*KubernetesOwnerReference `mapstructure:"owner_reference"`
+}
+
+// DefaultKubernetesObjectConfig returns a configuration that is populated with
+// the default values.
+func DefaultKubernetesObjectConfig() *KubernetesObjectConfig {
+	return &KubernetesObjectConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *KubernetesObjectConfig) Copy() *KubernetesObjectConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o KubernetesObjectConfig
+
+	o.Key = c.Key
+
+	if c.Labels != nil {
+		o.Labels = make(map[string]string, len(c.Labels))
+		for k, v := range c.Labels {
+			o.Labels[k] = v
+		}
+	}
+
+	if c.Annotations != nil {
+		o.Annotations = make(map[string]string, len(c.Annotations))
+		for k, v := range c.Annotations {
+			o.Annotations[k] = v
+		}
+	}
+
+	for _, ref := range c.OwnerReferences {
+		o.OwnerReferences = append(o.OwnerReferences, ref.Copy())
+	}
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *KubernetesObjectConfig) Merge(o *KubernetesObjectConfig) *KubernetesObjectConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Key != nil {
+		r.Key = o.Key
+	}
+
+	if o.Labels != nil {
+		if r.Labels == nil {
+			r.Labels = make(map[string]string, len(o.Labels))
+		}
+		for k, v := range o.Labels {
+			r.Labels[k] = v
+		}
+	}
+
+	if o.Annotations != nil {
+		if r.Annotations == nil {
+			r.Annotations = make(map[string]string, len(o.Annotations))
+		}
+		for k, v := range o.Annotations {
+			r.Annotations[k] = v
+		}
+	}
+
+	for _, ref := range o.OwnerReferences {
+		r.OwnerReferences = append(r.OwnerReferences, ref.Copy())
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *KubernetesObjectConfig) Finalize() {
+	if c.Key == nil {
+		c.Key = String(DefaultKubernetesKey)
+	}
+
+	if c.Labels == nil {
+		c.Labels = map[string]string{}
+	}
+
+	if c.Annotations == nil {
+		c.Annotations = map[string]string{}
+	}
+
+	if c.OwnerReferences == nil {
+		c.OwnerReferences = []*KubernetesOwnerReference{}
+	}
+	for _, ref := range c.OwnerReferences {
+		ref.Finalize()
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *KubernetesObjectConfig) GoString() string {
+	if c == nil {
+		return "(*KubernetesObjectConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&KubernetesObjectConfig{"+
+		"Key:%s, "+
+		"Labels:%#v, "+
+		"Annotations:%#v, "+
+		"OwnerReferences:%#v"+
+		"}",
+		StringGoString(c.Key),
+		c.Labels,
+		c.Annotations,
+		c.OwnerReferences,
+	)
+}
+
+// KubernetesOwnerReference is an owner of a Secret or ConfigMap.
+type KubernetesOwnerReference struct {
+	APIVersion         *string `mapstructure:"api_version"`
+	Kind               *string `mapstructure:"kind"`
+	Name               *string `mapstructure:"name"`
+	UID                *string `mapstructure:"uid"`
+	Controller         *bool   `mapstructure:"controller"`
+	BlockOwnerDeletion *bool   `mapstructure:"block_owner_deletion"`
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *KubernetesOwnerReference) Copy() *KubernetesOwnerReference {
+	if c == nil {
+		return nil
+	}
+
+	var o KubernetesOwnerReference
+
+	o.APIVersion = c.APIVersion
+
+	o.Kind = c.Kind
+
+	o.Name = c.Name
+
+	o.UID = c.UID
+
+	o.Controller = c.Controller
+
+	o.BlockOwnerDeletion = c.BlockOwnerDeletion
+
+	return &o
+}
+
+// Finalize ensures there no nil pointers.
+func (c *KubernetesOwnerReference) Finalize() {
+	if c.APIVersion == nil {
+		c.APIVersion = String("")
+	}
+
+	if c.Kind == nil {
+		c.Kind = String("")
+	}
+
+	if c.Name == nil {
+		c.Name = String("")
+	}
+
+	if c.UID == nil {
+		c.UID = String("")
+	}
+
+	if c.Controller == nil {
+		c.Controller = Bool(false)
+	}
+
+	if c.BlockOwnerDeletion == nil {
+		c.BlockOwnerDeletion = Bool(false)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *KubernetesOwnerReference) GoString() string {
+	if c == nil {
+		return "(*KubernetesOwnerReference)(nil)"
+	}
+
+	return fmt.Sprintf("&KubernetesOwnerReference{"+
+		"APIVersion:%s, "+
+		"Kind:%s, "+
+		"Name:%s, "+
+		"UID:%s, "+
+		"Controller:%s, "+
+		"BlockOwnerDeletion:%s"+
+		"}",
+		StringGoString(c.APIVersion),
+		StringGoString(c.Kind),
+		StringGoString(c.Name),
+		StringGoString(c.UID),
+		BoolGoString(c.Controller),
+		BoolGoString(c.BlockOwnerDeletion),
+	)
+}
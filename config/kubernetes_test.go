@@ -0,0 +1,281 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestKubernetesConfig_Copy(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *KubernetesConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&KubernetesConfig{},
+		},
+		{
+			"same_enabled",
+			&KubernetesConfig{
+				Kubeconfig: String("/home/ct/.kube/config"),
+				Context:    String("prod"),
+				Namespace:  String("ct"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestKubernetesConfig_Merge(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *KubernetesConfig
+		b    *KubernetesConfig
+		r    *KubernetesConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&KubernetesConfig{},
+			&KubernetesConfig{},
+		},
+		{
+			"nil_b",
+			&KubernetesConfig{},
+			nil,
+			&KubernetesConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"kubeconfig_overrides",
+			&KubernetesConfig{Kubeconfig: String("a")},
+			&KubernetesConfig{Kubeconfig: String("")},
+			&KubernetesConfig{Kubeconfig: String("")},
+		},
+		{
+			"context_empty_one",
+			&KubernetesConfig{Context: String("prod")},
+			&KubernetesConfig{},
+			&KubernetesConfig{Context: String("prod")},
+		},
+		{
+			"namespace_empty_two",
+			&KubernetesConfig{},
+			&KubernetesConfig{Namespace: String("ct")},
+			&KubernetesConfig{Namespace: String("ct")},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestKubernetesConfig_Finalize(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    *KubernetesConfig
+		r    *KubernetesConfig
+	}{
+		{
+			"empty",
+			&KubernetesConfig{},
+			&KubernetesConfig{
+				Kubeconfig: String(""),
+				Context:    String(""),
+				Namespace:  String(""),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
+
+func TestKubernetesObjectConfig_Copy(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *KubernetesObjectConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&KubernetesObjectConfig{},
+		},
+		{
+			"same_enabled",
+			&KubernetesObjectConfig{
+				Key:         String("app.conf"),
+				Labels:      map[string]string{"app": "web"},
+				Annotations: map[string]string{"team": "infra"},
+				OwnerReferences: []*KubernetesOwnerReference{
+					{
+						APIVersion: String("apps/v1"),
+						Kind:       String("Deployment"),
+						Name:       String("web"),
+						UID:        String("1234"),
+						Controller: Bool(true),
+					},
+				},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestKubernetesObjectConfig_Merge(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *KubernetesObjectConfig
+		b    *KubernetesObjectConfig
+		r    *KubernetesObjectConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&KubernetesObjectConfig{},
+			&KubernetesObjectConfig{},
+		},
+		{
+			"nil_b",
+			&KubernetesObjectConfig{},
+			nil,
+			&KubernetesObjectConfig{},
+		},
+		{
+			"key_overrides",
+			&KubernetesObjectConfig{Key: String("a")},
+			&KubernetesObjectConfig{Key: String("b")},
+			&KubernetesObjectConfig{Key: String("b")},
+		},
+		{
+			"labels_merge",
+			&KubernetesObjectConfig{Labels: map[string]string{"a": "1", "b": "2"}},
+			&KubernetesObjectConfig{Labels: map[string]string{"b": "3"}},
+			&KubernetesObjectConfig{Labels: map[string]string{"a": "1", "b": "3"}},
+		},
+		{
+			"annotations_empty_one",
+			&KubernetesObjectConfig{Annotations: map[string]string{"a": "1"}},
+			&KubernetesObjectConfig{},
+			&KubernetesObjectConfig{Annotations: map[string]string{"a": "1"}},
+		},
+		{
+			"owner_references_merge",
+			&KubernetesObjectConfig{OwnerReferences: []*KubernetesOwnerReference{
+				{Name: String("a")},
+			}},
+			&KubernetesObjectConfig{OwnerReferences: []*KubernetesOwnerReference{
+				{Name: String("b")},
+			}},
+			&KubernetesObjectConfig{OwnerReferences: []*KubernetesOwnerReference{
+				{Name: String("a")},
+				{Name: String("b")},
+			}},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestKubernetesObjectConfig_Finalize(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    *KubernetesObjectConfig
+		r    *KubernetesObjectConfig
+	}{
+		{
+			"empty",
+			&KubernetesObjectConfig{},
+			&KubernetesObjectConfig{
+				Key:             String(DefaultKubernetesKey),
+				Labels:          map[string]string{},
+				Annotations:     map[string]string{},
+				OwnerReferences: []*KubernetesOwnerReference{},
+			},
+		},
+		{
+			"owner_reference",
+			&KubernetesObjectConfig{
+				OwnerReferences: []*KubernetesOwnerReference{{}},
+			},
+			&KubernetesObjectConfig{
+				Key:         String(DefaultKubernetesKey),
+				Labels:      map[string]string{},
+				Annotations: map[string]string{},
+				OwnerReferences: []*KubernetesOwnerReference{
+					{
+						APIVersion:         String(""),
+						Kind:               String(""),
+						Name:               String(""),
+						UID:                String(""),
+						Controller:         Bool(false),
+						BlockOwnerDeletion: Bool(false),
+					},
+				},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
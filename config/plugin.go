@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPluginTimeout is the default amount of time to wait for a plugin
+	// to answer a call.
+	DefaultPluginTimeout = 30 * time.Second
+
+	// DefaultPluginCacheTTL is the default amount of time results of a plugin
+	// are cached. By default, this is 0, which means results are cached until
+	// the plugin reports an update.
+	DefaultPluginCacheTTL = 0 * time.Second
+)
+
+// PluginConfig is the configuration of a long-running plugin, which answers
+// calls of the template "plugin" function over its stdin and stdout.
+type PluginConfig struct {
+	// CacheTTL is the amount of time the result of a call is cached before the
+	// plugin is called again. Zero means results are cached until the plugin
+	// reports an update.
+	CacheTTL *time.Duration `mapstructure:"cache_ttl"`
+
+	// Command is the command that starts the plugin.
+	Command commandList `mapstructure:"command"`
+
+	// Functions are the names of the template functions the plugin provides.
+	// Their data is fetched from the plugin and watched like other
+	// dependencies.
+	Functions []string `mapstructure:"functions"`
+
+	// Name is the name templates call the plugin by.
+	Name *string `mapstructure:"name"`
+
+	// Timeout is the maximum amount of time to wait for the plugin to answer a
+	// call. A plugin that does not answer in time is restarted.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultPluginConfig returns a configuration that is populated with the
+// default values.
+func DefaultPluginConfig() *PluginConfig {
+	return &PluginConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *PluginConfig) Copy() *PluginConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o PluginConfig
+
+	o.CacheTTL = c.CacheTTL
+
+	o.Command = c.Command
+
+	if c.Functions != nil {
+		o.Functions = append([]string{}, c.Functions...)
+	}
+
+	o.Name = c.Name
+
+	o.Timeout = c.Timeout
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *PluginConfig) Merge(o *PluginConfig) *PluginConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.CacheTTL != nil {
+		r.CacheTTL = o.CacheTTL
+	}
+
+	if o.Command != nil {
+		r.Command = o.Command
+	}
+
+	if o.Functions != nil {
+		r.Functions = append([]string{}, r.Functions...)
+		r.Functions = append(r.Functions, o.Functions...)
+	}
+
+	if o.Name != nil {
+		r.Name = o.Name
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *PluginConfig) Finalize() {
+	if c.CacheTTL == nil {
+		c.CacheTTL = TimeDuration(DefaultPluginCacheTTL)
+	}
+
+	if c.Command == nil {
+		c.Command = []string{}
+	}
+
+	if c.Functions == nil {
+		c.Functions = []string{}
+	}
+
+	if c.Name == nil {
+		c.Name = String("")
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultPluginTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *PluginConfig) GoString() string {
+	if c == nil {
+		return "(*PluginConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&PluginConfig{"+
+		"CacheTTL:%s, "+
+		"Command:%s, "+
+		"Functions:%s, "+
+		"Name:%s, "+
+		"Timeout:%s"+
+		"}",
+		TimeDurationGoString(c.CacheTTL),
+		c.Command,
+		c.Functions,
+		StringGoString(c.Name),
+		TimeDurationGoString(c.Timeout),
+	)
+}
+
+// PluginConfigs is a collection of PluginConfigs.
+type PluginConfigs []*PluginConfig
+
+// DefaultPluginConfigs returns a configuration that is populated with the
+// default values.
+func DefaultPluginConfigs() *PluginConfigs {
+	return &PluginConfigs{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *PluginConfigs) Copy() *PluginConfigs {
+	if c == nil {
+		return nil
+	}
+
+	o := make(PluginConfigs, len(*c))
+	for i, p := range *c {
+		o[i] = p.Copy()
+	}
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Plugins with the same name are merged, others are appended.
+func (c *PluginConfigs) Merge(o *PluginConfigs) *PluginConfigs {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+OUTER:
+	for _, op := range *o {
+		if name := StringVal(op.Name); name != "" {
+			for i, rp := range *r {
+				if StringVal(rp.Name) == name {
+					(*r)[i] = rp.Merge(op)
+					continue OUTER
+				}
+			}
+		}
+		*r = append(*r, op.Copy())
+	}
+
+	return r
+}
+
+// Finalize ensures the configuration has no nil pointers and sets default
+// values.
+func (c *PluginConfigs) Finalize() {
+	for _, p := range *c {
+		p.Finalize()
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *PluginConfigs) GoString() string {
+	if c == nil {
+		return "(*PluginConfigs)(nil)"
+	}
+
+	s := make([]string, len(*c))
+	for i, p := range *c {
+		s[i] = p.GoString()
+	}
+
+	return "{" + strings.Join(s, ", ") + "}"
+}
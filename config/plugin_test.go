@@ -0,0 +1,235 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPluginConfig_Copy(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *PluginConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&PluginConfig{},
+		},
+		{
+			"same_enabled",
+			&PluginConfig{
+				CacheTTL:  TimeDuration(1 * time.Minute),
+				Command:   []string{"lookup"},
+				Functions: []string{"lookupValue"},
+				Name:      String("lookup"),
+				Timeout:   TimeDuration(5 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestPluginConfig_Merge(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *PluginConfig
+		b    *PluginConfig
+		r    *PluginConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&PluginConfig{},
+			&PluginConfig{},
+		},
+		{
+			"nil_b",
+			&PluginConfig{},
+			nil,
+			&PluginConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			&PluginConfig{},
+			&PluginConfig{},
+			&PluginConfig{},
+		},
+		{
+			"cache_ttl_overrides",
+			&PluginConfig{CacheTTL: TimeDuration(10 * time.Second)},
+			&PluginConfig{CacheTTL: TimeDuration(20 * time.Second)},
+			&PluginConfig{CacheTTL: TimeDuration(20 * time.Second)},
+		},
+		{
+			"cache_ttl_empty_one",
+			&PluginConfig{CacheTTL: TimeDuration(10 * time.Second)},
+			&PluginConfig{},
+			&PluginConfig{CacheTTL: TimeDuration(10 * time.Second)},
+		},
+		{
+			"command_overrides",
+			&PluginConfig{Command: []string{"a"}},
+			&PluginConfig{Command: []string{"b"}},
+			&PluginConfig{Command: []string{"b"}},
+		},
+		{
+			"command_empty_two",
+			&PluginConfig{},
+			&PluginConfig{Command: []string{"b"}},
+			&PluginConfig{Command: []string{"b"}},
+		},
+		{
+			"functions_merges",
+			&PluginConfig{Functions: []string{"a"}},
+			&PluginConfig{Functions: []string{"b"}},
+			&PluginConfig{Functions: []string{"a", "b"}},
+		},
+		{
+			"functions_empty_one",
+			&PluginConfig{Functions: []string{"a"}},
+			&PluginConfig{},
+			&PluginConfig{Functions: []string{"a"}},
+		},
+		{
+			"timeout_overrides",
+			&PluginConfig{Timeout: TimeDuration(10 * time.Second)},
+			&PluginConfig{Timeout: TimeDuration(20 * time.Second)},
+			&PluginConfig{Timeout: TimeDuration(20 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestPluginConfig_Finalize(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    *PluginConfig
+		r    *PluginConfig
+	}{
+		{
+			"empty",
+			&PluginConfig{},
+			&PluginConfig{
+				CacheTTL:  TimeDuration(DefaultPluginCacheTTL),
+				Command:   []string{},
+				Functions: []string{},
+				Name:      String(""),
+				Timeout:   TimeDuration(DefaultPluginTimeout),
+			},
+		},
+		{
+			"with_timeout",
+			&PluginConfig{
+				Name:    String("lookup"),
+				Timeout: TimeDuration(5 * time.Second),
+			},
+			&PluginConfig{
+				CacheTTL:  TimeDuration(DefaultPluginCacheTTL),
+				Command:   []string{},
+				Functions: []string{},
+				Name:      String("lookup"),
+				Timeout:   TimeDuration(5 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
+
+func TestPluginConfigs_Merge(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *PluginConfigs
+		b    *PluginConfigs
+		r    *PluginConfigs
+	}{
+		{
+			"nil_a",
+			nil,
+			&PluginConfigs{},
+			&PluginConfigs{},
+		},
+		{
+			"nil_b",
+			&PluginConfigs{},
+			nil,
+			&PluginConfigs{},
+		},
+		{
+			"same_name_merges",
+			&PluginConfigs{
+				&PluginConfig{Name: String("a"), Command: []string{"a"}},
+			},
+			&PluginConfigs{
+				&PluginConfig{Name: String("a"), Timeout: TimeDuration(5 * time.Second)},
+			},
+			&PluginConfigs{
+				&PluginConfig{
+					Name:    String("a"),
+					Command: []string{"a"},
+					Timeout: TimeDuration(5 * time.Second),
+				},
+			},
+		},
+		{
+			"other_name_appends",
+			&PluginConfigs{
+				&PluginConfig{Name: String("a")},
+			},
+			&PluginConfigs{
+				&PluginConfig{Name: String("b")},
+			},
+			&PluginConfigs{
+				&PluginConfig{Name: String("a")},
+				&PluginConfig{Name: String("b")},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultReadinessInterval is the default time between readiness checks.
+	DefaultReadinessInterval = 1 * time.Second
+
+	// DefaultReadinessTimeout is the default time to wait for a child process
+	// to become ready.
+	DefaultReadinessTimeout = 30 * time.Second
+)
+
+// ReadinessConfig is the configuration for checking that an exec child process
+// is ready after it is started or reloaded. Each configured check must pass
+// for the child process to be ready.
+type ReadinessConfig struct {
+	// Command is a command that must exit successfully.
+	Command commandList `mapstructure:"command"`
+
+	// Enabled controls if the readiness probe is enabled.
+	Enabled *bool `mapstructure:"enabled"`
+
+	// HTTP is a URL that must respond with a 2xx or 3xx status code.
+	HTTP *string `mapstructure:"http"`
+
+	// Interval is the time to wait between checks, and the timeout of each
+	// individual check.
+	Interval *time.Duration `mapstructure:"interval"`
+
+	// RestoreOnFailure restores the previous contents of the templates that
+	// triggered a reload, and reloads the child process again, when the child
+	// process does not become ready after the reload.
+	RestoreOnFailure *bool `mapstructure:"restore_on_failure"`
+
+	// TCP is an address, in the form host:port, that must accept connections.
+	TCP *string `mapstructure:"tcp"`
+
+	// Timeout is the maximum time to wait for the child process to become
+	// ready.
+	Timeout *time.Duration `mapstructure:"timeout"`
+}
+
+// DefaultReadinessConfig returns a configuration that is populated with the
+// default values.
+func DefaultReadinessConfig() *ReadinessConfig {
+	return &ReadinessConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *ReadinessConfig) Copy() *ReadinessConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o ReadinessConfig
+
+	o.Command = c.Command
+
+	o.Enabled = c.Enabled
+
+	o.HTTP = c.HTTP
+
+	o.Interval = c.Interval
+
+	o.RestoreOnFailure = c.RestoreOnFailure
+
+	o.TCP = c.TCP
+
+	o.Timeout = c.Timeout
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *ReadinessConfig) Merge(o *ReadinessConfig) *ReadinessConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Command != nil {
+		r.Command = o.Command
+	}
+
+	if o.Enabled != nil {
+		r.Enabled = o.Enabled
+	}
+
+	if o.HTTP != nil {
+		r.HTTP = o.HTTP
+	}
+
+	if o.Interval != nil {
+		r.Interval = o.Interval
+	}
+
+	if o.RestoreOnFailure != nil {
+		r.RestoreOnFailure = o.RestoreOnFailure
+	}
+
+	if o.TCP != nil {
+		r.TCP = o.TCP
+	}
+
+	if o.Timeout != nil {
+		r.Timeout = o.Timeout
+	}
+
+	return r
+}
+
+// Finalize ensures there no nil pointers.
+func (c *ReadinessConfig) Finalize() {
+	if c.Command == nil {
+		c.Command = []string{}
+	}
+
+	if c.HTTP == nil {
+		c.HTTP = String("")
+	}
+
+	if c.TCP == nil {
+		c.TCP = String("")
+	}
+
+	if c.Enabled == nil {
+		c.Enabled = Bool(!c.Command.Empty() ||
+			StringPresent(c.HTTP) || StringPresent(c.TCP))
+	}
+
+	if c.Interval == nil {
+		c.Interval = TimeDuration(DefaultReadinessInterval)
+	}
+
+	if c.RestoreOnFailure == nil {
+		c.RestoreOnFailure = Bool(false)
+	}
+
+	if c.Timeout == nil {
+		c.Timeout = TimeDuration(DefaultReadinessTimeout)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *ReadinessConfig) GoString() string {
+	if c == nil {
+		return "(*ReadinessConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&ReadinessConfig{"+
+		"Command:%s, "+
+		"Enabled:%s, "+
+		"HTTP:%s, "+
+		"Interval:%s, "+
+		"RestoreOnFailure:%s, "+
+		"TCP:%s, "+
+		"Timeout:%s"+
+		"}",
+		c.Command,
+		BoolGoString(c.Enabled),
+		StringGoString(c.HTTP),
+		TimeDurationGoString(c.Interval),
+		BoolGoString(c.RestoreOnFailure),
+		StringGoString(c.TCP),
+		TimeDurationGoString(c.Timeout),
+	)
+}
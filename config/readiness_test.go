@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadinessConfig_Copy(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *ReadinessConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&ReadinessConfig{},
+		},
+		{
+			"same_enabled",
+			&ReadinessConfig{
+				Command:          []string{"check"},
+				Enabled:          Bool(true),
+				HTTP:             String("http://127.0.0.1:8080/health"),
+				Interval:         TimeDuration(2 * time.Second),
+				RestoreOnFailure: Bool(true),
+				TCP:              String("127.0.0.1:8080"),
+				Timeout:          TimeDuration(10 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestReadinessConfig_Merge(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *ReadinessConfig
+		b    *ReadinessConfig
+		r    *ReadinessConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&ReadinessConfig{},
+			&ReadinessConfig{},
+		},
+		{
+			"nil_b",
+			&ReadinessConfig{},
+			nil,
+			&ReadinessConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"command_overrides",
+			&ReadinessConfig{Command: []string{"a"}},
+			&ReadinessConfig{Command: []string{"b"}},
+			&ReadinessConfig{Command: []string{"b"}},
+		},
+		{
+			"http_overrides",
+			&ReadinessConfig{HTTP: String("http://a")},
+			&ReadinessConfig{HTTP: String("")},
+			&ReadinessConfig{HTTP: String("")},
+		},
+		{
+			"tcp_empty_one",
+			&ReadinessConfig{TCP: String("127.0.0.1:80")},
+			&ReadinessConfig{},
+			&ReadinessConfig{TCP: String("127.0.0.1:80")},
+		},
+		{
+			"restore_on_failure_overrides",
+			&ReadinessConfig{RestoreOnFailure: Bool(true)},
+			&ReadinessConfig{RestoreOnFailure: Bool(false)},
+			&ReadinessConfig{RestoreOnFailure: Bool(false)},
+		},
+		{
+			"timeout_empty_two",
+			&ReadinessConfig{},
+			&ReadinessConfig{Timeout: TimeDuration(5 * time.Second)},
+			&ReadinessConfig{Timeout: TimeDuration(5 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestReadinessConfig_Finalize(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    *ReadinessConfig
+		r    *ReadinessConfig
+	}{
+		{
+			"empty",
+			&ReadinessConfig{},
+			&ReadinessConfig{
+				Command:          []string{},
+				Enabled:          Bool(false),
+				HTTP:             String(""),
+				Interval:         TimeDuration(DefaultReadinessInterval),
+				RestoreOnFailure: Bool(false),
+				TCP:              String(""),
+				Timeout:          TimeDuration(DefaultReadinessTimeout),
+			},
+		},
+		{
+			"with_tcp",
+			&ReadinessConfig{
+				TCP: String("127.0.0.1:8080"),
+			},
+			&ReadinessConfig{
+				Command:          []string{},
+				Enabled:          Bool(true),
+				HTTP:             String(""),
+				Interval:         TimeDuration(DefaultReadinessInterval),
+				RestoreOnFailure: Bool(false),
+				TCP:              String("127.0.0.1:8080"),
+				Timeout:          TimeDuration(DefaultReadinessTimeout),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
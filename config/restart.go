@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// RestartPolicyNever never restarts the child process.
+	RestartPolicyNever = "never"
+
+	// RestartPolicyOnFailure restarts the child process only when it exits
+	// with a non-zero exit code.
+	RestartPolicyOnFailure = "on-failure"
+
+	// RestartPolicyAlways restarts the child process whenever it exits.
+	RestartPolicyAlways = "always"
+
+	// DefaultRestartPolicy is the default restart policy.
+	DefaultRestartPolicy = RestartPolicyNever
+
+	// DefaultRestartAttempts is the default number of restarts allowed within
+	// the restart window.
+	DefaultRestartAttempts = 5
+
+	// DefaultRestartWindow is the default window in which restarts are counted.
+	DefaultRestartWindow = 5 * time.Minute
+
+	// DefaultRestartBackoff is the default base for the exponential backoff
+	// between restarts.
+	DefaultRestartBackoff = 1 * time.Second
+
+	// DefaultRestartMaxBackoff is the default maximum backoff between restarts.
+	DefaultRestartMaxBackoff = 1 * time.Minute
+)
+
+// RestartConfig is the configuration for restarting an exec child process
+// when it exits. The attempts and backoff follow the semantics of
+// RetryConfig, with attempts counted within a sliding window.
+type RestartConfig struct {
+	// Policy is the restart policy: "never", "on-failure" or "always".
+	Policy *string `mapstructure:"policy"`
+
+	// Attempts is the maximum number of restarts within the window before the
+	// exit is treated as final. 0 means unlimited.
+	Attempts *int `mapstructure:"attempts"`
+
+	// Window is the period in which restarts are counted towards the attempts
+	// and the backoff. Restarts older than the window are forgotten, so a
+	// child process that stays up resets its backoff. 0 means restarts are
+	// never forgotten.
+	Window *time.Duration `mapstructure:"window"`
+
+	// Backoff is the base of the exponential backoff. This number will be
+	// multiplied by the next power of 2 on each restart.
+	Backoff *time.Duration `mapstructure:"backoff"`
+
+	// MaxBackoff is an upper limit to the sleep time between restarts. A
+	// MaxBackoff of zero means there is no limit to the exponential growth of
+	// the backoff.
+	MaxBackoff *time.Duration `mapstructure:"max_backoff"`
+}
+
+// DefaultRestartConfig returns a configuration that is populated with the
+// default values.
+func DefaultRestartConfig() *RestartConfig {
+	return &RestartConfig{}
+}
+
+// Copy returns a deep copy of this configuration.
+func (c *RestartConfig) Copy() *RestartConfig {
+	if c == nil {
+		return nil
+	}
+
+	var o RestartConfig
+
+	o.Policy = c.Policy
+
+	o.Attempts = c.Attempts
+
+	o.Window = c.Window
+
+	o.Backoff = c.Backoff
+
+	o.MaxBackoff = c.MaxBackoff
+
+	return &o
+}
+
+// Merge combines all values in this configuration with the values in the other
+// configuration, with values in the other configuration taking precedence.
+// Maps and slices are merged, most other values are overwritten. Complex
+// structs define their own merge functionality.
+func (c *RestartConfig) Merge(o *RestartConfig) *RestartConfig {
+	if c == nil {
+		if o == nil {
+			return nil
+		}
+		return o.Copy()
+	}
+
+	if o == nil {
+		return c.Copy()
+	}
+
+	r := c.Copy()
+
+	if o.Policy != nil {
+		r.Policy = o.Policy
+	}
+
+	if o.Attempts != nil {
+		r.Attempts = o.Attempts
+	}
+
+	if o.Window != nil {
+		r.Window = o.Window
+	}
+
+	if o.Backoff != nil {
+		r.Backoff = o.Backoff
+	}
+
+	if o.MaxBackoff != nil {
+		r.MaxBackoff = o.MaxBackoff
+	}
+
+	return r
+}
+
+// RetryFunc returns the function that decides, given the number of restarts
+// within the window, whether to restart again and how long to wait first.
+func (c *RestartConfig) RetryFunc() RetryFunc {
+	retry := &RetryConfig{
+		Attempts:   c.Attempts,
+		Backoff:    c.Backoff,
+		MaxBackoff: c.MaxBackoff,
+		Enabled:    Bool(StringVal(c.Policy) != RestartPolicyNever),
+	}
+	return retry.RetryFunc()
+}
+
+// Finalize ensures there no nil pointers.
+func (c *RestartConfig) Finalize() {
+	if c.Policy == nil {
+		c.Policy = String(DefaultRestartPolicy)
+	}
+
+	if c.Attempts == nil {
+		c.Attempts = Int(DefaultRestartAttempts)
+	}
+
+	if c.Window == nil {
+		c.Window = TimeDuration(DefaultRestartWindow)
+	}
+
+	if c.Backoff == nil {
+		c.Backoff = TimeDuration(DefaultRestartBackoff)
+	}
+
+	if c.MaxBackoff == nil {
+		c.MaxBackoff = TimeDuration(DefaultRestartMaxBackoff)
+	}
+}
+
+// GoString defines the printable version of this struct.
+func (c *RestartConfig) GoString() string {
+	if c == nil {
+		return "(*RestartConfig)(nil)"
+	}
+
+	return fmt.Sprintf("&RestartConfig{"+
+		"Policy:%s, "+
+		"Attempts:%s, "+
+		"Window:%s, "+
+		"Backoff:%s, "+
+		"MaxBackoff:%s"+
+		"}",
+		StringGoString(c.Policy),
+		IntGoString(c.Attempts),
+		TimeDurationGoString(c.Window),
+		TimeDurationGoString(c.Backoff),
+		TimeDurationGoString(c.MaxBackoff),
+	)
+}
@@ -0,0 +1,221 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRestartConfig_RetryFunc(t *testing.T) {
+
+	cases := []struct {
+		name  string
+		c     *RestartConfig
+		retry int
+		ok    bool
+		sleep time.Duration
+	}{
+		{
+			"never",
+			&RestartConfig{Policy: String(RestartPolicyNever)},
+			0,
+			false,
+			0,
+		},
+		{
+			"on_failure_first",
+			&RestartConfig{Policy: String(RestartPolicyOnFailure)},
+			0,
+			true,
+			DefaultRestartBackoff,
+		},
+		{
+			"always_backoff",
+			&RestartConfig{Policy: String(RestartPolicyAlways)},
+			2,
+			true,
+			4 * DefaultRestartBackoff,
+		},
+		{
+			"max_backoff",
+			&RestartConfig{
+				Policy:   String(RestartPolicyAlways),
+				Attempts: Int(10),
+			},
+			6,
+			true,
+			DefaultRestartMaxBackoff,
+		},
+		{
+			"attempts_exhausted",
+			&RestartConfig{Policy: String(RestartPolicyAlways)},
+			DefaultRestartAttempts,
+			false,
+			0,
+		},
+		{
+			"unlimited_attempts",
+			&RestartConfig{
+				Policy:   String(RestartPolicyAlways),
+				Attempts: Int(0),
+			},
+			20,
+			true,
+			DefaultRestartMaxBackoff,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.c.Finalize()
+			ok, sleep := tc.c.RetryFunc()(tc.retry)
+			if ok != tc.ok {
+				t.Errorf("\nexp ok: %t\nact ok: %t", tc.ok, ok)
+			}
+			if sleep != tc.sleep {
+				t.Errorf("\nexp sleep: %s\nact sleep: %s", tc.sleep, sleep)
+			}
+		})
+	}
+}
+
+func TestRestartConfig_Copy(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *RestartConfig
+	}{
+		{
+			"nil",
+			nil,
+		},
+		{
+			"empty",
+			&RestartConfig{},
+		},
+		{
+			"same_enabled",
+			&RestartConfig{
+				Policy:     String(RestartPolicyOnFailure),
+				Attempts:   Int(3),
+				Window:     TimeDuration(10 * time.Minute),
+				Backoff:    TimeDuration(2 * time.Second),
+				MaxBackoff: TimeDuration(30 * time.Second),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Copy()
+			if !reflect.DeepEqual(tc.a, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.a, r)
+			}
+		})
+	}
+}
+
+func TestRestartConfig_Merge(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a    *RestartConfig
+		b    *RestartConfig
+		r    *RestartConfig
+	}{
+		{
+			"nil_a",
+			nil,
+			&RestartConfig{},
+			&RestartConfig{},
+		},
+		{
+			"nil_b",
+			&RestartConfig{},
+			nil,
+			&RestartConfig{},
+		},
+		{
+			"nil_both",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"policy_overrides",
+			&RestartConfig{Policy: String(RestartPolicyAlways)},
+			&RestartConfig{Policy: String(RestartPolicyOnFailure)},
+			&RestartConfig{Policy: String(RestartPolicyOnFailure)},
+		},
+		{
+			"policy_empty_one",
+			&RestartConfig{Policy: String(RestartPolicyAlways)},
+			&RestartConfig{},
+			&RestartConfig{Policy: String(RestartPolicyAlways)},
+		},
+		{
+			"attempts_overrides",
+			&RestartConfig{Attempts: Int(10)},
+			&RestartConfig{Attempts: Int(0)},
+			&RestartConfig{Attempts: Int(0)},
+		},
+		{
+			"window_overrides",
+			&RestartConfig{Window: TimeDuration(1 * time.Minute)},
+			&RestartConfig{Window: TimeDuration(0)},
+			&RestartConfig{Window: TimeDuration(0)},
+		},
+		{
+			"backoff_empty_two",
+			&RestartConfig{},
+			&RestartConfig{Backoff: TimeDuration(2 * time.Second)},
+			&RestartConfig{Backoff: TimeDuration(2 * time.Second)},
+		},
+		{
+			"max_backoff_overrides",
+			&RestartConfig{MaxBackoff: TimeDuration(10 * time.Second)},
+			&RestartConfig{MaxBackoff: TimeDuration(20 * time.Second)},
+			&RestartConfig{MaxBackoff: TimeDuration(20 * time.Second)},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			r := tc.a.Merge(tc.b)
+			if !reflect.DeepEqual(tc.r, r) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, r)
+			}
+		})
+	}
+}
+
+func TestRestartConfig_Finalize(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    *RestartConfig
+		r    *RestartConfig
+	}{
+		{
+			"empty",
+			&RestartConfig{},
+			&RestartConfig{
+				Policy:     String(DefaultRestartPolicy),
+				Attempts:   Int(DefaultRestartAttempts),
+				Window:     TimeDuration(DefaultRestartWindow),
+				Backoff:    TimeDuration(DefaultRestartBackoff),
+				MaxBackoff: TimeDuration(DefaultRestartMaxBackoff),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.i.Finalize()
+			if !reflect.DeepEqual(tc.r, tc.i) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.r, tc.i)
+			}
+		})
+	}
+}
@@ -14,9 +14,36 @@ const (
 	// DefaultTemplateCommandTimeout is the amount of time to wait for a command
 	// to return.
 	DefaultTemplateCommandTimeout = 30 * time.Second
+
+	// DestinationTypeFile renders the template to a file on disk.
+	DestinationTypeFile = "file"
+
+	// DestinationTypeStdout renders the template to standard out.
+	DestinationTypeStdout = "stdout"
+
+	// DestinationTypePipe renders the template to a named pipe, which is
+	// created if it does not exist.
+	DestinationTypePipe = "pipe"
+
+	// DestinationTypeConsulKV renders the template to a key in Consul's KV
+	// store.
+	DestinationTypeConsulKV = "consul-kv"
+
+	// DestinationTypeVaultKV renders the template to a secret in one of
+	// Vault's KV secrets engines.
+	DestinationTypeVaultKV = "vault-kv"
+
+	// DefaultDestinationType is the default destination type.
+	DefaultDestinationType = DestinationTypeFile
 )
 
 var (
+	// DefaultTemplateFunctionDenylist is the list of functions that are denied
+	// when a template does not set function_denylist. These functions have side
+	// effects outside of the rendered template, so they must be opted into by
+	// explicitly setting a denylist that does not include them.
+	DefaultTemplateFunctionDenylist = []string{"keyPutIfAbsent"}
+
 	// ErrTemplateStringEmpty is the error returned with the template contents
 	// are empty.
 	ErrTemplateStringEmpty = errors.New("template: cannot be empty")
@@ -52,6 +79,14 @@ type TemplateConfig struct {
 	// This is required unless running in debug/dry mode.
 	Destination *string `mapstructure:"destination"`
 
+	// DestinationType is the kind of destination the template is rendered to,
+	// which determines how Destination is interpreted: a path for "file" and
+	// "pipe", a key for "consul-kv", a secret path for "vault-kv" and an
+	// optionally namespaced object name, "namespace/name", for
+	// "kubernetes-secret" and "kubernetes-configmap". It is ignored for
+	// "stdout". The default value is "file".
+	DestinationType *string `mapstructure:"destination_type"`
+
 	// ErrMissingKey is used to control how the template behaves when attempting
 	// to index a struct or map key that does not exist.
 	ErrMissingKey *bool `mapstructure:"error_on_missing_key"`
@@ -64,6 +99,19 @@ type TemplateConfig struct {
 	// successfully.
 	Exec *ExecConfig `mapstructure:"exec"`
 
+	// ExecReloadSignal is the signal to send to the exec mode child processes
+	// watching this template when it renders, instead of their reload signal.
+	ExecReloadSignal *os.Signal `mapstructure:"exec_reload_signal"`
+
+	// ExecDiffFile controls whether the command is given a JSON file describing
+	// the changed dependencies, in the CT_DIFF_FILE environment variable.
+	ExecDiffFile *bool `mapstructure:"exec_diff_file"`
+
+	// Kubernetes is the configuration of the Secret or ConfigMap the template
+	// renders to, for the "kubernetes-secret" and "kubernetes-configmap"
+	// destination types.
+	Kubernetes *KubernetesObjectConfig `mapstructure:"kubernetes"`
+
 	// Perms are the file system permissions to use when creating the file on
 	// disk. This is useful for when files contain sensitive information, such as
 	// secrets from Vault.
@@ -115,6 +163,10 @@ type TemplateConfig struct {
 	// and causes an error if a relative path tries to traverse outside that
 	// prefix.
 	SandboxPath *string `mapstructure:"sandbox_path"`
+
+	// Sprig makes the Sprig template functions available without their
+	// "sprig_" prefix. Built-in functions of the same name take precedence.
+	Sprig *bool `mapstructure:"sprig"`
 }
 
 // DefaultTemplateConfig returns a configuration that is populated with the
@@ -146,6 +198,8 @@ func (c *TemplateConfig) Copy() *TemplateConfig {
 
 	o.Destination = c.Destination
 
+	o.DestinationType = c.DestinationType
+
 	o.ErrMissingKey = c.ErrMissingKey
 
 	o.ErrFatal = c.ErrFatal
@@ -154,6 +208,14 @@ func (c *TemplateConfig) Copy() *TemplateConfig {
 		o.Exec = c.Exec.Copy()
 	}
 
+	o.ExecReloadSignal = c.ExecReloadSignal
+
+	o.ExecDiffFile = c.ExecDiffFile
+
+	if c.Kubernetes != nil {
+		o.Kubernetes = c.Kubernetes.Copy()
+	}
+
 	o.Perms = c.Perms
 
 	o.Source = c.Source
@@ -165,8 +227,8 @@ func (c *TemplateConfig) Copy() *TemplateConfig {
 	o.LeftDelim = c.LeftDelim
 	o.RightDelim = c.RightDelim
 
-	for _, fun := range c.FunctionDenylist {
-		o.FunctionDenylist = append(o.FunctionDenylist, fun)
+	if c.FunctionDenylist != nil {
+		o.FunctionDenylist = append([]string{}, c.FunctionDenylist...)
 	}
 
 	for _, fun := range c.FunctionDenylistDeprecated {
@@ -175,6 +237,8 @@ func (c *TemplateConfig) Copy() *TemplateConfig {
 
 	o.SandboxPath = c.SandboxPath
 
+	o.Sprig = c.Sprig
+
 	return &o
 }
 
@@ -220,6 +284,10 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 		r.Destination = o.Destination
 	}
 
+	if o.DestinationType != nil {
+		r.DestinationType = o.DestinationType
+	}
+
 	if o.ErrMissingKey != nil {
 		r.ErrMissingKey = o.ErrMissingKey
 	}
@@ -232,6 +300,18 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 		r.Exec = r.Exec.Merge(o.Exec)
 	}
 
+	if o.ExecReloadSignal != nil {
+		r.ExecReloadSignal = o.ExecReloadSignal
+	}
+
+	if o.ExecDiffFile != nil {
+		r.ExecDiffFile = o.ExecDiffFile
+	}
+
+	if o.Kubernetes != nil {
+		r.Kubernetes = r.Kubernetes.Merge(o.Kubernetes)
+	}
+
 	if o.Perms != nil {
 		r.Perms = o.Perms
 	}
@@ -252,8 +332,11 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 		r.RightDelim = o.RightDelim
 	}
 
-	for _, fun := range o.FunctionDenylist {
-		r.FunctionDenylist = append(r.FunctionDenylist, fun)
+	// An explicitly empty denylist is meaningful, since it replaces the default
+	// denylist, so make sure it survives the merge.
+	if o.FunctionDenylist != nil {
+		r.FunctionDenylist = append([]string{}, r.FunctionDenylist...)
+		r.FunctionDenylist = append(r.FunctionDenylist, o.FunctionDenylist...)
 	}
 
 	for _, fun := range o.FunctionDenylistDeprecated {
@@ -264,6 +347,10 @@ func (c *TemplateConfig) Merge(o *TemplateConfig) *TemplateConfig {
 		r.SandboxPath = o.SandboxPath
 	}
 
+	if o.Sprig != nil {
+		r.Sprig = o.Sprig
+	}
+
 	return r
 }
 
@@ -294,6 +381,10 @@ func (c *TemplateConfig) Finalize() {
 		c.Destination = String("")
 	}
 
+	if c.DestinationType == nil {
+		c.DestinationType = String(DefaultDestinationType)
+	}
+
 	if c.ErrMissingKey == nil {
 		c.ErrMissingKey = Bool(false)
 	}
@@ -331,6 +422,19 @@ func (c *TemplateConfig) Finalize() {
 	}
 	c.Exec.Finalize()
 
+	if c.ExecReloadSignal == nil {
+		c.ExecReloadSignal = Signal(nil)
+	}
+
+	if c.ExecDiffFile == nil {
+		c.ExecDiffFile = Bool(false)
+	}
+
+	if c.Kubernetes == nil {
+		c.Kubernetes = DefaultKubernetesObjectConfig()
+	}
+	c.Kubernetes.Finalize()
+
 	if c.Perms == nil {
 		c.Perms = FileMode(0)
 	}
@@ -356,8 +460,12 @@ func (c *TemplateConfig) Finalize() {
 		c.SandboxPath = String("")
 	}
 
+	if c.Sprig == nil {
+		c.Sprig = Bool(false)
+	}
+
 	if c.FunctionDenylist == nil && c.FunctionDenylistDeprecated == nil {
-		c.FunctionDenylist = []string{}
+		c.FunctionDenylist = append([]string{}, DefaultTemplateFunctionDenylist...)
 		c.FunctionDenylistDeprecated = []string{}
 	} else {
 		c.FunctionDenylist = combineLists(c.FunctionDenylist, c.FunctionDenylistDeprecated)
@@ -377,16 +485,21 @@ func (c *TemplateConfig) GoString() string {
 		"Contents:%s, "+
 		"CreateDestDirs:%s, "+
 		"Destination:%s, "+
+		"DestinationType:%s, "+
 		"ErrMissingKey:%s, "+
 		"ErrFatal:%s, "+
 		"Exec:%#v, "+
+		"ExecReloadSignal:%s, "+
+		"ExecDiffFile:%s, "+
+		"Kubernetes:%#v, "+
 		"Perms:%s, "+
 		"Source:%s, "+
 		"Wait:%#v, "+
 		"LeftDelim:%s, "+
 		"RightDelim:%s, "+
 		"FunctionDenylist:%s, "+
-		"SandboxPath:%s"+
+		"SandboxPath:%s, "+
+		"Sprig:%s"+
 		"}",
 		BoolGoString(c.Backup),
 		c.Command,
@@ -394,9 +507,13 @@ func (c *TemplateConfig) GoString() string {
 		StringGoString(c.Contents),
 		BoolGoString(c.CreateDestDirs),
 		StringGoString(c.Destination),
+		StringGoString(c.DestinationType),
 		BoolGoString(c.ErrMissingKey),
 		BoolGoString(c.ErrFatal),
 		c.Exec,
+		SignalGoString(c.ExecReloadSignal),
+		BoolGoString(c.ExecDiffFile),
+		c.Kubernetes,
 		FileModeGoString(c.Perms),
 		StringGoString(c.Source),
 		c.Wait,
@@ -404,6 +521,7 @@ func (c *TemplateConfig) GoString() string {
 		StringGoString(c.RightDelim),
 		combineLists(c.FunctionDenylist, c.FunctionDenylistDeprecated),
 		StringGoString(c.SandboxPath),
+		BoolGoString(c.Sprig),
 	)
 }
 
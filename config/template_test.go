@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"reflect"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -274,6 +275,48 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Exec: &ExecConfig{Command: []string{"command"}}},
 			&TemplateConfig{Exec: &ExecConfig{Command: []string{"command"}}},
 		},
+		{
+			"exec_reload_signal_overrides",
+			&TemplateConfig{ExecReloadSignal: Signal(syscall.SIGUSR1)},
+			&TemplateConfig{ExecReloadSignal: Signal(syscall.SIGUSR2)},
+			&TemplateConfig{ExecReloadSignal: Signal(syscall.SIGUSR2)},
+		},
+		{
+			"exec_reload_signal_empty_one",
+			&TemplateConfig{ExecReloadSignal: Signal(syscall.SIGUSR1)},
+			&TemplateConfig{},
+			&TemplateConfig{ExecReloadSignal: Signal(syscall.SIGUSR1)},
+		},
+		{
+			"exec_reload_signal_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{ExecReloadSignal: Signal(syscall.SIGUSR1)},
+			&TemplateConfig{ExecReloadSignal: Signal(syscall.SIGUSR1)},
+		},
+		{
+			"destination_type_overrides",
+			&TemplateConfig{DestinationType: String(DestinationTypeFile)},
+			&TemplateConfig{DestinationType: String(DestinationTypeConsulKV)},
+			&TemplateConfig{DestinationType: String(DestinationTypeConsulKV)},
+		},
+		{
+			"destination_type_empty_one",
+			&TemplateConfig{DestinationType: String(DestinationTypeStdout)},
+			&TemplateConfig{},
+			&TemplateConfig{DestinationType: String(DestinationTypeStdout)},
+		},
+		{
+			"exec_diff_file_overrides",
+			&TemplateConfig{ExecDiffFile: Bool(true)},
+			&TemplateConfig{ExecDiffFile: Bool(false)},
+			&TemplateConfig{ExecDiffFile: Bool(false)},
+		},
+		{
+			"exec_diff_file_empty_one",
+			&TemplateConfig{ExecDiffFile: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{ExecDiffFile: Bool(true)},
+		},
 		{
 			"perms_overrides",
 			&TemplateConfig{Perms: FileMode(0600)},
@@ -298,6 +341,18 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{Perms: FileMode(0600)},
 			&TemplateConfig{Perms: FileMode(0600)},
 		},
+		{
+			"sprig_overrides",
+			&TemplateConfig{Sprig: Bool(true)},
+			&TemplateConfig{Sprig: Bool(false)},
+			&TemplateConfig{Sprig: Bool(false)},
+		},
+		{
+			"sprig_empty_one",
+			&TemplateConfig{Sprig: Bool(true)},
+			&TemplateConfig{},
+			&TemplateConfig{Sprig: Bool(true)},
+		},
 		{
 			"source_overrides",
 			&TemplateConfig{Source: String("source")},
@@ -394,6 +449,24 @@ func TestTemplateConfig_Merge(t *testing.T) {
 			&TemplateConfig{RightDelim: String("right_delim")},
 			&TemplateConfig{RightDelim: String("right_delim")},
 		},
+		{
+			"function_denylist_empty_one",
+			&TemplateConfig{FunctionDenylist: []string{}},
+			&TemplateConfig{},
+			&TemplateConfig{FunctionDenylist: []string{}},
+		},
+		{
+			"function_denylist_empty_two",
+			&TemplateConfig{},
+			&TemplateConfig{FunctionDenylist: []string{}},
+			&TemplateConfig{FunctionDenylist: []string{}},
+		},
+		{
+			"function_denylist_merge",
+			&TemplateConfig{FunctionDenylist: []string{"plugin"}},
+			&TemplateConfig{FunctionDenylist: []string{"writeToFile"}},
+			&TemplateConfig{FunctionDenylist: []string{"plugin", "writeToFile"}},
+		},
 	}
 
 	for i, tc := range cases {
@@ -417,17 +490,19 @@ func TestTemplateConfig_Finalize(t *testing.T) {
 			"empty",
 			&TemplateConfig{},
 			&TemplateConfig{
-				Backup:         Bool(false),
-				Command:        []string{},
-				CommandTimeout: TimeDuration(DefaultTemplateCommandTimeout),
-				Contents:       String(""),
-				CreateDestDirs: Bool(true),
-				Destination:    String(""),
-				ErrMissingKey:  Bool(false),
-				ErrFatal:       Bool(true),
+				Backup:          Bool(false),
+				Command:         []string{},
+				CommandTimeout:  TimeDuration(DefaultTemplateCommandTimeout),
+				Contents:        String(""),
+				CreateDestDirs:  Bool(true),
+				Destination:     String(""),
+				DestinationType: String(DefaultDestinationType),
+				ErrMissingKey:   Bool(false),
+				ErrFatal:        Bool(true),
 				Exec: &ExecConfig{
-					Command: []string{},
-					Enabled: Bool(false),
+					Command:  []string{},
+					Critical: Bool(true),
+					Enabled:  Bool(false),
 					Env: &EnvConfig{
 						Denylist:            []string{},
 						DenylistDeprecated:  []string{},
@@ -436,11 +511,38 @@ func TestTemplateConfig_Finalize(t *testing.T) {
 						Allowlist:           []string{},
 						AllowlistDeprecated: []string{},
 					},
-					KillSignal:   Signal(DefaultExecKillSignal),
-					KillTimeout:  TimeDuration(DefaultExecKillTimeout),
+					KillSignal:  Signal(DefaultExecKillSignal),
+					KillTimeout: TimeDuration(DefaultExecKillTimeout),
+					Name:        String(""),
+					Readiness: &ReadinessConfig{
+						Command:          []string{},
+						Enabled:          Bool(false),
+						HTTP:             String(""),
+						Interval:         TimeDuration(DefaultReadinessInterval),
+						RestoreOnFailure: Bool(false),
+						TCP:              String(""),
+						Timeout:          TimeDuration(DefaultReadinessTimeout),
+					},
 					ReloadSignal: Signal(DefaultExecReloadSignal),
-					Splay:        TimeDuration(0 * time.Second),
-					Timeout:      TimeDuration(DefaultTemplateCommandTimeout),
+					Restart: &RestartConfig{
+						Policy:     String(DefaultRestartPolicy),
+						Attempts:   Int(DefaultRestartAttempts),
+						Window:     TimeDuration(DefaultRestartWindow),
+						Backoff:    TimeDuration(DefaultRestartBackoff),
+						MaxBackoff: TimeDuration(DefaultRestartMaxBackoff),
+					},
+					RestartStrategy: String(DefaultExecRestartStrategy),
+					Splay:           TimeDuration(0 * time.Second),
+					Templates:       []string{},
+					Timeout:         TimeDuration(DefaultTemplateCommandTimeout),
+				},
+				ExecReloadSignal: Signal(nil),
+				ExecDiffFile:     Bool(false),
+				Kubernetes: &KubernetesObjectConfig{
+					Key:             String(DefaultKubernetesKey),
+					Labels:          map[string]string{},
+					Annotations:     map[string]string{},
+					OwnerReferences: []*KubernetesOwnerReference{},
 				},
 				Perms:  FileMode(0),
 				Source: String(""),
@@ -451,9 +553,10 @@ func TestTemplateConfig_Finalize(t *testing.T) {
 				},
 				LeftDelim:                  String(""),
 				RightDelim:                 String(""),
-				FunctionDenylist:           []string{},
+				FunctionDenylist:           DefaultTemplateFunctionDenylist,
 				FunctionDenylistDeprecated: []string{},
 				SandboxPath:                String(""),
+				Sprig:                      Bool(false),
 			},
 		},
 	}
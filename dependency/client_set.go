@@ -20,8 +20,10 @@ import (
 type ClientSet struct {
 	sync.RWMutex
 
-	vault  *vaultClient
-	consul *consulClient
+	vault   *vaultClient
+	consul  *consulClient
+	http    *httpClient
+	plugins map[string]*PluginClient
 }
 
 // consulClient is a wrapper around a real Consul API client.
@@ -36,6 +38,14 @@ type vaultClient struct {
 	httpClient *http.Client
 }
 
+// httpClient is the client of the requests of HTTP dependencies.
+type httpClient struct {
+	client       *http.Client
+	transport    *http.Transport
+	headers      map[string]string
+	pollInterval time.Duration
+}
+
 // TransportDialer is an interface that allows passing a custom dialer function
 // to an HTTP client's transport config
 type TransportDialer interface {
@@ -96,6 +106,28 @@ type CreateVaultClientInput struct {
 	TransportTLSHandshakeTimeout time.Duration
 }
 
+// CreateHTTPClientInput is used as input to the CreateHTTPClient function.
+type CreateHTTPClientInput struct {
+	Headers      map[string]string
+	PollInterval time.Duration
+	Timeout      time.Duration
+	SSLEnabled   bool
+	SSLVerify    bool
+	SSLCert      string
+	SSLKey       string
+	SSLCACert    string
+	SSLCAPath    string
+	ServerName   string
+}
+
+// CreatePluginInput is used as input to the CreatePlugin function.
+type CreatePluginInput struct {
+	Name     string
+	Command  []string
+	Timeout  time.Duration
+	CacheTTL time.Duration
+}
+
 // NewClientSet creates a new client set that is ready to accept clients.
 func NewClientSet() *ClientSet {
 	return &ClientSet{}
@@ -338,6 +370,111 @@ func (c *ClientSet) CreateVaultClient(i *CreateVaultClientInput) error {
 	return nil
 }
 
+// CreateHTTPClient creates the client of HTTP dependencies from the given
+// input.
+func (c *ClientSet) CreateHTTPClient(i *CreateHTTPClientInput) error {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	// Configure SSL
+	if i.SSLEnabled {
+		var tlsConfig tls.Config
+
+		// Custom certificate or certificate and key
+		if i.SSLCert != "" && i.SSLKey != "" {
+			cert, err := tls.LoadX509KeyPair(i.SSLCert, i.SSLKey)
+			if err != nil {
+				return fmt.Errorf("client set: http: %s", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		} else if i.SSLCert != "" {
+			cert, err := tls.LoadX509KeyPair(i.SSLCert, i.SSLCert)
+			if err != nil {
+				return fmt.Errorf("client set: http: %s", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		// Custom CA certificate
+		if i.SSLCACert != "" || i.SSLCAPath != "" {
+			rootConfig := &rootcerts.Config{
+				CAFile: i.SSLCACert,
+				CAPath: i.SSLCAPath,
+			}
+			if err := rootcerts.ConfigureTLS(&tlsConfig, rootConfig); err != nil {
+				return fmt.Errorf("client set: http configuring TLS failed: %s", err)
+			}
+		}
+
+		// Construct all the certificates now
+		tlsConfig.BuildNameToCertificate()
+
+		// SSL verification
+		if i.ServerName != "" {
+			tlsConfig.ServerName = i.ServerName
+			tlsConfig.InsecureSkipVerify = false
+		}
+		if !i.SSLVerify {
+			log.Printf("[WARN] (clients) disabling http SSL verification")
+			tlsConfig.InsecureSkipVerify = true
+		}
+
+		// Save the TLS config on our transport
+		transport.TLSClientConfig = &tlsConfig
+	}
+
+	headers := make(map[string]string, len(i.Headers))
+	for k, v := range i.Headers {
+		headers[k] = v
+	}
+
+	// Save the data on ourselves
+	c.Lock()
+	c.http = &httpClient{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   i.Timeout,
+		},
+		transport:    transport,
+		headers:      headers,
+		pollInterval: i.PollInterval,
+	}
+	c.Unlock()
+
+	return nil
+}
+
+// CreatePlugin adds a client of a long-running plugin from the given input.
+// The plugin process is started when it is first called.
+func (c *ClientSet) CreatePlugin(i *CreatePluginInput) error {
+	if i.Name == "" {
+		return fmt.Errorf("plugin: missing name")
+	}
+	if len(i.Command) == 0 {
+		return fmt.Errorf("plugin %q: missing command", i.Name)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if c.plugins == nil {
+		c.plugins = make(map[string]*PluginClient)
+	}
+	if _, ok := c.plugins[i.Name]; ok {
+		return fmt.Errorf("plugin %q: already exists", i.Name)
+	}
+	c.plugins[i.Name] = newPluginClient(i)
+
+	return nil
+}
+
 // Consul returns the Consul client for this set.
 func (c *ClientSet) Consul() *consulapi.Client {
 	c.RLock()
@@ -352,7 +489,16 @@ func (c *ClientSet) Vault() *vaultapi.Client {
 	return c.vault.client
 }
 
-// Stop closes all idle connections for any attached clients.
+// Plugin returns the client of the named plugin, or nil if there is no such
+// plugin.
+func (c *ClientSet) Plugin(name string) *PluginClient {
+	c.RLock()
+	defer c.RUnlock()
+	return c.plugins[name]
+}
+
+// Stop closes all idle connections for any attached clients, and stops any
+// plugins.
 func (c *ClientSet) Stop() {
 	c.Lock()
 	defer c.Unlock()
@@ -364,4 +510,12 @@ func (c *ClientSet) Stop() {
 	if c.vault != nil {
 		c.vault.httpClient.Transport.(*http.Transport).CloseIdleConnections()
 	}
+
+	if c.http != nil {
+		c.http.transport.CloseIdleConnections()
+	}
+
+	for _, p := range c.plugins {
+		p.Stop()
+	}
 }
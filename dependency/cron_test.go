@@ -0,0 +1,126 @@
+package dependency
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    string
+		err  bool
+	}{
+		{
+			"every_minute",
+			"* * * * *",
+			false,
+		},
+		{
+			"lists_ranges_steps",
+			"*/15 9-17 1,15 * 1-5",
+			false,
+		},
+		{
+			"alias",
+			"@daily",
+			false,
+		},
+		{
+			"too_few_fields",
+			"* * * *",
+			true,
+		},
+		{
+			"out_of_range",
+			"60 * * * *",
+			true,
+		},
+		{
+			"bad_step",
+			"*/0 * * * *",
+			true,
+		},
+		{
+			"bad_value",
+			"a * * * *",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			_, err := parseCron(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+
+	// Wednesday
+	from := time.Date(2021, 3, 10, 10, 7, 30, 0, time.UTC)
+
+	cases := []struct {
+		name string
+		i    string
+		exp  time.Time
+	}{
+		{
+			"every_minute",
+			"* * * * *",
+			time.Date(2021, 3, 10, 10, 8, 0, 0, time.UTC),
+		},
+		{
+			"step",
+			"*/15 * * * *",
+			time.Date(2021, 3, 10, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			"next_day",
+			"0 9 * * *",
+			time.Date(2021, 3, 11, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			"day_of_week",
+			"30 8 * * 1",
+			time.Date(2021, 3, 15, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			"sunday_as_7",
+			"0 0 * * 7",
+			time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"day_of_month_or_week",
+			"0 0 1 * 5",
+			time.Date(2021, 3, 12, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"next_year",
+			"@yearly",
+			time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			"never",
+			"0 0 31 2 *",
+			time.Time{},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c, err := parseCron(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act := c.Next(from); !act.Equal(tc.exp) {
+				t.Errorf("\nexp: %s\nact: %s", tc.exp, act)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package dependency
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/pkg/errors"
+)
+
+const (
+	// dnsResolvConf is the resolver configuration that DNS dependencies use.
+	dnsResolvConf = "/etc/resolv.conf"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*DNSQuery)(nil)
+
+	// DNSQueryMinTTL is the minimum amount of time between queries of a DNS
+	// dependency, for records with a lower TTL.
+	DNSQueryMinTTL = 1 * time.Second
+
+	// DNSQueryNegativeTTL is the amount of time between queries of a DNS
+	// dependency whose name has no records, if the server does not give one.
+	DNSQueryNegativeTTL = 30 * time.Second
+
+	// DNSQueryRetryInterval is the amount of time between queries of a DNS
+	// dependency after an error.
+	DNSQueryRetryInterval = 5 * time.Second
+)
+
+// DNSSRV is an SRV record.
+type DNSSRV struct {
+	Target   string
+	Port     uint16
+	Priority uint16
+	Weight   uint16
+}
+
+// DNSQuery represents the A, AAAA, SRV or TXT records of a name. The name is
+// resolved again when the TTL of its records expires.
+type DNSQuery struct {
+	stopCh chan struct{}
+
+	qtype uint16
+	name  string
+
+	// config is the resolver configuration, which is read from
+	// dnsResolvConf on the first fetch.
+	config *dns.ClientConfig
+
+	// data, lastIndex and ttl are those of the last answer.
+	data      interface{}
+	lastIndex uint64
+	ttl       time.Duration
+}
+
+// NewDNSAQuery creates a DNS dependency for the IPv4 addresses of a name.
+func NewDNSAQuery(s string) (*DNSQuery, error) {
+	return newDNSQuery(dns.TypeA, s)
+}
+
+// NewDNSAAAAQuery creates a DNS dependency for the IPv6 addresses of a name.
+func NewDNSAAAAQuery(s string) (*DNSQuery, error) {
+	return newDNSQuery(dns.TypeAAAA, s)
+}
+
+// NewDNSSRVQuery creates a DNS dependency for the SRV records of a name, such
+// as "_ldap._tcp.example.com".
+func NewDNSSRVQuery(s string) (*DNSQuery, error) {
+	return newDNSQuery(dns.TypeSRV, s)
+}
+
+// NewDNSTXTQuery creates a DNS dependency for the TXT records of a name.
+func NewDNSTXTQuery(s string) (*DNSQuery, error) {
+	return newDNSQuery(dns.TypeTXT, s)
+}
+
+func newDNSQuery(qtype uint16, s string) (*DNSQuery, error) {
+	s = strings.TrimSpace(s)
+	if _, ok := dns.IsDomainName(s); !ok || s == "" || s == "." {
+		return nil, fmt.Errorf("dns%s: invalid format: %q", dns.TypeToString[qtype], s)
+	}
+
+	return &DNSQuery{
+		stopCh: make(chan struct{}, 1),
+		qtype:  qtype,
+		name:   s,
+	}, nil
+}
+
+// Fetch resolves the name and returns its records: IP addresses for A and
+// AAAA records, *DNSSRV for SRV records, and the joined strings of each TXT
+// record. Records are sorted, so only changes in the answer are reported. If
+// this is not the first fetch, it waits for the TTL of the last answer first.
+// Errors after the first answer are logged and the last answer is kept.
+func (d *DNSQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	if opts.WaitIndex != 0 {
+		log.Printf("[TRACE] %s: resolving again in %s", d, d.ttl)
+
+		timer := time.NewTimer(d.ttl)
+		defer timer.Stop()
+
+		select {
+		case <-d.stopCh:
+			log.Printf("[TRACE] %s: stopped", d)
+			return nil, nil, ErrStopped
+		case <-timer.C:
+		}
+	}
+
+	data, ttl, err := d.resolve()
+	if err != nil {
+		if d.lastIndex == 0 {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+		log.Printf("[WARN] %s: keeping the last answer: %s", d, err)
+		d.ttl = DNSQueryRetryInterval
+	} else {
+		if ttl < DNSQueryMinTTL {
+			ttl = DNSQueryMinTTL
+		}
+		d.ttl = ttl
+
+		if d.lastIndex == 0 || !reflect.DeepEqual(data, d.data) {
+			log.Printf("[TRACE] %s: reported change", d)
+			d.data = data
+			d.lastIndex = uint64(time.Now().UnixNano())
+		}
+	}
+
+	return d.data, &ResponseMetadata{
+		LastIndex: d.lastIndex,
+	}, nil
+}
+
+// resolve queries the names of the search list of the resolver configuration
+// in order, and returns the records of the first that has any, with the
+// lowest TTL of the records. If no name has records, it returns no records
+// with the negative TTL of the zone.
+func (d *DNSQuery) resolve() (interface{}, time.Duration, error) {
+	if d.config == nil {
+		config, err := dns.ClientConfigFromFile(dnsResolvConf)
+		if err != nil {
+			return nil, 0, err
+		}
+		d.config = config
+	}
+
+	ttl := DNSQueryNegativeTTL
+	for _, name := range d.config.NameList(d.name) {
+		msg, err := d.exchange(name)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		switch msg.Rcode {
+		case dns.RcodeSuccess, dns.RcodeNameError:
+		default:
+			return nil, 0, fmt.Errorf("query for %s failed: %s", name, dns.RcodeToString[msg.Rcode])
+		}
+
+		if data, answerTTL, ok := d.records(msg); ok {
+			return data, answerTTL, nil
+		}
+
+		// The negative TTL is the minimum of the SOA record of the zone.
+		for _, rr := range msg.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				ttl = time.Duration(minUint32(soa.Hdr.Ttl, soa.Minttl)) * time.Second
+			}
+		}
+	}
+
+	data, _, _ := d.records(new(dns.Msg))
+	return data, ttl, nil
+}
+
+// exchange sends the query to the servers of the resolver configuration until
+// one answers, over TCP if the answer is truncated.
+func (d *DNSQuery) exchange(name string) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, d.qtype)
+
+	timeout := time.Duration(d.config.Timeout) * time.Second
+	attempts := d.config.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		for _, server := range d.config.Servers {
+			addr := net.JoinHostPort(server, d.config.Port)
+
+			c := &dns.Client{Timeout: timeout}
+			msg, _, err := c.Exchange(m, addr)
+			if err == nil && msg.Truncated {
+				c.Net = "tcp"
+				msg, _, err = c.Exchange(m, addr)
+			}
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			return msg, nil
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no servers in %s", dnsResolvConf)
+	}
+	return nil, lastErr
+}
+
+// records returns the sorted records of the answer, and their lowest TTL. It
+// returns false if the answer has no records of the type of the query.
+func (d *DNSQuery) records(msg *dns.Msg) (interface{}, time.Duration, bool) {
+	var ttl uint32
+	var found bool
+	addTTL := func(rr dns.RR) {
+		if !found || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+		found = true
+	}
+
+	switch d.qtype {
+	case dns.TypeSRV:
+		srvs := make([]*DNSSRV, 0, len(msg.Answer))
+		for _, rr := range msg.Answer {
+			if srv, ok := rr.(*dns.SRV); ok {
+				addTTL(rr)
+				srvs = append(srvs, &DNSSRV{
+					Target:   srv.Target,
+					Port:     srv.Port,
+					Priority: srv.Priority,
+					Weight:   srv.Weight,
+				})
+			}
+		}
+		sort.Stable(ByPriorityWeight(srvs))
+		return srvs, time.Duration(ttl) * time.Second, found
+
+	default:
+		values := make([]string, 0, len(msg.Answer))
+		for _, rr := range msg.Answer {
+			switch rr := rr.(type) {
+			case *dns.A:
+				if d.qtype == dns.TypeA {
+					addTTL(rr)
+					values = append(values, rr.A.String())
+				}
+			case *dns.AAAA:
+				if d.qtype == dns.TypeAAAA {
+					addTTL(rr)
+					values = append(values, rr.AAAA.String())
+				}
+			case *dns.TXT:
+				if d.qtype == dns.TypeTXT {
+					addTTL(rr)
+					values = append(values, strings.Join(rr.Txt, ""))
+				}
+			}
+		}
+		sort.Strings(values)
+		return values, time.Duration(ttl) * time.Second, found
+	}
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *DNSQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the dependency's fetch function.
+func (d *DNSQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *DNSQuery) String() string {
+	return fmt.Sprintf("dns%s(%s)", dns.TypeToString[d.qtype], d.name)
+}
+
+// Type returns the type of this dependency.
+func (d *DNSQuery) Type() Type {
+	return TypeLocal
+}
+
+// ByPriorityWeight is a sortable slice of SRV records, by priority and then
+// by descending weight, target and port.
+type ByPriorityWeight []*DNSSRV
+
+func (s ByPriorityWeight) Len() int      { return len(s) }
+func (s ByPriorityWeight) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s ByPriorityWeight) Less(i, j int) bool {
+	if s[i].Priority != s[j].Priority {
+		return s[i].Priority < s[j].Priority
+	}
+	if s[i].Weight != s[j].Weight {
+		return s[i].Weight > s[j].Weight
+	}
+	if s[i].Target != s[j].Target {
+		return s[i].Target < s[j].Target
+	}
+	return s[i].Port < s[j].Port
+}
+
+func minUint32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
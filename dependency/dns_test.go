@@ -0,0 +1,391 @@
+package dependency
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDNSServer is a local DNS server that answers with its records.
+type testDNSServer struct {
+	sync.Mutex
+	records map[string][]string
+	rcode   int
+}
+
+// set replaces the records of the server, in the zone file format.
+func (s *testDNSServer) set(records ...string) {
+	s.Lock()
+	defer s.Unlock()
+	s.records = make(map[string][]string)
+	for _, r := range records {
+		rr, err := dns.NewRR(r)
+		if err != nil {
+			panic(err)
+		}
+		key := rr.Header().Name + dns.TypeToString[rr.Header().Rrtype]
+		s.records[key] = append(s.records[key], r)
+	}
+	s.rcode = dns.RcodeSuccess
+}
+
+func (s *testDNSServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	s.Lock()
+	defer s.Unlock()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	q := r.Question[0]
+	for _, record := range s.records[q.Name+dns.TypeToString[q.Qtype]] {
+		rr, _ := dns.NewRR(record)
+		m.Answer = append(m.Answer, rr)
+	}
+	m.Rcode = s.rcode
+	if len(m.Answer) == 0 && m.Rcode == dns.RcodeSuccess {
+		m.Rcode = dns.RcodeNameError
+		soa, _ := dns.NewRR("example.com. 3600 IN SOA ns.example.com. admin.example.com. 1 3600 600 86400 60")
+		m.Ns = append(m.Ns, soa)
+	}
+	w.WriteMsg(m)
+}
+
+// startDNSServer starts a local DNS server, and returns it and a resolver
+// configuration for it.
+func startDNSServer(t *testing.T, records ...string) (*testDNSServer, *dns.ClientConfig) {
+	s := &testDNSServer{}
+	s.set(records...)
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{
+		PacketConn:        pc,
+		Handler:           s,
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+
+	return s, &dns.ClientConfig{
+		Servers:  []string{"127.0.0.1"},
+		Port:     strconv.Itoa(pc.LocalAddr().(*net.UDPAddr).Port),
+		Ndots:    1,
+		Timeout:  1,
+		Attempts: 1,
+	}
+}
+
+func TestNewDNSQuery(t *testing.T) {
+
+	cases := []struct {
+		name string
+		f    func(string) (*DNSQuery, error)
+		i    string
+		exp  *DNSQuery
+		err  bool
+	}{
+		{
+			"empty",
+			NewDNSAQuery,
+			"",
+			nil,
+			true,
+		},
+		{
+			"root",
+			NewDNSAQuery,
+			".",
+			nil,
+			true,
+		},
+		{
+			"invalid",
+			NewDNSAQuery,
+			"a..example.com",
+			nil,
+			true,
+		},
+		{
+			"a",
+			NewDNSAQuery,
+			"example.com",
+			&DNSQuery{
+				qtype: dns.TypeA,
+				name:  "example.com",
+			},
+			false,
+		},
+		{
+			"aaaa",
+			NewDNSAAAAQuery,
+			"example.com",
+			&DNSQuery{
+				qtype: dns.TypeAAAA,
+				name:  "example.com",
+			},
+			false,
+		},
+		{
+			"srv",
+			NewDNSSRVQuery,
+			"_ldap._tcp.example.com.",
+			&DNSQuery{
+				qtype: dns.TypeSRV,
+				name:  "_ldap._tcp.example.com.",
+			},
+			false,
+		},
+		{
+			"txt",
+			NewDNSTXTQuery,
+			"example.com",
+			&DNSQuery{
+				qtype: dns.TypeTXT,
+				name:  "example.com",
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := tc.f(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestDNSQuery_Fetch(t *testing.T) {
+
+	_, config := startDNSServer(t,
+		"db.example.com. 60 IN A 10.0.0.2",
+		"db.example.com. 30 IN A 10.0.0.1",
+		"db.example.com. 60 IN AAAA 2001:db8::1",
+		"db.svc.local. 60 IN A 10.1.0.1",
+		"_ldap._tcp.example.com. 60 IN SRV 20 10 389 ldap3.example.com.",
+		"_ldap._tcp.example.com. 60 IN SRV 10 10 389 ldap2.example.com.",
+		"_ldap._tcp.example.com. 60 IN SRV 10 50 389 ldap1.example.com.",
+		`example.com. 60 IN TXT "v=spf1 " "-all"`,
+		`example.com. 60 IN TXT "site-verification=abc"`,
+	)
+	searchConfig := *config
+	searchConfig.Search = []string{"svc.local"}
+
+	cases := []struct {
+		name   string
+		f      func(string) (*DNSQuery, error)
+		i      string
+		config *dns.ClientConfig
+		exp    interface{}
+		ttl    time.Duration
+	}{
+		{
+			"a",
+			NewDNSAQuery,
+			"db.example.com",
+			config,
+			[]string{"10.0.0.1", "10.0.0.2"},
+			30 * time.Second,
+		},
+		{
+			"aaaa",
+			NewDNSAAAAQuery,
+			"db.example.com",
+			config,
+			[]string{"2001:db8::1"},
+			60 * time.Second,
+		},
+		{
+			"srv",
+			NewDNSSRVQuery,
+			"_ldap._tcp.example.com",
+			config,
+			[]*DNSSRV{
+				{Target: "ldap1.example.com.", Port: 389, Priority: 10, Weight: 50},
+				{Target: "ldap2.example.com.", Port: 389, Priority: 10, Weight: 10},
+				{Target: "ldap3.example.com.", Port: 389, Priority: 20, Weight: 10},
+			},
+			60 * time.Second,
+		},
+		{
+			"txt",
+			NewDNSTXTQuery,
+			"example.com",
+			config,
+			[]string{"site-verification=abc", "v=spf1 -all"},
+			60 * time.Second,
+		},
+		{
+			"search",
+			NewDNSAQuery,
+			"db",
+			&searchConfig,
+			[]string{"10.1.0.1"},
+			60 * time.Second,
+		},
+		{
+			"no_records",
+			NewDNSAQuery,
+			"missing.example.com",
+			config,
+			[]string{},
+			60 * time.Second,
+		},
+		{
+			"no_srv_records",
+			NewDNSSRVQuery,
+			"_missing._tcp.example.com",
+			config,
+			[]*DNSSRV{},
+			60 * time.Second,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := tc.f(tc.i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.config = tc.config
+
+			act, _, err := d.Fetch(nil, &QueryOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, act)
+			assert.Equal(t, tc.ttl, d.ttl)
+		})
+	}
+}
+
+func TestDNSQuery_Fetch_resolvesAgain(t *testing.T) {
+
+	s, config := startDNSServer(t, "db.example.com. 0 IN A 10.0.0.1")
+
+	d, err := NewDNSAQuery("db.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = config
+
+	act, rm, err := d.Fetch(nil, &QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"10.0.0.1"}, act)
+	assert.Equal(t, DNSQueryMinTTL, d.ttl)
+
+	// The same answer keeps the index, so the view does not store it.
+	act, rm2, err := d.Fetch(nil, &QueryOptions{WaitIndex: rm.LastIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"10.0.0.1"}, act)
+	assert.Equal(t, rm.LastIndex, rm2.LastIndex)
+
+	s.set("db.example.com. 0 IN A 10.0.0.1", "db.example.com. 0 IN A 10.0.0.3")
+	act, rm3, err := d.Fetch(nil, &QueryOptions{WaitIndex: rm2.LastIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.3"}, act)
+	assert.True(t, rm3.LastIndex > rm2.LastIndex)
+
+	// Errors keep the last answer.
+	s.Lock()
+	s.rcode = dns.RcodeServerFailure
+	s.Unlock()
+	act, rm4, err := d.Fetch(nil, &QueryOptions{WaitIndex: rm3.LastIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.3"}, act)
+	assert.Equal(t, rm3.LastIndex, rm4.LastIndex)
+	assert.Equal(t, DNSQueryRetryInterval, d.ttl)
+}
+
+func TestDNSQuery_Fetch_error(t *testing.T) {
+
+	s, config := startDNSServer(t)
+	s.Lock()
+	s.rcode = dns.RcodeServerFailure
+	s.Unlock()
+
+	d, err := NewDNSAQuery("db.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.config = config
+
+	if _, _, err := d.Fetch(nil, &QueryOptions{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDNSQuery_Stop(t *testing.T) {
+
+	d, err := NewDNSAQuery("db.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.ttl = time.Minute
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := d.Fetch(nil, &QueryOptions{WaitIndex: 1})
+		errCh <- err
+	}()
+	d.Stop()
+
+	select {
+	case err := <-errCh:
+		if err != ErrStopped {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("did not stop")
+	}
+}
+
+func TestDNSQuery_String(t *testing.T) {
+
+	cases := []struct {
+		name string
+		f    func(string) (*DNSQuery, error)
+		exp  string
+	}{
+		{"a", NewDNSAQuery, "dnsA(example.com)"},
+		{"aaaa", NewDNSAAAAQuery, "dnsAAAA(example.com)"},
+		{"srv", NewDNSSRVQuery, "dnsSRV(example.com)"},
+		{"txt", NewDNSTXTQuery, "dnsTXT(example.com)"},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := tc.f("example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
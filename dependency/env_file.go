@@ -0,0 +1,174 @@
+package dependency
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*EnvFileQuery)(nil)
+
+	// envFileKeyRe is the format of a key in an env file.
+	envFileKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+)
+
+// EnvFileQuery represents the variables of a local env file in the dotenv
+// format. The file is watched like a file dependency.
+type EnvFileQuery struct {
+	file *FileQuery
+}
+
+// NewEnvFileQuery creates an env file dependency from the given path.
+func NewEnvFileQuery(s string) (*EnvFileQuery, error) {
+	file, err := NewFileQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("envFile: invalid format: %q", s)
+	}
+
+	return &EnvFileQuery{
+		file: file,
+	}, nil
+}
+
+// Fetch reads the env file once it changes and returns its variables.
+func (d *EnvFileQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	data, rm, err := d.file.Fetch(clients, opts)
+	if err != nil {
+		if err == ErrStopped {
+			return nil, nil, err
+		}
+		return nil, nil, errors.Wrap(errors.Cause(err), d.String())
+	}
+
+	vars, err := parseEnvFile(data.(string))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+	return vars, rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *EnvFileQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the dependency's fetch function.
+func (d *EnvFileQuery) Stop() {
+	d.file.Stop()
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *EnvFileQuery) String() string {
+	return fmt.Sprintf("envFile(%s)", d.file.path)
+}
+
+// Type returns the type of this dependency.
+func (d *EnvFileQuery) Type() Type {
+	return TypeLocal
+}
+
+// parseEnvFile parses the contents of an env file. Each line is a KEY=value
+// pair, optionally prefixed with "export". Values may be unquoted, in which
+// case a " #" starts a comment; single-quoted, which are taken literally; or
+// double-quoted, which may span several lines and contain the escapes \n, \r,
+// \t, \" and \\. Blank lines and lines starting with "#" are ignored.
+// Variables are not expanded.
+func parseEnvFile(s string) (map[string]string, error) {
+	vars := make(map[string]string)
+
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimSpace(line[len("export"):])
+		}
+
+		idx := strings.Index(line, "=")
+		if idx == -1 {
+			return nil, fmt.Errorf("line %d: expected KEY=value", lineNo)
+		}
+		key := strings.TrimSpace(line[:idx])
+		if !envFileKeyRe.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", lineNo, key)
+		}
+		value := strings.TrimSpace(line[idx+1:])
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", lineNo)
+			}
+			value = value[1 : end+1]
+
+		case strings.HasPrefix(value, `"`):
+			// Double-quoted values continue on the next lines until the
+			// closing quote.
+			raw := value[1:]
+			for {
+				v, ok := unquoteEnvValue(raw)
+				if ok {
+					value = v
+					break
+				}
+				i++
+				if i == len(lines) {
+					return nil, fmt.Errorf("line %d: unterminated double quote", lineNo)
+				}
+				raw += "\n" + lines[i]
+			}
+
+		default:
+			if idx := strings.Index(value, " #"); idx != -1 {
+				value = strings.TrimSpace(value[:idx])
+			}
+		}
+
+		vars[key] = value
+	}
+
+	return vars, nil
+}
+
+// unquoteEnvValue returns the value up to the first unescaped double quote,
+// with its escapes replaced. It returns false if there is no closing quote.
+func unquoteEnvValue(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), true
+		case '\\':
+			if i+1 == len(s) {
+				b.WriteByte(c)
+				continue
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
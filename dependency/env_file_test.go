@@ -0,0 +1,182 @@
+package dependency
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnvFile(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    string
+		exp  map[string]string
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			map[string]string{},
+			false,
+		},
+		{
+			"simple",
+			"A=1\nB = two \n\n# comment\nexport C=3\n",
+			map[string]string{"A": "1", "B": "two", "C": "3"},
+			false,
+		},
+		{
+			"empty_value",
+			"A=",
+			map[string]string{"A": ""},
+			false,
+		},
+		{
+			"inline_comment",
+			"A=1 # one\nB=a#b",
+			map[string]string{"A": "1", "B": "a#b"},
+			false,
+		},
+		{
+			"single_quoted",
+			`A='a \n "b" # c'`,
+			map[string]string{"A": `a \n "b" # c`},
+			false,
+		},
+		{
+			"double_quoted",
+			`A="a\n\"b\"\t\\ # c" # comment`,
+			map[string]string{"A": "a\n\"b\"\t\\ # c"},
+			false,
+		},
+		{
+			"multiline",
+			"KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nB=2",
+			map[string]string{"KEY": "-----BEGIN KEY-----\nabc\n-----END KEY-----", "B": "2"},
+			false,
+		},
+		{
+			"crlf",
+			"A=1\r\nB=2\r\n",
+			map[string]string{"A": "1", "B": "2"},
+			false,
+		},
+		{
+			"no_equals",
+			"A",
+			nil,
+			true,
+		},
+		{
+			"invalid_key",
+			"1A=1",
+			nil,
+			true,
+		},
+		{
+			"unterminated_single_quote",
+			"A='1",
+			nil,
+			true,
+		},
+		{
+			"unterminated_double_quote",
+			"A=\"1\nB=2",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := parseEnvFile(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestEnvFileQuery_Fetch(t *testing.T) {
+
+	t.Run("non_existent", func(t *testing.T) {
+		d, err := NewEnvFileQuery("/not/a/real/path/ever")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = d.Fetch(nil, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "envFile(") {
+			t.Fatalf("expected envFile error, got %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.env")
+		if err := ioutil.WriteFile(path, []byte("A"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		d, err := NewEnvFileQuery(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := d.Fetch(nil, nil); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("fires_changes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.env")
+		if err := ioutil.WriteFile(path, []byte("A=1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := NewEnvFileQuery(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Stop()
+
+		dataCh := make(chan interface{}, 1)
+		go func() {
+			for {
+				data, _, err := d.Fetch(nil, nil)
+				if err != nil {
+					return
+				}
+				dataCh <- data
+			}
+		}()
+
+		expect := func(exp map[string]string) {
+			select {
+			case data := <-dataCh:
+				assert.Equal(t, exp, data)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("expected %v", exp)
+			}
+		}
+
+		expect(map[string]string{"A": "1"})
+
+		if err := ioutil.WriteFile(path, []byte("A=2\nB=3"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		expect(map[string]string{"A": "2", "B": "3"})
+	})
+}
+
+func TestEnvFileQuery_String(t *testing.T) {
+
+	d, err := NewEnvFileQuery("/run/app.env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "envFile(/run/app.env)", d.String())
+}
@@ -15,8 +15,9 @@ var (
 	// Ensure implements
 	_ Dependency = (*FileQuery)(nil)
 
-	// FileQuerySleepTime is the amount of time to sleep between queries, since
-	// the fsnotify library is not compatible with solaris and other OSes yet.
+	// FileQuerySleepTime is the amount of time to sleep between queries of
+	// files that cannot be watched for events, such as on OSes other than
+	// Linux, or files in directories that do not exist yet.
 	FileQuerySleepTime = 2 * time.Second
 )
 
@@ -63,7 +64,12 @@ func (d *FileQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{},
 		}
 
 		d.stat = r.stat
-		return respWithMetadata(string(data))
+
+		// Events may report changes several times per second, so the index
+		// must be more precise than the one of respWithMetadata.
+		return string(data), &ResponseMetadata{
+			LastIndex: uint64(time.Now().UnixNano()),
+		}, nil
 	}
 }
 
@@ -92,14 +98,26 @@ type watchResult struct {
 	err  error
 }
 
-// watch watchers the file for changes
+// watch watchers the file for changes. The file is checked when there is an
+// event in its directory, or every FileQuerySleepTime if there are no events.
 func (d *FileQuery) watch(lastStat os.FileInfo) <-chan *watchResult {
 	ch := make(chan *watchResult, 1)
+	w := getFileWatcher()
 
 	go func(lastStat os.FileInfo) {
 		for {
+			// Subscribe before the stat, so changes in between are not
+			// missed. Subscribe again every time, since a swapped symlink
+			// may point to another directory.
+			var events <-chan struct{}
+			cancel := func() {}
+			if w != nil {
+				events, cancel = w.subscribe(d.path)
+			}
+
 			stat, err := os.Stat(d.path)
 			if err != nil {
+				cancel()
 				select {
 				case <-d.stopCh:
 					return
@@ -109,10 +127,12 @@ func (d *FileQuery) watch(lastStat os.FileInfo) <-chan *watchResult {
 			}
 
 			changed := lastStat == nil ||
+				!os.SameFile(lastStat, stat) ||
 				lastStat.Size() != stat.Size() ||
 				lastStat.ModTime() != stat.ModTime()
 
 			if changed {
+				cancel()
 				select {
 				case <-d.stopCh:
 					return
@@ -121,7 +141,22 @@ func (d *FileQuery) watch(lastStat os.FileInfo) <-chan *watchResult {
 				}
 			}
 
-			time.Sleep(FileQuerySleepTime)
+			if events == nil {
+				select {
+				case <-d.stopCh:
+					return
+				case <-time.After(FileQuerySleepTime):
+				}
+				continue
+			}
+
+			select {
+			case <-d.stopCh:
+				cancel()
+				return
+			case <-events:
+				cancel()
+			}
 		}
 	}(lastStat)
 
@@ -0,0 +1,176 @@
+package dependency
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	// sharedFileWatcher is the watcher shared by all file dependencies, so
+	// they use a single inotify instance. It is nil if file events are not
+	// supported, in which case files are polled every FileQuerySleepTime.
+	sharedFileWatcher     *fileWatcher
+	sharedFileWatcherOnce sync.Once
+)
+
+// fileWatcher notifies subscribers of events in the directories of the files
+// they watch. Directories are watched instead of files, so that files which
+// are replaced, by an atomic rename or by swapping a symlink like Kubernetes
+// does for ConfigMaps, are still watched.
+type fileWatcher struct {
+	sync.Mutex
+
+	watcher *fsnotify.Watcher
+
+	// subs are the channels of the subscribers to each directory.
+	subs map[string]map[chan struct{}]struct{}
+}
+
+// getFileWatcher returns the shared file watcher, creating it on first use.
+// It returns nil if file events are not supported.
+func getFileWatcher() *fileWatcher {
+	sharedFileWatcherOnce.Do(func() {
+		if !fileEventsSupported {
+			return
+		}
+
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.Printf("[WARN] (file) failed to watch files, polling every %s: %s",
+				FileQuerySleepTime, err)
+			return
+		}
+
+		sharedFileWatcher = &fileWatcher{
+			watcher: w,
+			subs:    make(map[string]map[chan struct{}]struct{}),
+		}
+		go sharedFileWatcher.run()
+	})
+	return sharedFileWatcher
+}
+
+// subscribe returns a channel that receives a value when there is an event in
+// the directory of any of the given paths, or in the directory of the file it
+// links to, and a function to unsubscribe. It returns a nil channel if the
+// directories cannot be watched.
+func (w *fileWatcher) subscribe(paths ...string) (<-chan struct{}, func()) {
+	var dirs []string
+	seen := make(map[string]bool)
+	addDir := func(dir string) {
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, path := range paths {
+		addDir(filepath.Dir(path))
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			addDir(filepath.Dir(resolved))
+		}
+	}
+
+	ch := make(chan struct{}, 1)
+
+	w.Lock()
+	defer w.Unlock()
+
+	var added []string
+	for _, dir := range dirs {
+		if len(w.subs[dir]) == 0 {
+			if err := w.watcher.Add(dir); err != nil {
+				log.Printf("[DEBUG] (file) failed to watch %s, polling: %s", dir, err)
+				for _, dir := range added {
+					w.unsubscribeLocked(dir, ch)
+				}
+				return nil, func() {}
+			}
+			w.subs[dir] = make(map[chan struct{}]struct{})
+		}
+		w.subs[dir][ch] = struct{}{}
+		added = append(added, dir)
+	}
+
+	return ch, func() {
+		w.Lock()
+		defer w.Unlock()
+		for _, dir := range dirs {
+			w.unsubscribeLocked(dir, ch)
+		}
+	}
+}
+
+// unsubscribeLocked removes the subscriber from the directory, and stops
+// watching the directory if it was the last one. The lock must be held.
+func (w *fileWatcher) unsubscribeLocked(dir string, ch chan struct{}) {
+	subs, ok := w.subs[dir]
+	if !ok {
+		return
+	}
+	delete(subs, ch)
+	if len(subs) == 0 {
+		delete(w.subs, dir)
+		w.watcher.Remove(dir)
+	}
+}
+
+// run notifies subscribers of events until the watcher is closed.
+func (w *fileWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been lost, so everyone has to check their files.
+			log.Printf("[WARN] (file) watch error: %s", err)
+			w.notifyAll()
+		}
+	}
+}
+
+// handle notifies the subscribers of the directory of the event.
+func (w *fileWatcher) handle(event fsnotify.Event) {
+	w.Lock()
+	defer w.Unlock()
+
+	notify(w.subs[filepath.Dir(event.Name)])
+
+	// A watched directory that is removed or renamed is no longer watched by
+	// inotify, so it has to be watched again by the next subscribe.
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		if subs, ok := w.subs[event.Name]; ok {
+			notify(subs)
+			delete(w.subs, event.Name)
+			w.watcher.Remove(event.Name)
+		}
+	}
+}
+
+// notifyAll notifies all subscribers.
+func (w *fileWatcher) notifyAll() {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, subs := range w.subs {
+		notify(subs)
+	}
+}
+
+// notify sends to the channels that have not been notified yet.
+func notify(subs map[chan struct{}]struct{}) {
+	for ch := range subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
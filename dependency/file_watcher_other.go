@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package dependency
+
+// fileEventsSupported is true if file dependencies are watched with inotify
+// instead of polling.
+const fileEventsSupported = false
@@ -0,0 +1,129 @@
+//go:build linux
+// +build linux
+
+package dependency
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileQuery_Fetch_events(t *testing.T) {
+	if getFileWatcher() == nil {
+		t.Skip("file events are not supported")
+	}
+
+	// Changes must be noticed from events, not by polling.
+	defer func(d time.Duration) { FileQuerySleepTime = d }(FileQuerySleepTime)
+	FileQuerySleepTime = time.Minute
+
+	write := func(t *testing.T, path, contents string) {
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// fetch returns a channel that receives the data of each fetch.
+	fetch := func(t *testing.T, path string) <-chan interface{} {
+		d, err := NewFileQuery(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(d.Stop)
+
+		dataCh := make(chan interface{}, 1)
+		go func() {
+			for {
+				data, _, err := d.Fetch(nil, nil)
+				if err != nil {
+					return
+				}
+				dataCh <- data
+			}
+		}()
+		return dataCh
+	}
+
+	expect := func(t *testing.T, dataCh <-chan interface{}, exp string) {
+		select {
+		case data := <-dataCh:
+			assert.Equal(t, exp, data)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %q", exp)
+		}
+	}
+
+	t.Run("write", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "file")
+		write(t, path, "hello")
+
+		dataCh := fetch(t, path)
+		expect(t, dataCh, "hello")
+
+		write(t, path, "goodbye")
+		expect(t, dataCh, "goodbye")
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "file")
+		write(t, path, "hello")
+
+		dataCh := fetch(t, path)
+		expect(t, dataCh, "hello")
+
+		write(t, path+".tmp", "goodbye")
+		if err := os.Rename(path+".tmp", path); err != nil {
+			t.Fatal(err)
+		}
+		expect(t, dataCh, "goodbye")
+	})
+
+	t.Run("symlink_swap", func(t *testing.T) {
+		// This is the layout of a Kubernetes ConfigMap volume, which is
+		// updated by swapping the "..data" symlink to a new directory.
+		dir := t.TempDir()
+		for _, version := range []string{"..v1", "..v2"} {
+			if err := os.Mkdir(filepath.Join(dir, version), 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write(t, filepath.Join(dir, "..v1", "key"), "hello")
+		write(t, filepath.Join(dir, "..v2", "key"), "goodbye")
+		if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join("..data", "key"), filepath.Join(dir, "key")); err != nil {
+			t.Fatal(err)
+		}
+
+		dataCh := fetch(t, filepath.Join(dir, "key"))
+		expect(t, dataCh, "hello")
+
+		if err := os.Symlink("..v2", filepath.Join(dir, "..data_tmp")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.RemoveAll(filepath.Join(dir, "..v1")); err != nil {
+			t.Fatal(err)
+		}
+		expect(t, dataCh, "goodbye")
+	})
+
+	t.Run("missing_directory_polls", func(t *testing.T) {
+		w := getFileWatcher()
+		events, cancel := w.subscribe("/not/a/real/path/ever")
+		defer cancel()
+		if events != nil {
+			t.Fatal("expected no events for a missing directory")
+		}
+	})
+}
@@ -0,0 +1,337 @@
+package dependency
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*FilesQuery)(nil)
+)
+
+// File is a file matched by a FilesQuery.
+type File struct {
+	// Name is the base name of the file.
+	Name string
+
+	// Path is the path of the file.
+	Path string
+
+	// Contents are the contents of the file.
+	Contents string
+
+	// ModTime is the time the file was last modified.
+	ModTime time.Time
+}
+
+// FilesQuery represents the local files that match a glob pattern, or the
+// files in a directory. Directories themselves are not included.
+type FilesQuery struct {
+	stopCh chan struct{}
+
+	pattern string
+	dir     bool
+	sandbox string
+	stats   []fileStat
+}
+
+// fileStat is the stat of a matched file.
+type fileStat struct {
+	path string
+	stat os.FileInfo
+}
+
+// NewFilesQuery creates a files dependency from the given glob pattern, in the
+// syntax of filepath.Match.
+func NewFilesQuery(s string) (*FilesQuery, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("files: invalid format: %q", s)
+	}
+
+	if _, err := filepath.Match(s, ""); err != nil {
+		return nil, fmt.Errorf("files: invalid pattern %q: %s", s, err)
+	}
+
+	return &FilesQuery{
+		stopCh:  make(chan struct{}, 1),
+		pattern: s,
+	}, nil
+}
+
+// NewDirQuery creates a files dependency for the files in the given
+// directory.
+func NewDirQuery(s string) (*FilesQuery, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("dir: invalid format: %q", s)
+	}
+
+	return &FilesQuery{
+		stopCh:  make(chan struct{}, 1),
+		pattern: filepath.Clean(s),
+		dir:     true,
+	}, nil
+}
+
+// SetSandboxPath restricts the matching files to the given directory. Matches
+// that resolve outside of it, such as through symlinks, are an error. Each
+// match is checked when the files are listed, so files that are removed in
+// the meantime are not an error.
+func (d *FilesQuery) SetSandboxPath(path string) {
+	d.sandbox = path
+}
+
+// Fetch retrieves the matching files once they are added, removed or changed.
+// A missing directory matches no files.
+func (d *FilesQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	log.Printf("[TRACE] %s: LIST %s", d, d.pattern)
+
+	select {
+	case <-d.stopCh:
+		log.Printf("[TRACE] %s: stopped", d)
+		return nil, nil, ErrStopped
+	case r := <-d.watch(d.stats):
+		if r.err != nil {
+			return nil, nil, errors.Wrap(r.err, d.String())
+		}
+
+		log.Printf("[TRACE] %s: reported change", d)
+
+		files := make([]*File, 0, len(r.stats))
+		for _, s := range r.stats {
+			data, err := ioutil.ReadFile(s.path)
+			if os.IsNotExist(err) {
+				// The file was removed since it was listed, which the next
+				// fetch will report.
+				continue
+			}
+			if err != nil {
+				return nil, nil, errors.Wrap(err, d.String())
+			}
+
+			files = append(files, &File{
+				Name:     filepath.Base(s.path),
+				Path:     s.path,
+				Contents: string(data),
+				ModTime:  s.stat.ModTime(),
+			})
+		}
+
+		d.stats = r.stats
+
+		// Events may report changes several times per second, so the index
+		// must be more precise than the one of respWithMetadata.
+		return files, &ResponseMetadata{
+			LastIndex: uint64(time.Now().UnixNano()),
+		}, nil
+	}
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *FilesQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the dependency's fetch function.
+func (d *FilesQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency. The sandbox
+// path is included, since it changes the matching files.
+func (d *FilesQuery) String() string {
+	name, spec := "files", d.pattern
+	if d.dir {
+		name = "dir"
+	}
+	if d.sandbox != "" {
+		spec += " sandbox=" + d.sandbox
+	}
+	return fmt.Sprintf("%s(%s)", name, spec)
+}
+
+// Type returns the type of this dependency.
+func (d *FilesQuery) Type() Type {
+	return TypeLocal
+}
+
+// list returns the stats of the matching files, sorted by path.
+func (d *FilesQuery) list() ([]fileStat, error) {
+	pattern := d.pattern
+	if d.dir {
+		pattern = filepath.Join(escapeGlob(d.pattern), "*")
+	}
+
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]fileStat, 0, len(paths))
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			// A removed file, or a broken symlink.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if stat.IsDir() {
+			continue
+		}
+		if err := d.inSandbox(path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		stats = append(stats, fileStat{path: path, stat: stat})
+	}
+	return stats, nil
+}
+
+// inSandbox returns an error if the path resolves outside of the sandbox.
+func (d *FilesQuery) inSandbox(path string) error {
+	if d.sandbox == "" {
+		return nil
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(d.sandbox, resolved)
+	if err != nil {
+		return err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("'%s' is outside of sandbox", path)
+	}
+	return nil
+}
+
+// watchPaths returns the paths to watch for events: the directory of the
+// pattern, which is where files are added and removed, and the files.
+func (d *FilesQuery) watchPaths(stats []fileStat) []string {
+	paths := make([]string, 0, len(stats)+1)
+	if d.dir {
+		paths = append(paths, filepath.Join(d.pattern, "*"))
+	} else {
+		paths = append(paths, d.pattern)
+	}
+	for _, s := range stats {
+		paths = append(paths, s.path)
+	}
+	return paths
+}
+
+type filesWatchResult struct {
+	stats []fileStat
+	err   error
+}
+
+// watch watches the files for changes. They are checked when there is an
+// event in the directory, or every FileQuerySleepTime if there are no events
+// or the pattern has wildcards in its directories.
+func (d *FilesQuery) watch(lastStats []fileStat) <-chan *filesWatchResult {
+	ch := make(chan *filesWatchResult, 1)
+	w := getFileWatcher()
+	if !d.dir && hasGlobMeta(filepath.Dir(d.pattern)) {
+		w = nil
+	}
+
+	go func(lastStats []fileStat) {
+		for {
+			// Subscribe before the listing, so changes in between are not
+			// missed. Subscribe again every time, since the files change.
+			var events <-chan struct{}
+			cancel := func() {}
+			if w != nil {
+				events, cancel = w.subscribe(d.watchPaths(lastStats)...)
+			}
+
+			stats, err := d.list()
+			if err != nil {
+				cancel()
+				select {
+				case <-d.stopCh:
+					return
+				case ch <- &filesWatchResult{err: err}:
+					return
+				}
+			}
+
+			if lastStats == nil || filesChanged(lastStats, stats) {
+				cancel()
+				select {
+				case <-d.stopCh:
+					return
+				case ch <- &filesWatchResult{stats: stats}:
+					return
+				}
+			}
+
+			if events == nil {
+				select {
+				case <-d.stopCh:
+					return
+				case <-time.After(FileQuerySleepTime):
+				}
+				continue
+			}
+
+			select {
+			case <-d.stopCh:
+				cancel()
+				return
+			case <-events:
+				cancel()
+			}
+		}
+	}(lastStats)
+
+	return ch
+}
+
+// filesChanged returns true if files were added, removed or changed.
+func filesChanged(a, b []fileStat) bool {
+	if len(a) != len(b) {
+		return true
+	}
+	for i := range a {
+		if a[i].path != b[i].path ||
+			!os.SameFile(a[i].stat, b[i].stat) ||
+			a[i].stat.Size() != b[i].stat.Size() ||
+			a[i].stat.ModTime() != b[i].stat.ModTime() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGlobMeta returns true if the path contains glob wildcards.
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// escapeGlob escapes the glob wildcards in the path.
+func escapeGlob(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if strings.ContainsRune("*?[", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
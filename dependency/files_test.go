@@ -0,0 +1,279 @@
+package dependency
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFilesQuery(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *FilesQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"bad_pattern",
+			"/etc/[",
+			nil,
+			true,
+		},
+		{
+			"pattern",
+			"/etc/app/*.json",
+			&FilesQuery{
+				pattern: "/etc/app/*.json",
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewFilesQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestNewDirQuery(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *FilesQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"dir",
+			"/etc/app/conf.d/",
+			&FilesQuery{
+				pattern: "/etc/app/conf.d",
+				dir:     true,
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewDirQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestFilesQuery_Fetch(t *testing.T) {
+
+	write := func(t *testing.T, path, contents string) {
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// contents returns the names and contents of the files.
+	contents := func(data interface{}) map[string]string {
+		r := make(map[string]string)
+		for _, f := range data.([]*File) {
+			r[f.Name] = f.Contents
+		}
+		return r
+	}
+
+	t.Run("glob", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "a.json"), "a")
+		write(t, filepath.Join(dir, "b.json"), "b")
+		write(t, filepath.Join(dir, "c.txt"), "c")
+		if err := os.Mkdir(filepath.Join(dir, "d.json"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := NewFilesQuery(filepath.Join(dir, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		act, _, err := d.Fetch(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files := act.([]*File)
+		if len(files) != 2 {
+			t.Fatalf("expected 2 files, got %d", len(files))
+		}
+		assert.Equal(t, "a.json", files[0].Name)
+		assert.Equal(t, filepath.Join(dir, "a.json"), files[0].Path)
+		assert.Equal(t, "a", files[0].Contents)
+		assert.False(t, files[0].ModTime.IsZero())
+		assert.Equal(t, "b.json", files[1].Name)
+	})
+
+	t.Run("sandbox", func(t *testing.T) {
+		sandbox, err := filepath.EvalSymlinks(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		outside := filepath.Join(t.TempDir(), "secret")
+		write(t, outside, "secret")
+		write(t, filepath.Join(sandbox, "a.json"), "a")
+
+		d, err := NewFilesQuery(filepath.Join(sandbox, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.SetSandboxPath(sandbox)
+		act, _, err := d.Fetch(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, map[string]string{"a.json": "a"}, contents(act))
+
+		link := filepath.Join(sandbox, "b.json")
+		if err := os.Symlink(outside, link); err != nil {
+			t.Fatal(err)
+		}
+		d, err = NewFilesQuery(filepath.Join(sandbox, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.SetSandboxPath(sandbox)
+		_, _, err = d.Fetch(nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "outside of sandbox") {
+			t.Errorf("expected sandbox error, got %v", err)
+		}
+	})
+
+	t.Run("dir_missing", func(t *testing.T) {
+		d, err := NewDirQuery("/not/a/real/path/ever")
+		if err != nil {
+			t.Fatal(err)
+		}
+		act, _, err := d.Fetch(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []*File{}, act)
+	})
+
+	t.Run("fires_changes", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "a"), "a")
+
+		d, err := NewDirQuery(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Stop()
+
+		dataCh := make(chan interface{}, 1)
+		go func() {
+			for {
+				data, _, err := d.Fetch(nil, nil)
+				if err != nil {
+					return
+				}
+				dataCh <- data
+			}
+		}()
+
+		expect := func(exp map[string]string) {
+			select {
+			case data := <-dataCh:
+				assert.Equal(t, exp, contents(data))
+			case <-time.After(2 * time.Second):
+				t.Fatalf("expected %v", exp)
+			}
+		}
+
+		expect(map[string]string{"a": "a"})
+
+		write(t, filepath.Join(dir, "b"), "b")
+		expect(map[string]string{"a": "a", "b": "b"})
+
+		if err := os.Remove(filepath.Join(dir, "a")); err != nil {
+			t.Fatal(err)
+		}
+		expect(map[string]string{"b": "b"})
+	})
+
+	t.Run("stops", func(t *testing.T) {
+		d, err := NewFilesQuery(filepath.Join(t.TempDir(), "*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := d.Fetch(nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, _, err := d.Fetch(nil, nil)
+			errCh <- err
+		}()
+		d.Stop()
+
+		select {
+		case err := <-errCh:
+			if err != ErrStopped {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("did not stop")
+		}
+	})
+}
+
+func TestFilesQuery_String(t *testing.T) {
+
+	f, err := NewFilesQuery("/etc/app/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "files(/etc/app/*.json)", f.String())
+
+	d, err := NewDirQuery("/etc/app/conf.d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "dir(/etc/app/conf.d)", d.String())
+
+	d.SetSandboxPath("/etc/app")
+	assert.Equal(t, "dir(/etc/app/conf.d sandbox=/etc/app)", d.String())
+}
@@ -0,0 +1,299 @@
+package dependency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// HTTPFormatText returns the body of the response as a string.
+	HTTPFormatText = "text"
+
+	// HTTPFormatJSON parses the body of the response as JSON.
+	HTTPFormatJSON = "json"
+
+	// HTTPFormatYAML parses the body of the response as YAML.
+	HTTPFormatYAML = "yaml"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*HTTPQuery)(nil)
+
+	// defaultHTTPClient is used by HTTP dependencies if the client set has no
+	// HTTP client.
+	defaultHTTPClient = &httpClient{
+		client:       &http.Client{Timeout: 30 * time.Second},
+		pollInterval: 1 * time.Minute,
+	}
+)
+
+// HTTPQuery represents the response of a GET request to an HTTP endpoint,
+// which is polled for changes. Requests are conditional on the ETag and
+// Last-Modified headers of the last response, so unchanged responses are not
+// transferred again by servers that support them.
+type HTTPQuery struct {
+	stopCh chan struct{}
+
+	url      string
+	format   string
+	interval time.Duration
+	headers  map[string]string
+
+	// etag, lastModified, data and lastIndex are those of the last response.
+	etag         string
+	lastModified string
+	data         interface{}
+	lastIndex    uint64
+}
+
+// NewHTTPQuery creates an HTTP dependency from the given URL and options. The
+// options are:
+//
+//	format=<text|json|yaml>  - how to parse the body, text by default
+//	interval=<duration>      - the poll interval, overriding the configured one
+//	header=<Name: value>     - a request header, which may be given many times
+func NewHTTPQuery(s string, opts ...string) (*HTTPQuery, error) {
+	s = strings.TrimSpace(s)
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("http: invalid format: %q", s)
+	}
+
+	d := &HTTPQuery{
+		stopCh: make(chan struct{}, 1),
+		url:    s,
+		format: HTTPFormatText,
+	}
+
+	for _, opt := range opts {
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("http: invalid option: %q", opt)
+		}
+
+		switch key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]); key {
+		case "format":
+			switch value {
+			case HTTPFormatText, HTTPFormatJSON, HTTPFormatYAML:
+				d.format = value
+			default:
+				return nil, fmt.Errorf("http: invalid format option: %q", value)
+			}
+		case "interval":
+			interval, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("http: invalid interval option: %q", value)
+			}
+			if interval < time.Second {
+				return nil, fmt.Errorf("http: interval must be at least 1s: %q", value)
+			}
+			d.interval = interval
+		case "header":
+			header := strings.SplitN(value, ":", 2)
+			name := strings.TrimSpace(header[0])
+			if len(header) != 2 || name == "" {
+				return nil, fmt.Errorf("http: invalid header option: %q", value)
+			}
+			if d.headers == nil {
+				d.headers = make(map[string]string)
+			}
+			d.headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(header[1])
+		default:
+			return nil, fmt.Errorf("http: invalid option: %q", opt)
+		}
+	}
+
+	return d, nil
+}
+
+// Fetch requests the endpoint and returns its parsed response. If this is not
+// the first fetch, it waits for the poll interval first. Errors after the first
+// response are logged and the last response is kept, so an endpoint that is
+// briefly unavailable does not stop the templates that use it.
+func (d *HTTPQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	c := d.client(clients)
+
+	if opts.WaitIndex != 0 {
+		interval := d.interval
+		if interval == 0 {
+			interval = c.pollInterval
+		}
+
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+
+		select {
+		case <-d.stopCh:
+			log.Printf("[TRACE] %s: stopped", d)
+			return nil, nil, ErrStopped
+		case <-timer.C:
+		}
+	}
+
+	changed, err := d.request(c)
+	if err != nil {
+		if d.lastIndex == 0 {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+		log.Printf("[WARN] %s: keeping the last response: %s", d, err)
+	}
+
+	if changed {
+		log.Printf("[TRACE] %s: reported change", d)
+		d.lastIndex = uint64(time.Now().UnixNano())
+	}
+
+	return d.data, &ResponseMetadata{
+		LastIndex: d.lastIndex,
+	}, nil
+}
+
+// request requests the endpoint and stores the response. It returns false if
+// the endpoint responded that nothing changed.
+func (d *HTTPQuery) request(c *httpClient) (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, d.url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range d.headers {
+		req.Header.Set(k, v)
+	}
+	if d.etag != "" {
+		req.Header.Set("If-None-Match", d.etag)
+	}
+	if d.lastModified != "" {
+		req.Header.Set("If-Modified-Since", d.lastModified)
+	}
+
+	log.Printf("[TRACE] %s: GET %s", d, d.url)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && d.lastIndex != 0 {
+		log.Printf("[TRACE] %s: not modified", d)
+		return false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return false, fmt.Errorf("unexpected response code: %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := d.parse(body)
+	if err != nil {
+		return false, err
+	}
+
+	d.etag = resp.Header.Get("ETag")
+	d.lastModified = resp.Header.Get("Last-Modified")
+	d.data = data
+	return true, nil
+}
+
+// parse parses the body of a response in the format of the dependency. An
+// empty body is an empty map in the JSON and YAML formats, like the parseJSON
+// and parseYAML template functions return.
+func (d *HTTPQuery) parse(body []byte) (interface{}, error) {
+	if d.format == HTTPFormatText {
+		return string(body), nil
+	}
+
+	if len(body) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	var data interface{}
+	switch d.format {
+	case HTTPFormatJSON:
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, errors.Wrap(err, "parsing JSON")
+		}
+	case HTTPFormatYAML:
+		if err := yaml.Unmarshal(body, &data); err != nil {
+			return nil, errors.Wrap(err, "parsing YAML")
+		}
+	}
+	return data, nil
+}
+
+// client returns the HTTP client of the client set, or a default one.
+func (d *HTTPQuery) client(clients *ClientSet) *httpClient {
+	if clients == nil {
+		return defaultHTTPClient
+	}
+
+	clients.RLock()
+	defer clients.RUnlock()
+	if clients.http == nil {
+		return defaultHTTPClient
+	}
+	return clients.http
+}
+
+// Format returns the format the response is parsed in.
+func (d *HTTPQuery) Format() string {
+	return d.format
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *HTTPQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the dependency's fetch function.
+func (d *HTTPQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency. Header values
+// are hashed, so credentials are not logged.
+func (d *HTTPQuery) String() string {
+	opts := []string{d.url}
+	if d.format != HTTPFormatText {
+		opts = append(opts, "format="+d.format)
+	}
+	if d.interval != 0 {
+		opts = append(opts, "interval="+d.interval.String())
+	}
+
+	names := make([]string, 0, len(d.headers))
+	for name := range d.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sum := sha256.Sum256([]byte(d.headers[name]))
+		opts = append(opts, fmt.Sprintf("header=%s:%s", name, hex.EncodeToString(sum[:4])))
+	}
+
+	return fmt.Sprintf("http(%s)", strings.Join(opts, " "))
+}
+
+// Type returns the type of this dependency.
+func (d *HTTPQuery) Type() Type {
+	return TypeLocal
+}
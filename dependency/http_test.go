@@ -0,0 +1,425 @@
+package dependency
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPQuery(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    string
+		opts []string
+		exp  *HTTPQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"no_scheme",
+			"example.com/flags",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"bad_scheme",
+			"ftp://example.com/flags",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"url",
+			"https://example.com/flags",
+			nil,
+			&HTTPQuery{
+				url:    "https://example.com/flags",
+				format: "text",
+			},
+			false,
+		},
+		{
+			"options",
+			"https://example.com/flags",
+			[]string{"format=json", "interval=30s", "header=authorization: Bearer abcd"},
+			&HTTPQuery{
+				url:      "https://example.com/flags",
+				format:   "json",
+				interval: 30 * time.Second,
+				headers:  map[string]string{"Authorization": "Bearer abcd"},
+			},
+			false,
+		},
+		{
+			"bad_format",
+			"https://example.com/flags",
+			[]string{"format=xml"},
+			nil,
+			true,
+		},
+		{
+			"short_interval",
+			"https://example.com/flags",
+			[]string{"interval=10ms"},
+			nil,
+			true,
+		},
+		{
+			"bad_header",
+			"https://example.com/flags",
+			[]string{"header=Authorization"},
+			nil,
+			true,
+		},
+		{
+			"unknown_option",
+			"https://example.com/flags",
+			[]string{"method=POST"},
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewHTTPQuery(tc.i, tc.opts...)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestHTTPQuery_Fetch(t *testing.T) {
+
+	t.Run("formats", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/json":
+				fmt.Fprint(w, `{"flags":{"beta":true}}`)
+			case "/yaml":
+				fmt.Fprint(w, "flags:\n  - beta\n")
+			case "/empty":
+			default:
+				fmt.Fprint(w, "hello")
+			}
+		}))
+		defer ts.Close()
+
+		cases := []struct {
+			name string
+			path string
+			opts []string
+			exp  interface{}
+		}{
+			{
+				"text",
+				"/text",
+				nil,
+				"hello",
+			},
+			{
+				"json",
+				"/json",
+				[]string{"format=json"},
+				map[string]interface{}{"flags": map[string]interface{}{"beta": true}},
+			},
+			{
+				"yaml",
+				"/yaml",
+				[]string{"format=yaml"},
+				map[interface{}]interface{}{"flags": []interface{}{"beta"}},
+			},
+			{
+				"empty_json",
+				"/empty",
+				[]string{"format=json"},
+				map[string]interface{}{},
+			},
+		}
+
+		for i, tc := range cases {
+			t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+				d, err := NewHTTPQuery(ts.URL+tc.path, tc.opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				act, _, err := d.Fetch(nil, &QueryOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tc.exp, act)
+			})
+		}
+	})
+
+	t.Run("invalid_json", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "{")
+		}))
+		defer ts.Close()
+
+		d, err := NewHTTPQuery(ts.URL, "format=json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := d.Fetch(nil, &QueryOptions{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("status_error", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer ts.Close()
+
+		d, err := NewHTTPQuery(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := d.Fetch(nil, &QueryOptions{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		var got http.Header
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Clone()
+		}))
+		defer ts.Close()
+
+		clients := NewClientSet()
+		if err := clients.CreateHTTPClient(&CreateHTTPClientInput{
+			Headers: map[string]string{
+				"Authorization": "Bearer config",
+				"X-Team":        "platform",
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := NewHTTPQuery(ts.URL, "header=Authorization: Bearer template")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := d.Fetch(clients, &QueryOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "Bearer template", got.Get("Authorization"))
+		assert.Equal(t, "platform", got.Get("X-Team"))
+	})
+
+	t.Run("conditional", func(t *testing.T) {
+		var mu sync.Mutex
+		body, etag := "v1", `"1"`
+		modified := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		var conditional []string
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			conditional = append(conditional,
+				r.Header.Get("If-None-Match")+"|"+r.Header.Get("If-Modified-Since"))
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+			fmt.Fprint(w, body)
+		}))
+		defer ts.Close()
+
+		clients := NewClientSet()
+		if err := clients.CreateHTTPClient(&CreateHTTPClientInput{
+			PollInterval: 10 * time.Millisecond,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		d, err := NewHTTPQuery(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act, rm, err := d.Fetch(clients, &QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "v1", act)
+
+		// Not modified keeps the index, so the view does not store it.
+		act, rm2, err := d.Fetch(clients, &QueryOptions{WaitIndex: rm.LastIndex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "v1", act)
+		assert.Equal(t, rm.LastIndex, rm2.LastIndex)
+
+		mu.Lock()
+		body, etag = "v2", `"2"`
+		mu.Unlock()
+
+		act, rm3, err := d.Fetch(clients, &QueryOptions{WaitIndex: rm2.LastIndex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "v2", act)
+		assert.True(t, rm3.LastIndex > rm2.LastIndex)
+
+		mu.Lock()
+		defer mu.Unlock()
+		lastModified := modified.Format(http.TimeFormat)
+		assert.Equal(t, []string{
+			"|",
+			`"1"|` + lastModified,
+			`"1"|` + lastModified,
+		}, conditional)
+	})
+
+	t.Run("keeps_last_response", func(t *testing.T) {
+		var mu sync.Mutex
+		status := http.StatusOK
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			w.WriteHeader(status)
+			fmt.Fprint(w, "v1")
+		}))
+		defer ts.Close()
+
+		d, err := NewHTTPQuery(ts.URL, "interval=1s")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, rm, err := d.Fetch(nil, &QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mu.Lock()
+		status = http.StatusServiceUnavailable
+		mu.Unlock()
+
+		act, rm2, err := d.Fetch(nil, &QueryOptions{WaitIndex: rm.LastIndex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "v1", act)
+		assert.Equal(t, rm.LastIndex, rm2.LastIndex)
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "secure")
+		}))
+		defer ts.Close()
+
+		d, err := NewHTTPQuery(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The certificate of the test server is not trusted by default.
+		if _, _, err := d.Fetch(nil, &QueryOptions{}); err == nil {
+			t.Fatal("expected error")
+		}
+
+		caCert := filepath.Join(t.TempDir(), "ca.pem")
+		f, err := os.Create(caCert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(pemEncodeCert(ts.Certificate().Raw)); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		clients := NewClientSet()
+		if err := clients.CreateHTTPClient(&CreateHTTPClientInput{
+			SSLEnabled: true,
+			SSLVerify:  true,
+			SSLCACert:  caCert,
+			ServerName: "example.com",
+		}); err != nil {
+			t.Fatal(err)
+		}
+		act, _, err := d.Fetch(clients, &QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "secure", act)
+	})
+
+	t.Run("stops", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer ts.Close()
+
+		d, err := NewHTTPQuery(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, _, err := d.Fetch(nil, &QueryOptions{WaitIndex: 1})
+			errCh <- err
+		}()
+		d.Stop()
+
+		select {
+		case err := <-errCh:
+			if err != ErrStopped {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("did not stop")
+		}
+	})
+}
+
+func TestHTTPQuery_String(t *testing.T) {
+
+	d, err := NewHTTPQuery("https://example.com/flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "http(https://example.com/flags)", d.String())
+
+	d, err = NewHTTPQuery("https://example.com/flags",
+		"format=json", "interval=30s", "header=Authorization: Bearer abcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "http(https://example.com/flags format=json interval=30s header=Authorization:"+
+		"25e84d4c)", d.String())
+}
+
+// pemEncodeCert returns the PEM encoding of a DER certificate.
+func pemEncodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
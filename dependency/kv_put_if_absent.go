@@ -0,0 +1,141 @@
+package dependency
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*KVPutIfAbsentQuery)(nil)
+
+	// KVPutIfAbsentQueryRe is the regular expression to use.
+	KVPutIfAbsentQueryRe = regexp.MustCompile(`\A` + keyRe + dcRe + `\z`)
+)
+
+// KVPutIfAbsentQuery writes a value to the KV store if, and only if, the key
+// does not already exist. After the initial write attempt, it behaves like a
+// blocking query for the key, returning whichever value won the write.
+type KVPutIfAbsentQuery struct {
+	stopCh chan struct{}
+
+	dc    string
+	key   string
+	value string
+
+	// written is true once the check-and-set write has been attempted
+	// successfully. The write is only ever performed once per dependency.
+	written bool
+}
+
+// NewKVPutIfAbsentQuery parses a string into a dependency. The given value is
+// written to the key on the first fetch if the key does not exist.
+func NewKVPutIfAbsentQuery(s, value string) (*KVPutIfAbsentQuery, error) {
+	if s == "" || !KVPutIfAbsentQueryRe.MatchString(s) {
+		return nil, fmt.Errorf("kv.putIfAbsent: invalid format: %q", s)
+	}
+
+	m := regexpMatch(KVPutIfAbsentQueryRe, s)
+	return &KVPutIfAbsentQuery{
+		stopCh: make(chan struct{}, 1),
+		dc:     m["dc"],
+		key:    m["key"],
+		value:  value,
+	}, nil
+}
+
+// Fetch queries the Consul API defined by the given client. On the first call
+// the value is written using a check-and-set with an index of 0, which Consul
+// only accepts when the key does not exist.
+func (d *KVPutIfAbsentQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	select {
+	case <-d.stopCh:
+		return nil, nil, ErrStopped
+	default:
+	}
+
+	opts = opts.Merge(&QueryOptions{
+		Datacenter: d.dc,
+	})
+
+	if !d.written {
+		log.Printf("[TRACE] %s: PUT %s", d, &url.URL{
+			Path:     "/v1/kv/" + d.key,
+			RawQuery: "cas=0",
+		})
+
+		ok, _, err := clients.Consul().KV().CAS(&consulapi.KVPair{
+			Key:         d.key,
+			Value:       []byte(d.value),
+			ModifyIndex: 0,
+		}, &consulapi.WriteOptions{
+			Datacenter: opts.Datacenter,
+		})
+		if err != nil {
+			return nil, nil, errors.Wrap(err, d.String())
+		}
+
+		if ok {
+			log.Printf("[DEBUG] %s: wrote value", d)
+		} else {
+			log.Printf("[DEBUG] %s: key already exists, not writing", d)
+		}
+		d.written = true
+	}
+
+	log.Printf("[TRACE] %s: GET %s", d, &url.URL{
+		Path:     "/v1/kv/" + d.key,
+		RawQuery: opts.String(),
+	})
+
+	pair, qm, err := clients.Consul().KV().Get(d.key, opts.ToConsulOpts())
+	if err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	rm := &ResponseMetadata{
+		LastIndex:   qm.LastIndex,
+		LastContact: qm.LastContact,
+		Block:       true,
+	}
+
+	if pair == nil {
+		log.Printf("[TRACE] %s: returned nil", d)
+		return nil, rm, nil
+	}
+
+	log.Printf("[TRACE] %s: returned value", d)
+	return string(pair.Value), rm, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *KVPutIfAbsentQuery) CanShare() bool {
+	return true
+}
+
+// String returns the human-friendly version of this dependency. The value is
+// deliberately not part of the string, since templates commonly generate a new
+// value on every evaluation and the dependency must stay stable across
+// evaluations.
+func (d *KVPutIfAbsentQuery) String() string {
+	key := d.key
+	if d.dc != "" {
+		key = key + "@" + d.dc
+	}
+	return fmt.Sprintf("kv.putIfAbsent(%s)", key)
+}
+
+// Stop halts the dependency's fetch function.
+func (d *KVPutIfAbsentQuery) Stop() {
+	close(d.stopCh)
+}
+
+// Type returns the type of this dependency.
+func (d *KVPutIfAbsentQuery) Type() Type {
+	return TypeConsul
+}
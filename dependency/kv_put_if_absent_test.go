@@ -0,0 +1,178 @@
+package dependency
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKVPutIfAbsentQuery(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *KVPutIfAbsentQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"dc_only",
+			"@dc1",
+			nil,
+			true,
+		},
+		{
+			"key",
+			"key",
+			&KVPutIfAbsentQuery{
+				key:   "key",
+				value: "value",
+			},
+			false,
+		},
+		{
+			"dc",
+			"key@dc1",
+			&KVPutIfAbsentQuery{
+				key:   "key",
+				dc:    "dc1",
+				value: "value",
+			},
+			false,
+		},
+		{
+			"leading_slash",
+			"/leading/slash",
+			&KVPutIfAbsentQuery{
+				key:   "leading/slash",
+				value: "value",
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewKVPutIfAbsentQuery(tc.i, "value")
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestKVPutIfAbsentQuery_Fetch(t *testing.T) {
+
+	testConsul.SetKVString(t, "test-kv-put-if-absent/exists", "original")
+
+	cases := []struct {
+		name  string
+		i     string
+		value string
+		exp   interface{}
+	}{
+		{
+			"exists",
+			"test-kv-put-if-absent/exists",
+			"generated",
+			"original",
+		},
+		{
+			"no_exist",
+			"test-kv-put-if-absent/new",
+			"generated",
+			"generated",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewKVPutIfAbsentQuery(tc.i, tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, _, err := d.Fetch(testClients, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, tc.exp, act)
+			assert.Equal(t, tc.exp, testConsul.GetKVString(t, tc.i))
+		})
+	}
+
+	t.Run("writes_once", func(t *testing.T) {
+		d, err := NewKVPutIfAbsentQuery("test-kv-put-if-absent/once", "first")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, qm, err := d.Fetch(testClients, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dataCh := make(chan interface{}, 1)
+		errCh := make(chan error, 1)
+		go func() {
+			data, _, err := d.Fetch(testClients, &QueryOptions{WaitIndex: qm.LastIndex})
+			if err != nil {
+				errCh <- err
+				return
+			}
+			dataCh <- data
+		}()
+
+		testConsul.SetKVString(t, "test-kv-put-if-absent/once", "changed")
+
+		select {
+		case err := <-errCh:
+			t.Fatal(err)
+		case data := <-dataCh:
+			assert.Equal(t, "changed", data)
+		}
+	})
+}
+
+func TestKVPutIfAbsentQuery_String(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    string
+		exp  string
+	}{
+		{
+			"key",
+			"key",
+			"kv.putIfAbsent(key)",
+		},
+		{
+			"dc",
+			"key@dc1",
+			"kv.putIfAbsent(key@dc1)",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			d, err := NewKVPutIfAbsentQuery(tc.i, "value")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tc.exp, d.String())
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package dependency
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*PluginQuery)(nil)
+
+	// ErrPluginExited is the error returned for calls that were pending when
+	// the plugin process exited.
+	ErrPluginExited = errors.New("plugin exited")
+)
+
+// PluginClient is a client of a long-running plugin process. The plugin is
+// started on the first call and restarted on the next call if it exits or
+// does not answer in time.
+//
+// Plugins speak JSON-RPC 2.0 over their stdin and stdout, one message per line.
+// Each call of the template "plugin" function is a "call" request whose params
+// are the arguments given in the template:
+//
+//	{"jsonrpc":"2.0","id":1,"method":"call","params":["arg1","arg2"]}
+//
+// The plugin answers with the result, or with an error:
+//
+//	{"jsonrpc":"2.0","id":1,"result":"value"}
+//	{"jsonrpc":"2.0","id":1,"error":{"code":1,"message":"not found"}}
+//
+// String results are rendered as they are, other results are rendered as JSON.
+// The plugin may send an "update" notification at any time, which means its
+// data changed and all calls are made again:
+//
+//	{"jsonrpc":"2.0","method":"update"}
+//
+// Plugins that provide template functions also answer "fetch" requests, see
+// PluginFunctionQuery.
+type PluginClient struct {
+	sync.Mutex
+
+	name     string
+	command  string
+	args     []string
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	proc     *pluginProcess
+	nextID   uint64
+	updateCh chan struct{}
+	stopped  bool
+}
+
+// pluginProcess is a running plugin process. Its pending calls are guarded by
+// the lock of the client.
+type pluginProcess struct {
+	cmd     *exec.Cmd
+	pending map[uint64]chan *pluginMessage
+
+	// stdinLock serializes requests, which are written without holding the
+	// lock of the client so that a slow plugin does not block its responses.
+	stdin     io.WriteCloser
+	stdinLock sync.Mutex
+}
+
+// pluginMessage is a JSON-RPC request, response or notification.
+type pluginMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *uint64         `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *pluginError    `json:"error,omitempty"`
+}
+
+// pluginError is the error of a JSON-RPC response.
+type pluginError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// newPluginClient creates a client of the plugin. The process is not started
+// until the first call.
+func newPluginClient(i *CreatePluginInput) *PluginClient {
+	return &PluginClient{
+		name:     i.Name,
+		command:  i.Command[0],
+		args:     i.Command[1:],
+		timeout:  i.Timeout,
+		cacheTTL: i.CacheTTL,
+		updateCh: make(chan struct{}),
+	}
+}
+
+// CacheTTL returns the amount of time results of the plugin are cached. Zero
+// means results are cached until the plugin sends an update.
+func (c *PluginClient) CacheTTL() time.Duration {
+	return c.cacheTTL
+}
+
+// Updated returns a channel that is closed when the plugin sends the next
+// update notification.
+func (c *PluginClient) Updated() <-chan struct{} {
+	c.Lock()
+	defer c.Unlock()
+	return c.updateCh
+}
+
+// Call calls the plugin with the arguments and returns its result. If the
+// plugin does not answer within the timeout, it is killed.
+func (c *PluginClient) Call(args []string) (string, error) {
+	result, err := c.call("call", args, c.timeout)
+	if err != nil {
+		return "", err
+	}
+	return pluginResult(result)
+}
+
+// call makes a request to the plugin and returns the raw result. If the plugin
+// does not answer within the timeout, it is killed.
+func (c *PluginClient) call(method string, params interface{}, timeout time.Duration) (json.RawMessage, error) {
+	c.Lock()
+	if c.stopped {
+		c.Unlock()
+		return nil, ErrStopped
+	}
+	if c.proc == nil {
+		if err := c.start(); err != nil {
+			c.Unlock()
+			return nil, errors.Wrapf(err, "plugin %q", c.name)
+		}
+	}
+	p := c.proc
+
+	c.nextID++
+	id := c.nextID
+	respCh := make(chan *pluginMessage, 1)
+	p.pending[id] = respCh
+	c.Unlock()
+
+	req, err := json.Marshal(&pluginMessage{
+		JSONRPC: "2.0",
+		ID:      &id,
+		Method:  method,
+		Params:  params,
+	})
+	if err == nil {
+		p.stdinLock.Lock()
+		_, err = p.stdin.Write(append(req, '\n'))
+		p.stdinLock.Unlock()
+	}
+	if err != nil {
+		c.Lock()
+		delete(p.pending, id)
+		c.Unlock()
+		return nil, errors.Wrapf(err, "plugin %q", c.name)
+	}
+
+	select {
+	case resp := <-respCh:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("plugin %q: %s", c.name, resp.Error.Message)
+		}
+		return resp.Result, nil
+	case <-time.After(timeout):
+		log.Printf("[WARN] (plugin) %s did not answer in %s, restarting", c.name, timeout)
+		c.kill(p)
+		return nil, fmt.Errorf("plugin %q: did not answer in %s", c.name, timeout)
+	}
+}
+
+// Stop kills the plugin process. Calls made after the client is stopped fail.
+func (c *PluginClient) Stop() {
+	c.Lock()
+	c.stopped = true
+	p := c.proc
+	c.Unlock()
+
+	if p != nil {
+		c.kill(p)
+	}
+}
+
+// start starts the plugin process. It must be called with the lock held.
+func (c *PluginClient) start() error {
+	cmd := exec.Command(c.command, c.args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] (plugin) %s started with pid %d", c.name, cmd.Process.Pid)
+
+	p := &pluginProcess{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[uint64]chan *pluginMessage),
+	}
+	c.proc = p
+	go c.read(p, stdout)
+	return nil
+}
+
+// kill kills the plugin process, if it is still the current one. Its pending
+// calls fail once its stdout is closed.
+func (c *PluginClient) kill(p *pluginProcess) {
+	c.Lock()
+	if c.proc == p {
+		c.proc = nil
+	}
+	c.Unlock()
+
+	if err := p.cmd.Process.Kill(); err != nil {
+		log.Printf("[DEBUG] (plugin) %s failed to kill: %s", c.name, err)
+	}
+}
+
+// read dispatches the responses and notifications of the plugin process until
+// it closes its stdout.
+func (c *PluginClient) read(p *pluginProcess, stdout io.Reader) {
+	r := bufio.NewReader(stdout)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			c.handle(p, line)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	c.Lock()
+	if c.proc == p {
+		c.proc = nil
+	}
+	for id, respCh := range p.pending {
+		respCh <- &pluginMessage{Error: &pluginError{Message: ErrPluginExited.Error()}}
+		delete(p.pending, id)
+	}
+	c.Unlock()
+
+	if err := p.cmd.Wait(); err != nil {
+		log.Printf("[DEBUG] (plugin) %s exited: %s", c.name, err)
+	}
+}
+
+// handle dispatches a message of the plugin process.
+func (c *PluginClient) handle(p *pluginProcess, line []byte) {
+	var msg pluginMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		log.Printf("[WARN] (plugin) %s sent invalid message: %s", c.name, err)
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	switch {
+	case msg.ID != nil:
+		if respCh, ok := p.pending[*msg.ID]; ok {
+			respCh <- &msg
+			delete(p.pending, *msg.ID)
+		}
+	case msg.Method == "update":
+		log.Printf("[TRACE] (plugin) %s sent update", c.name)
+		close(c.updateCh)
+		c.updateCh = make(chan struct{})
+	default:
+		log.Printf("[WARN] (plugin) %s sent unknown method %q", c.name, msg.Method)
+	}
+}
+
+// pluginResult returns the result of a call as a string. Strings are returned
+// as they are, other results are returned as JSON.
+func pluginResult(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+
+	var b bytes.Buffer
+	if err := json.Compact(&b, raw); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// PluginQuery is the result of a call to a long-running plugin. The plugin is
+// called again when it sends an update, or when the cache TTL of the plugin
+// expires.
+type PluginQuery struct {
+	stopCh chan struct{}
+
+	name     string
+	args     []string
+	updateCh <-chan struct{}
+}
+
+// NewPluginQuery creates a plugin dependency for the call of the named plugin
+// with the arguments.
+func NewPluginQuery(name string, args []string) (*PluginQuery, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("plugin: invalid format: %q", name)
+	}
+
+	return &PluginQuery{
+		stopCh: make(chan struct{}, 1),
+		name:   name,
+		args:   args,
+	}, nil
+}
+
+// Fetch calls the plugin. Unless this is the first fetch, it first waits for
+// the plugin to send an update or for its cache TTL to expire.
+func (d *PluginQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	p := clients.Plugin(d.name)
+	if p == nil {
+		return "", nil, fmt.Errorf("%s: plugin is not configured", d)
+	}
+
+	if opts.WaitIndex != 0 {
+		var expired <-chan time.Time
+		if ttl := p.CacheTTL(); ttl > 0 {
+			expired = time.After(ttl)
+		}
+
+		select {
+		case <-d.stopCh:
+			log.Printf("[TRACE] %s: stopped", d)
+			return "", nil, ErrStopped
+		case <-d.updateCh:
+			log.Printf("[TRACE] %s: reported update", d)
+		case <-expired:
+			log.Printf("[TRACE] %s: cache expired", d)
+		}
+	}
+
+	// Watch for updates before calling, so updates during the call are not
+	// missed.
+	d.updateCh = p.Updated()
+
+	log.Printf("[TRACE] %s: CALL", d)
+	result, err := p.Call(d.args)
+	if err == ErrStopped {
+		return "", nil, ErrStopped
+	}
+	if err != nil {
+		return "", nil, errors.Wrap(err, d.String())
+	}
+
+	// Calls may return several times per second, so the index must be more
+	// precise than the one of respWithMetadata.
+	return result, &ResponseMetadata{
+		LastIndex: uint64(time.Now().UnixNano()),
+	}, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *PluginQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the dependency's fetch function.
+func (d *PluginQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *PluginQuery) String() string {
+	args := make([]string, len(d.args))
+	for i, arg := range d.args {
+		args[i] = strconv.Quote(arg)
+	}
+	return fmt.Sprintf("plugin(%s)", strings.Join(append([]string{d.name}, args...), " "))
+}
+
+// Type returns the type of this dependency.
+func (d *PluginQuery) Type() Type {
+	return TypeLocal
+}
@@ -0,0 +1,190 @@
+package dependency
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*PluginFunctionQuery)(nil)
+)
+
+func init() {
+	// Data of plugin functions is decoded from JSON, so it is shared with the
+	// de-duplication manager as generic maps and slices.
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// pluginFetchParams are the params of a "fetch" request.
+type pluginFetchParams struct {
+	Function string   `json:"function"`
+	Args     []string `json:"args"`
+	Index    uint64   `json:"index,omitempty"`
+	WaitMS   int64    `json:"wait_ms,omitempty"`
+}
+
+// pluginFetchResult is the result of a "fetch" request.
+type pluginFetchResult struct {
+	Data  interface{} `json:"data"`
+	Index uint64      `json:"index"`
+}
+
+// PluginFunctionQuery is the data of a template function provided by a
+// long-running plugin. Each fetch is a "fetch" request to the plugin:
+//
+//	{"jsonrpc":"2.0","id":1,"method":"fetch","params":{"function":"ssmParameter","args":["/app/db"],"index":12,"wait_ms":60000}}
+//
+// The plugin answers with the data, which may be any JSON value, and an
+// optional index:
+//
+//	{"jsonrpc":"2.0","id":1,"result":{"data":{"value":"postgres://"},"index":13}}
+//
+// Plugins that return an index are blocking: the next fetch is sent the index,
+// and the plugin should answer once the data changes past it, or after the
+// wait time with the same index. Plugins that do not return an index are
+// polled: the data is fetched again when the plugin sends an update, or when
+// its cache TTL expires.
+type PluginFunctionQuery struct {
+	stopCh chan struct{}
+
+	plugin   string
+	function string
+	args     []string
+
+	// blocking is true if the plugin returned an index on the last fetch.
+	blocking bool
+	updateCh <-chan struct{}
+}
+
+// NewPluginFunctionQuery creates a dependency for the call of the template
+// function provided by the named plugin.
+func NewPluginFunctionQuery(plugin, function string, args []string) (*PluginFunctionQuery, error) {
+	plugin, function = strings.TrimSpace(plugin), strings.TrimSpace(function)
+	if plugin == "" || function == "" {
+		return nil, fmt.Errorf("plugin function: invalid format: %q.%q", plugin, function)
+	}
+
+	return &PluginFunctionQuery{
+		stopCh:   make(chan struct{}, 1),
+		plugin:   plugin,
+		function: function,
+		args:     args,
+	}, nil
+}
+
+// Fetch fetches the data of the function from the plugin, blocking or polling
+// depending on the last answer of the plugin.
+func (d *PluginFunctionQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	p := clients.Plugin(d.plugin)
+	if p == nil {
+		return nil, nil, fmt.Errorf("%s: plugin is not configured", d)
+	}
+
+	params := &pluginFetchParams{
+		Function: d.function,
+		Args:     d.args,
+	}
+	timeout := p.timeout
+
+	switch {
+	case opts.WaitIndex == 0:
+	case d.blocking:
+		params.Index = opts.WaitIndex
+		params.WaitMS = opts.WaitTime.Milliseconds()
+		timeout += opts.WaitTime
+	default:
+		var expired <-chan time.Time
+		if ttl := p.CacheTTL(); ttl > 0 {
+			expired = time.After(ttl)
+		}
+
+		select {
+		case <-d.stopCh:
+			log.Printf("[TRACE] %s: stopped", d)
+			return nil, nil, ErrStopped
+		case <-d.updateCh:
+			log.Printf("[TRACE] %s: reported update", d)
+		case <-expired:
+			log.Printf("[TRACE] %s: cache expired", d)
+		}
+	}
+
+	// Watch for updates before fetching, so updates during the fetch are not
+	// missed.
+	d.updateCh = p.Updated()
+
+	log.Printf("[TRACE] %s: FETCH index=%d", d, params.Index)
+
+	type response struct {
+		raw json.RawMessage
+		err error
+	}
+	respCh := make(chan response, 1)
+	go func() {
+		raw, err := p.call("fetch", params, timeout)
+		respCh <- response{raw, err}
+	}()
+
+	var resp response
+	select {
+	case <-d.stopCh:
+		log.Printf("[TRACE] %s: stopped", d)
+		return nil, nil, ErrStopped
+	case resp = <-respCh:
+	}
+	if resp.err == ErrStopped {
+		return nil, nil, ErrStopped
+	}
+	if resp.err != nil {
+		return nil, nil, errors.Wrap(resp.err, d.String())
+	}
+
+	var result pluginFetchResult
+	if err := json.Unmarshal(resp.raw, &result); err != nil {
+		return nil, nil, errors.Wrap(err, d.String())
+	}
+
+	d.blocking = result.Index != 0
+	if !d.blocking {
+		// Fetches may return several times per second, so the index must be
+		// more precise than the one of respWithMetadata.
+		result.Index = uint64(time.Now().UnixNano())
+	}
+
+	return result.Data, &ResponseMetadata{
+		LastIndex: result.Index,
+	}, nil
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *PluginFunctionQuery) CanShare() bool {
+	return true
+}
+
+// Stop halts the dependency's fetch function.
+func (d *PluginFunctionQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *PluginFunctionQuery) String() string {
+	args := make([]string, len(d.args))
+	for i, arg := range d.args {
+		args[i] = strconv.Quote(arg)
+	}
+	return fmt.Sprintf("%s.%s(%s)", d.plugin, d.function, strings.Join(args, " "))
+}
+
+// Type returns the type of this dependency.
+func (d *PluginFunctionQuery) Type() Type {
+	return TypeLocal
+}
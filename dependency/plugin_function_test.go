@@ -0,0 +1,126 @@
+package dependency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testPluginFunctionScript is a plugin that provides a blocking function,
+// which echoes the index and wait time it was sent, and a polled function.
+const testPluginFunctionScript = `
+while IFS= read -r line; do
+	id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+	case "$line" in
+	*'"function":"block"'*)
+		exec sleep 10
+		;;
+	*'"function":"poll"'*)
+		echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"data\":[\"a\",\"b\"]}}"
+		;;
+	*'"index":'*)
+		index=$(echo "$line" | sed 's/.*"index":\([0-9]*\).*/\1/')
+		wait=$(echo "$line" | sed 's/.*"wait_ms":\([0-9]*\).*/\1/')
+		echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"data\":{\"index\":$index,\"wait_ms\":$wait},\"index\":$((index+1))}}"
+		;;
+	*)
+		echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"data\":{\"index\":0},\"index\":5}}"
+		;;
+	esac
+done
+`
+
+func TestNewPluginFunctionQuery(t *testing.T) {
+
+	if _, err := NewPluginFunctionQuery("", "fn", nil); err == nil {
+		t.Error("expected error for empty plugin")
+	}
+	if _, err := NewPluginFunctionQuery("etcd", "", nil); err == nil {
+		t.Error("expected error for empty function")
+	}
+
+	d, err := NewPluginFunctionQuery("etcd", "etcdKey", []string{"app/db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `etcd.etcdKey("app/db")`, d.String())
+	assert.True(t, d.CanShare())
+}
+
+func TestPluginFunctionQuery_Fetch(t *testing.T) {
+
+	t.Run("blocking", func(t *testing.T) {
+		clients := testPluginClients(t, testPluginFunctionScript, time.Second, 0)
+
+		d, err := NewPluginFunctionQuery("test", "value", []string{"a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act, rm, err := d.Fetch(clients, &QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, map[string]interface{}{"index": 0.0}, act)
+		assert.Equal(t, uint64(5), rm.LastIndex)
+
+		act, rm, err = d.Fetch(clients, &QueryOptions{
+			WaitIndex: rm.LastIndex,
+			WaitTime:  2 * time.Second,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, map[string]interface{}{"index": 5.0, "wait_ms": 2000.0}, act)
+		assert.Equal(t, uint64(6), rm.LastIndex)
+	})
+
+	t.Run("polling", func(t *testing.T) {
+		clients := testPluginClients(t, testPluginFunctionScript, time.Second, 50*time.Millisecond)
+
+		d, err := NewPluginFunctionQuery("test", "poll", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		act, rm, err := d.Fetch(clients, &QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []interface{}{"a", "b"}, act)
+
+		start := time.Now()
+		if _, _, err := d.Fetch(clients, &QueryOptions{WaitIndex: rm.LastIndex}); err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(start) < 50*time.Millisecond {
+			t.Error("expected fetch to wait for the cache TTL")
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		clients := testPluginClients(t, testPluginFunctionScript, time.Second, 0)
+
+		d, err := NewPluginFunctionQuery("test", "block", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		errCh := make(chan error, 1)
+		go func() {
+			_, _, err := d.Fetch(clients, &QueryOptions{})
+			errCh <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+		d.Stop()
+
+		select {
+		case err := <-errCh:
+			if err != ErrStopped {
+				t.Fatalf("expected %q, got %v", ErrStopped, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("did not stop")
+		}
+	})
+}
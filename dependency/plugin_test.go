@@ -0,0 +1,270 @@
+package dependency
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testPluginScript is a plugin that answers calls with its first argument and
+// its pid, so tests can tell whether the process was restarted.
+const testPluginScript = `
+while IFS= read -r line; do
+	id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+	arg=$(echo "$line" | sed 's/.*"params":\["\([^"]*\)".*/\1/')
+	case "$arg" in
+	sleep)
+		exec sleep 10
+		;;
+	fail)
+		echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"error\":{\"code\":1,\"message\":\"failed\"}}"
+		;;
+	update)
+		echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"pid\":$$}}"
+		echo '{"jsonrpc":"2.0","method":"update"}'
+		;;
+	*)
+		echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":\"$arg-$$\"}"
+		;;
+	esac
+done
+`
+
+func testPluginClients(t *testing.T, contents string, timeout, cacheTTL time.Duration) *ClientSet {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	script := filepath.Join(dir, "plugin.sh")
+	if err := ioutil.WriteFile(script, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	clients := NewClientSet()
+	if err := clients.CreatePlugin(&CreatePluginInput{
+		Name:     "test",
+		Command:  []string{"sh", script},
+		Timeout:  timeout,
+		CacheTTL: cacheTTL,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(clients.Stop)
+	return clients
+}
+
+func TestNewPluginQuery(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    string
+		args []string
+		exp  *PluginQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"name",
+			"lookup",
+			[]string{"a b", "c"},
+			&PluginQuery{
+				name: "lookup",
+				args: []string{"a b", "c"},
+			},
+			false,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewPluginQuery(tc.i, tc.args)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestPluginQuery_Fetch(t *testing.T) {
+
+	t.Run("persistent", func(t *testing.T) {
+		clients := testPluginClients(t, testPluginScript, time.Second, 0)
+
+		var pids []string
+		for _, arg := range []string{"a", "b"} {
+			d, err := NewPluginQuery("test", []string{arg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			act, _, err := d.Fetch(clients, &QueryOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts := strings.SplitN(act.(string), "-", 2)
+			assert.Equal(t, arg, parts[0])
+			pids = append(pids, parts[1])
+		}
+		assert.Equal(t, pids[0], pids[1], "expected one plugin process")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		clients := testPluginClients(t, testPluginScript, time.Second, 0)
+
+		d, err := NewPluginQuery("test", []string{"a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := d.Fetch(clients, &QueryOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, _, err := d.Fetch(clients, &QueryOptions{WaitIndex: 1})
+			errCh <- err
+		}()
+
+		select {
+		case err := <-errCh:
+			t.Fatalf("expected fetch to wait for an update, got %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		u, err := NewPluginQuery("test", []string{"update"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		act, _, err := u.Fetch(clients, &QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Regexp(t, `^\{"pid":\d+\}$`, act)
+
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected fetch to return after an update")
+		}
+	})
+
+	t.Run("cache_ttl", func(t *testing.T) {
+		clients := testPluginClients(t, testPluginScript, time.Second, 50*time.Millisecond)
+
+		d, err := NewPluginQuery("test", []string{"a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		if _, _, err := d.Fetch(clients, &QueryOptions{WaitIndex: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(start) < 50*time.Millisecond {
+			t.Error("expected fetch to wait for the cache TTL")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		clients := testPluginClients(t, testPluginScript, 100*time.Millisecond, 0)
+
+		call := func(arg string) (string, error) {
+			d, err := NewPluginQuery("test", []string{arg})
+			if err != nil {
+				t.Fatal(err)
+			}
+			act, _, err := d.Fetch(clients, &QueryOptions{})
+			return act.(string), err
+		}
+
+		before, err := call("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := call("sleep"); err == nil || !strings.Contains(err.Error(), "did not answer") {
+			t.Fatalf("expected timeout, got %v", err)
+		}
+		after, err := call("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NotEqual(t, before, after, "expected plugin to be restarted")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		clients := testPluginClients(t, testPluginScript, time.Second, 0)
+
+		d, err := NewPluginQuery("test", []string{"fail"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = d.Fetch(clients, &QueryOptions{})
+		if err == nil || !strings.Contains(err.Error(), "failed") {
+			t.Fatalf("expected plugin error, got %v", err)
+		}
+	})
+
+	t.Run("not_configured", func(t *testing.T) {
+		d, err := NewPluginQuery("nope", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := d.Fetch(NewClientSet(), &QueryOptions{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		clients := testPluginClients(t, testPluginScript, time.Second, 0)
+
+		d, err := NewPluginQuery("test", []string{"a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		errCh := make(chan error, 1)
+		go func() {
+			_, _, err := d.Fetch(clients, &QueryOptions{WaitIndex: 1})
+			errCh <- err
+		}()
+		d.Stop()
+
+		select {
+		case err := <-errCh:
+			if err != ErrStopped {
+				t.Fatalf("expected %q, got %v", ErrStopped, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("did not stop")
+		}
+	})
+}
+
+func TestPluginQuery_String(t *testing.T) {
+
+	d, err := NewPluginQuery("lookup", []string{"a b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `plugin(lookup "a b" "c")`, d.String())
+}
@@ -0,0 +1,128 @@
+package dependency
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	// Ensure implements
+	_ Dependency = (*TimerQuery)(nil)
+)
+
+// TimerQuery is a dependency that changes on a schedule, which is either an
+// interval, such as "5m", or a cron expression, such as "0 9 * * 1-5". Its
+// data is the current time as of the last tick, to the second.
+type TimerQuery struct {
+	stopCh chan struct{}
+
+	spec     string
+	interval time.Duration
+	schedule *cronSchedule
+}
+
+// NewTimerQuery creates a timer dependency from the given interval or cron
+// expression. Intervals are aligned to the local wall clock, so "1h" ticks at
+// the start of every hour.
+func NewTimerQuery(s string) (*TimerQuery, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("timer: invalid format: %q", s)
+	}
+
+	d := &TimerQuery{
+		stopCh: make(chan struct{}, 1),
+		spec:   s,
+	}
+
+	if interval, err := time.ParseDuration(s); err == nil {
+		if interval < time.Second {
+			return nil, fmt.Errorf("timer: interval must be at least 1s: %q", s)
+		}
+		d.interval = interval
+		return d, nil
+	}
+
+	schedule, err := parseCron(s)
+	if err != nil {
+		return nil, fmt.Errorf("timer: %q is neither an interval nor a cron expression: %s", s, err)
+	}
+	d.schedule = schedule
+	return d, nil
+}
+
+// Fetch returns the current time. If this is not the first fetch, it waits for
+// the next tick first.
+func (d *TimerQuery) Fetch(clients *ClientSet, opts *QueryOptions) (interface{}, *ResponseMetadata, error) {
+	if opts.WaitIndex != 0 {
+		next := d.next(time.Now())
+		if next.IsZero() {
+			log.Printf("[WARN] %s: schedule has no next tick", d)
+			<-d.stopCh
+			return nil, nil, ErrStopped
+		}
+
+		log.Printf("[TRACE] %s: next tick at %s", d, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		defer timer.Stop()
+
+		select {
+		case <-d.stopCh:
+			log.Printf("[TRACE] %s: stopped", d)
+			return nil, nil, ErrStopped
+		case <-timer.C:
+		}
+	}
+
+	now := time.Now()
+	return now.Truncate(time.Second), &ResponseMetadata{
+		LastIndex: uint64(now.UnixNano()),
+	}, nil
+}
+
+// next returns the time of the first tick after t. Intervals are aligned in the
+// time zone of t, like cron expressions, so "24h" ticks at local midnight.
+func (d *TimerQuery) next(t time.Time) time.Time {
+	if d.schedule != nil {
+		return d.schedule.Next(t)
+	}
+
+	// Truncate aligns to the zero time, which is UTC, so shift t by the offset
+	// of its zone first.
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	next := t.Add(shift).Truncate(d.interval).Add(d.interval).Add(-shift)
+
+	// Keep the tick aligned if the offset changes before it, such as at the
+	// start or end of daylight saving time.
+	if _, nextOffset := next.Zone(); nextOffset != offset {
+		adjusted := next.Add(time.Duration(offset-nextOffset) * time.Second)
+		if adjusted.After(t) {
+			next = adjusted
+		}
+	}
+	return next
+}
+
+// CanShare returns a boolean if this dependency is shareable.
+func (d *TimerQuery) CanShare() bool {
+	return false
+}
+
+// Stop halts the dependency's fetch function.
+func (d *TimerQuery) Stop() {
+	close(d.stopCh)
+}
+
+// String returns the human-friendly version of this dependency.
+func (d *TimerQuery) String() string {
+	return fmt.Sprintf("timer(%s)", d.spec)
+}
+
+// Type returns the type of this dependency.
+func (d *TimerQuery) Type() Type {
+	return TypeLocal
+}
@@ -0,0 +1,161 @@
+package dependency
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTimerQuery(t *testing.T) {
+
+	cases := []struct {
+		name string
+		i    string
+		exp  *TimerQuery
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			nil,
+			true,
+		},
+		{
+			"interval",
+			"5m",
+			&TimerQuery{
+				spec:     "5m",
+				interval: 5 * time.Minute,
+			},
+			false,
+		},
+		{
+			"interval_too_short",
+			"10ms",
+			nil,
+			true,
+		},
+		{
+			"cron",
+			"0 9 * * 1-5",
+			&TimerQuery{
+				spec: "0 9 * * 1-5",
+				schedule: func() *cronSchedule {
+					c, err := parseCron("0 9 * * 1-5")
+					if err != nil {
+						t.Fatal(err)
+					}
+					return c
+				}(),
+			},
+			false,
+		},
+		{
+			"invalid",
+			"sometimes",
+			nil,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act, err := NewTimerQuery(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+
+			if act != nil {
+				act.stopCh = nil
+			}
+
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestTimerQuery_Fetch(t *testing.T) {
+
+	t.Run("ticks", func(t *testing.T) {
+		d, err := NewTimerQuery("1s")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		first, rm, err := d.Fetch(nil, &QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		second, _, err := d.Fetch(nil, &QueryOptions{WaitIndex: rm.LastIndex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !second.(time.Time).After(first.(time.Time)) {
+			t.Errorf("expected %s to be after %s", second, first)
+		}
+	})
+
+	t.Run("stops", func(t *testing.T) {
+		d, err := NewTimerQuery("1h")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, _, err := d.Fetch(nil, &QueryOptions{WaitIndex: 1})
+			errCh <- err
+		}()
+		d.Stop()
+
+		select {
+		case err := <-errCh:
+			if err != ErrStopped {
+				t.Fatalf("expected %q, got %v", ErrStopped, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("did not stop")
+		}
+	})
+}
+
+func TestTimerQuery_next(t *testing.T) {
+	// A zone with an offset that is not a whole number of hours shows whether
+	// intervals are aligned in local time rather than UTC.
+	zone := time.FixedZone("IST", 5*60*60+30*60)
+	now := time.Date(2020, 3, 14, 10, 20, 30, 0, zone)
+
+	cases := []struct {
+		spec string
+		exp  time.Time
+	}{
+		{"15m", time.Date(2020, 3, 14, 10, 30, 0, 0, zone)},
+		{"1h", time.Date(2020, 3, 14, 11, 0, 0, 0, zone)},
+		{"24h", time.Date(2020, 3, 15, 0, 0, 0, 0, zone)},
+		{"0 9 * * *", time.Date(2020, 3, 15, 9, 0, 0, 0, zone)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.spec, func(t *testing.T) {
+			d, err := NewTimerQuery(tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act := d.next(now); !act.Equal(tc.exp) {
+				t.Errorf("\nexp: %s\nact: %s", tc.exp, act)
+			}
+		})
+	}
+}
+
+func TestTimerQuery_String(t *testing.T) {
+
+	d, err := NewTimerQuery("*/5 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "timer(*/5 * * * *)", d.String())
+}
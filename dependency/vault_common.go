@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
@@ -356,6 +357,21 @@ func isKVv2(client *api.Client, path string) (string, bool, error) {
 	return mountPath, false, nil
 }
 
+// VaultKVPath returns the API path of the secret at the given path, and
+// whether the secret is in version 2 of the KV secrets engine, in which case
+// "data/" is inserted into the path like it is for reading secrets.
+func VaultKVPath(client *api.Client, path string) (string, bool, error) {
+	path = strings.TrimPrefix(path, "/")
+	mountPath, isV2, err := isKVv2(client, path)
+	if err != nil {
+		return "", false, err
+	}
+	if !isV2 {
+		return path, false, nil
+	}
+	return shimKVv2Path(path, mountPath), true, nil
+}
+
 // Make sure to only set VaultDefaultLeaseDuration once
 func SetVaultDefaultLeaseDuration(t time.Duration) {
 	set := func() {
@@ -77,7 +77,8 @@ func Setup(config *Config) error {
 	}
 
 	log.SetFlags(0)
-	log.SetOutput(logOutput)
+	log.SetOutput(redactWriter{out: logOutput})
+	unredacted.Store(log.New(logOutput, "", 0))
 
 	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"log"
 	"strings"
 	"testing"
 	"time"
@@ -111,3 +113,53 @@ func TestWriter(t *testing.T) {
 			})
 	}
 }
+
+func TestRedactWriter(t *testing.T) {
+	defer SetRedact(nil)
+
+	var buf bytes.Buffer
+	w := redactWriter{out: &buf}
+
+	if _, err := w.Write([]byte("token=s3cr3t\n")); err != nil {
+		t.Fatal(err)
+	}
+	SetRedact(func(s string) string {
+		return strings.Replace(s, "s3cr3t", "<redacted>", -1)
+	})
+	n, err := w.Write([]byte("token=s3cr3t\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len("token=s3cr3t\n") {
+		t.Errorf("expected %d bytes written, got %d", len("token=s3cr3t\n"), n)
+	}
+
+	expected := "token=s3cr3t\ntoken=<redacted>\n"
+	if buf.String() != expected {
+		t.Errorf("\nexp: %q\nact: %q", expected, buf.String())
+	}
+}
+
+func TestUnredacted(t *testing.T) {
+	defer SetRedact(nil)
+
+	var buf bytes.Buffer
+	if err := Setup(&Config{Level: "INFO", Writer: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	defer Setup(&Config{Level: "INFO", Writer: ioutil.Discard})
+	SetRedact(func(s string) string {
+		return strings.Replace(s, "s3cr3t", "<redacted>", -1)
+	})
+
+	log.Printf("[INFO] token=s3cr3t")
+	Unredacted().Printf("[INFO] token=s3cr3t")
+
+	// Log lines are prefixed with a timestamp.
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 ||
+		!strings.HasSuffix(lines[0], "[INFO] token=<redacted>") ||
+		!strings.HasSuffix(lines[1], "[INFO] token=s3cr3t") {
+		t.Errorf("expected the second line to not be redacted: %q", buf.String())
+	}
+}
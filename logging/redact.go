@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"io"
+	"log"
+	"sync/atomic"
+)
+
+// redact holds the function that masks sensitive values in log lines.
+var redact atomic.Value
+
+// unredacted holds the logger that writes to the log output without masking
+// sensitive values.
+var unredacted atomic.Value
+
+// SetRedact sets the function that masks sensitive values, such as secrets
+// from Vault, in log lines. A nil function disables redaction.
+func SetRedact(fn func(string) string) {
+	redact.Store(fn)
+}
+
+// Unredacted returns a logger that writes to the log output without masking
+// sensitive values. It is only for output that the user asked to see in full,
+// such as logged diffs with redaction disabled. It returns the standard logger
+// if logging is not set up.
+func Unredacted() *log.Logger {
+	if l, ok := unredacted.Load().(*log.Logger); ok {
+		return l
+	}
+	return log.Default()
+}
+
+// redactWriter masks sensitive values in each log line before writing it to
+// the underlying writer.
+type redactWriter struct {
+	out io.Writer
+}
+
+func (w redactWriter) Write(b []byte) (int, error) {
+	fn, _ := redact.Load().(func(string) string)
+	if fn == nil {
+		return w.out.Write(b)
+	}
+	if _, err := io.WriteString(w.out, fn(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
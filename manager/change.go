@@ -0,0 +1,194 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+	"github.com/mitchellh/hashstructure"
+)
+
+// templateChange describes what changed when a template was rendered. It is
+// given to the template's command in its environment.
+type templateChange struct {
+	// ID is the ID of the template.
+	ID string `json:"template_id"`
+
+	// Destination is the path the template was rendered to.
+	Destination string `json:"destination"`
+
+	// PreviousChecksum and Checksum are the hex-encoded SHA256 checksums of the
+	// destination before and after it was rendered. PreviousChecksum is empty
+	// if the destination did not exist.
+	PreviousChecksum string `json:"previous_checksum"`
+	Checksum         string `json:"checksum"`
+
+	// Dependencies are the dependencies whose data changed since the template
+	// was last rendered. Every dependency is changed on the first render.
+	Dependencies []*dependencyChange `json:"changed_dependencies"`
+}
+
+// dependencyChange is the previous and current data of a dependency.
+type dependencyChange struct {
+	Dependency string      `json:"dependency"`
+	Previous   interface{} `json:"previous"`
+	Current    interface{} `json:"current"`
+}
+
+// env returns the environment variables describing the change.
+func (c *templateChange) env() []string {
+	deps := make([]string, len(c.Dependencies))
+	for i, d := range c.Dependencies {
+		deps[i] = d.Dependency
+	}
+
+	return []string{
+		"CT_TEMPLATE_ID=" + c.ID,
+		"CT_DESTINATION=" + c.Destination,
+		"CT_PREVIOUS_CHECKSUM=" + c.PreviousChecksum,
+		"CT_CHECKSUM=" + c.Checksum,
+		"CT_CHANGED_DEPENDENCIES=" + strings.Join(deps, ","),
+	}
+}
+
+// writeFile writes the change as JSON to a new temporary file, readable only
+// by the current user, and returns its path. The caller is responsible for
+// removing the file.
+func (c *templateChange) writeFile() (string, error) {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	f, err := ioutil.TempFile("", "consul-template-diff-")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// checksum returns the hex-encoded SHA256 checksum of the contents, or an
+// empty string if the contents are nil.
+func checksum(contents []byte) string {
+	if contents == nil {
+		return ""
+	}
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// renderedDependency is the data of a dependency when a template was last
+// rendered.
+type renderedDependency struct {
+	// hash is the hash of the data. If hashed is false, the data could not be
+	// hashed, and the dependency is always reported as changed.
+	hash   uint64
+	hashed bool
+
+	// data is the data itself, which is only kept for templates that give
+	// their commands a diff file, so the runner does not hold a copy of the
+	// data of every template.
+	data interface{}
+}
+
+// newRenderedDependency returns the rendered dependency for the data,
+// keeping the data if keepData is true.
+func newRenderedDependency(data interface{}, keepData bool) *renderedDependency {
+	rd := &renderedDependency{}
+	if hash, err := hashstructure.Hash(data, nil); err == nil {
+		rd.hash, rd.hashed = hash, true
+	}
+	if keepData {
+		rd.data = data
+	}
+	return rd
+}
+
+// changedDependencies returns the used dependencies whose data in the brain
+// differs from the data they had when the template was last rendered, sorted
+// by name. Their previous and current data are only included if the template
+// gives its commands a diff file, and the data of sensitive dependencies is
+// redacted.
+func (r *Runner) changedDependencies(tmpl *template.Template, used *dep.Set) []*dependencyChange {
+	previous := r.renderedData[tmpl.ID()]
+	keepData := r.keepRenderedData(tmpl)
+
+	var changes []*dependencyChange
+	for _, d := range used.List() {
+		data, _ := r.brain.Recall(d)
+		current := newRenderedDependency(data, keepData)
+		prev, ok := previous[d.String()]
+		if ok && prev.hashed && current.hashed && prev.hash == current.hash {
+			continue
+		}
+
+		change := &dependencyChange{
+			Dependency: d.String(),
+		}
+		if keepData {
+			if ok {
+				change.Previous = prev.data
+			}
+			change.Current = current.data
+			if r.brain.Sensitive(d) || d.Type() == dep.TypeVault {
+				change.Previous = redactData(change.Previous)
+				change.Current = redactData(change.Current)
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Dependency < changes[j].Dependency
+	})
+	return changes
+}
+
+// redactData returns the redacted placeholder for the data, or nil if there is
+// no data.
+func redactData(data interface{}) interface{} {
+	if data == nil {
+		return nil
+	}
+	return template.Redacted
+}
+
+// rememberRendered records the data of the used dependencies that the template
+// was rendered with, to find the changed dependencies on the next render.
+func (r *Runner) rememberRendered(tmpl *template.Template, used *dep.Set) {
+	keepData := r.keepRenderedData(tmpl)
+	data := make(map[string]*renderedDependency, used.Len())
+	for _, d := range used.List() {
+		if v, ok := r.brain.Recall(d); ok {
+			data[d.String()] = newRenderedDependency(v, keepData)
+		}
+	}
+	r.renderedData[tmpl.ID()] = data
+}
+
+// keepRenderedData returns true if any configuration of the template gives its
+// command a diff file, which needs the previous data of the dependencies.
+func (r *Runner) keepRenderedData(tmpl *template.Template) bool {
+	for _, tc := range r.templateConfigsFor(tmpl) {
+		if config.BoolVal(tc.ExecDiffFile) {
+			return true
+		}
+	}
+	return false
+}
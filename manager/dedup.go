@@ -2,11 +2,8 @@ package manager
 
 import (
 	"bytes"
-	"compress/lzw"
-	"encoding/gob"
 	"fmt"
 	"log"
-	"path"
 	"sync"
 	"time"
 
@@ -16,27 +13,14 @@ import (
 	dep "github.com/hashicorp/consul-template/dependency"
 	"github.com/hashicorp/consul-template/template"
 	"github.com/hashicorp/consul-template/version"
-	consulapi "github.com/hashicorp/consul/api"
 )
 
 var (
-	// sessionCreateRetry is the amount of time we wait
-	// to recreate a session when lost.
-	sessionCreateRetry = 15 * time.Second
-
 	// lockRetry is the interval on which we try to re-acquire locks
 	lockRetry = 10 * time.Second
 
 	// listRetry is the interval on which we retry listing a data path
 	listRetry = 10 * time.Second
-
-	// timeout passed through to consul api client Lock
-	// here to override in testing (see ./dedup_test.go)
-	lockWaitTime = 15 * time.Second
-)
-
-const (
-	templateNoDataStr = "__NO_DATA__"
 )
 
 // templateData is GOB encoded share the dependency values
@@ -50,8 +34,41 @@ type templateData struct {
 	Data map[string]interface{}
 }
 
-func templateNoData() []byte {
-	return []byte(templateNoDataStr)
+// DedupBackend is the storage used by the DedupManager to elect a leader for
+// each template and to share the template data from the leader with the
+// followers. Templates are identified by their ID.
+type DedupBackend interface {
+	// Start begins leader election for each of the given template IDs and
+	// returns immediately. When leadership of a template is acquired, leaderFn
+	// is called with a channel that is closed once leadership is lost. When
+	// leadership is lost, leaderFn is called with a nil channel. Errors while
+	// trying to acquire leadership are reported to failFn.
+	Start(ids []string, leaderFn func(id string, lockCh <-chan struct{}), failFn func(id string, err error)) error
+
+	// Stop halts leader election, releases any held leadership and blocks
+	// until all background work has finished.
+	Stop()
+
+	// Put stores the encoded data for the given template ID.
+	Put(id string, data []byte) error
+
+	// Get blocks until the data for the given template ID changes from the
+	// given index or until the backend's wait time passes. It returns the data,
+	// which is nil if the leader has not shared any data, and the index to use
+	// for the next call.
+	Get(id string, index uint64) ([]byte, uint64, error)
+}
+
+// newDedupBackend creates the backend named in the given configuration.
+func newDedupBackend(c *config.DedupConfig, clients *dep.ClientSet) (DedupBackend, error) {
+	switch b := config.StringVal(c.Backend); b {
+	case "", config.DedupBackendConsul:
+		return newConsulDedupBackend(c, clients), nil
+	case config.DedupBackendFile:
+		return newFileDedupBackend(c)
+	default:
+		return nil, fmt.Errorf("dedup: unknown backend %q", b)
+	}
 }
 
 // DedupManager is used to de-duplicate which instance of Consul-Template
@@ -61,10 +78,11 @@ func templateNoData() []byte {
 //
 // The leader instance operations like usual, but any time a template is
 // rendered, any of the data required for rendering is stored in the
-// Consul KV store under the lock path.
+// backend under the lock path. By default, the backend is the Consul KV
+// store.
 //
 // The follower instances depend on the leader to do the primary watching
-// and rendering, and instead only watch the aggregated data in the KV.
+// and rendering, and instead only watch the aggregated data in the backend.
 // Followers wait for updates and re-render the template.
 //
 // If a template depends on 50 views, and is running on 50 machines, that
@@ -76,8 +94,11 @@ type DedupManager struct {
 	// config is the deduplicate configuration
 	config *config.DedupConfig
 
-	// clients is used to access the underlying clients
-	clients *dep.ClientSet
+	// backend is used for leader election and to share data
+	backend DedupBackend
+
+	// codec encodes and optionally encrypts the shared data
+	codec *dedupCodec
 
 	// Brain is where we inject updates
 	brain *template.Brain
@@ -96,8 +117,13 @@ type DedupManager struct {
 	// updateCh is used to indicate an update watched data
 	updateCh chan struct{}
 
-	// wg is used to wait for a clean shutdown
-	wg sync.WaitGroup
+	// status tracks the de-duplication state of each template
+	status         map[*template.Template]*DedupStatus
+	statusLock     sync.Mutex
+	statusFileLock sync.Mutex
+
+	// statusFileContents are the contents last written to the status file.
+	statusFileContents []byte
 
 	stop     bool
 	stopCh   chan struct{}
@@ -106,14 +132,26 @@ type DedupManager struct {
 
 // NewDedupManager creates a new Dedup manager
 func NewDedupManager(config *config.DedupConfig, clients *dep.ClientSet, brain *template.Brain, templates []*template.Template) (*DedupManager, error) {
+	backend, err := newDedupBackend(config, clients)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := newDedupCodec(config, clients)
+	if err != nil {
+		return nil, err
+	}
+
 	d := &DedupManager{
 		config:    config,
-		clients:   clients,
+		backend:   backend,
+		codec:     codec,
 		brain:     brain,
 		templates: templates,
 		leader:    make(map[*template.Template]<-chan struct{}),
 		lastWrite: make(map[*template.Template]uint64),
 		updateCh:  make(chan struct{}, 1),
+		status:    make(map[*template.Template]*DedupStatus),
 		stopCh:    make(chan struct{}),
 	}
 	return d, nil
@@ -123,12 +161,32 @@ func NewDedupManager(config *config.DedupConfig, clients *dep.ClientSet, brain *
 func (d *DedupManager) Start() error {
 	log.Printf("[INFO] (dedup) starting de-duplication manager")
 
-	client := d.clients.Consul()
-	go d.createSession(client)
+	ids := make([]string, 0, len(d.templates))
+	byID := make(map[string]*template.Template, len(d.templates))
+	for _, t := range d.templates {
+		ids = append(ids, t.ID())
+		byID[t.ID()] = t
+		d.updateStatus(t, func(*DedupStatus) {})
+	}
+
+	leaderFn := func(id string, lockCh <-chan struct{}) {
+		if t, ok := byID[id]; ok {
+			d.setLeader(t, lockCh)
+		}
+	}
+	failFn := func(id string, err error) {
+		if t, ok := byID[id]; ok {
+			d.lockFailed(t, err)
+		}
+	}
+	err := d.backend.Start(ids, leaderFn, failFn)
+	if err != nil {
+		return err
+	}
 
 	// Start to watch each template
 	for _, t := range d.templates {
-		go d.watchTemplate(client, t)
+		go d.watchTemplate(t)
 	}
 	return nil
 }
@@ -144,52 +202,10 @@ func (d *DedupManager) Stop() error {
 	log.Printf("[INFO] (dedup) stopping de-duplication manager")
 	d.stop = true
 	close(d.stopCh)
-	d.wg.Wait()
+	d.backend.Stop()
 	return nil
 }
 
-// createSession is used to create and maintain a session to Consul
-func (d *DedupManager) createSession(client *consulapi.Client) {
-START:
-	log.Printf("[INFO] (dedup) attempting to create session")
-	session := client.Session()
-	sessionCh := make(chan struct{})
-	ttl := fmt.Sprintf("%.6fs", float64(*d.config.TTL)/float64(time.Second))
-	se := &consulapi.SessionEntry{
-		Name:      "Consul-Template de-duplication",
-		Behavior:  "delete",
-		TTL:       ttl,
-		LockDelay: 1 * time.Millisecond,
-	}
-	id, _, err := session.Create(se, nil)
-	if err != nil {
-		log.Printf("[ERR] (dedup) failed to create session: %v", err)
-		goto WAIT
-	}
-	log.Printf("[INFO] (dedup) created session %s", id)
-
-	// Attempt to lock each template
-	for _, t := range d.templates {
-		d.wg.Add(1)
-		go d.attemptLock(client, id, sessionCh, t)
-	}
-
-	// Renew our session periodically
-	if err := session.RenewPeriodic("15s", id, nil, d.stopCh); err != nil {
-		log.Printf("[ERR] (dedup) failed to renew session: %v", err)
-	}
-	close(sessionCh)
-	d.wg.Wait()
-
-WAIT:
-	select {
-	case <-time.After(sessionCreateRetry):
-		goto START
-	case <-d.stopCh:
-		return
-	}
-}
-
 // IsLeader checks if we are currently the leader instance
 func (d *DedupManager) IsLeader(tmpl *template.Template) bool {
 	d.leaderLock.RLock()
@@ -209,9 +225,6 @@ func (d *DedupManager) IsLeader(tmpl *template.Template) bool {
 
 // UpdateDeps is used to update the values of the dependencies for a template
 func (d *DedupManager) UpdateDeps(t *template.Template, deps []dep.Dependency) error {
-	// Calculate the path to write updates to
-	dataPath := path.Join(*d.config.Prefix, t.ID(), "data")
-
 	// Package up the dependency data
 	td := templateData{
 		Version: version.Version,
@@ -242,37 +255,43 @@ func (d *DedupManager) UpdateDeps(t *template.Template, deps []dep.Dependency) e
 	existing, ok := d.lastWrite[t]
 	d.lastWriteLock.RUnlock()
 	if ok && existing == hash {
-		log.Printf("[INFO] (dedup) de-duplicate data '%s' already current",
-			dataPath)
+		log.Printf("[INFO] (dedup) de-duplicate data for template hash %s already current",
+			t.ID())
 		return nil
 	}
 
-	// Encode via GOB and LZW compress
-	var buf bytes.Buffer
-	compress := lzw.NewWriter(&buf, lzw.LSB, 8)
-	enc := gob.NewEncoder(compress)
-	if err := enc.Encode(&td); err != nil {
-		return fmt.Errorf("encode failed: %v", err)
+	data, err := d.codec.encode(&td)
+	if err != nil {
+		return err
 	}
-	compress.Close()
 
-	// Write the KV update
-	kvPair := consulapi.KVPair{
-		Key:   dataPath,
-		Value: buf.Bytes(),
-		Flags: consulapi.LockFlagValue,
-	}
-	client := d.clients.Consul()
-	if _, err := client.KV().Put(&kvPair, nil); err != nil {
-		return fmt.Errorf("failed to write '%s': %v", dataPath, err)
+	// Write the update to the backend
+	if err := d.backend.Put(t.ID(), data); err != nil {
+		return err
 	}
-	log.Printf("[INFO] (dedup) updated de-duplicate data '%s'", dataPath)
+	log.Printf("[INFO] (dedup) updated de-duplicate data for template hash %s", t.ID())
 	d.lastWriteLock.Lock()
 	d.lastWrite[t] = hash
 	d.lastWriteLock.Unlock()
+	d.updateStatus(t, func(s *DedupStatus) {
+		s.LastUpdate = time.Now()
+	})
 	return nil
 }
 
+// lockFailed records an error while trying to acquire leadership
+func (d *DedupManager) lockFailed(tmpl *template.Template, err error) {
+	var failures uint64
+	d.updateStatus(tmpl, func(s *DedupStatus) {
+		s.LockFailures++
+		s.LastLockError = err.Error()
+		s.LastLockErrorTime = time.Now()
+		failures = s.LockFailures
+	})
+	log.Printf("[WARN] (dedup) lock failure %d for template hash %s: %v",
+		failures, tmpl.ID(), err)
+}
+
 // UpdateCh returns a channel to watch for dependency updates
 func (d *DedupManager) UpdateCh() <-chan struct{} {
 	return d.updateCh
@@ -296,6 +315,13 @@ func (d *DedupManager) setLeader(tmpl *template.Template, lockCh <-chan struct{}
 		d.lastWriteLock.Unlock()
 	}
 
+	log.Printf("[INFO] (dedup) leader for template hash %s: %t",
+		tmpl.ID(), lockCh != nil)
+	d.updateStatus(tmpl, func(s *DedupStatus) {
+		s.Leader = lockCh != nil
+		s.LeaderSince = time.Now()
+	})
+
 	// Do an async notify of an update
 	select {
 	case d.updateCh <- struct{}{}:
@@ -303,21 +329,8 @@ func (d *DedupManager) setLeader(tmpl *template.Template, lockCh <-chan struct{}
 	}
 }
 
-func (d *DedupManager) watchTemplate(client *consulapi.Client, t *template.Template) {
+func (d *DedupManager) watchTemplate(t *template.Template) {
 	log.Printf("[INFO] (dedup) starting watch for template hash %s", t.ID())
-	path := path.Join(*d.config.Prefix, t.ID(), "data")
-
-	// Determine if stale queries are allowed
-	var allowStale bool
-	if *d.config.MaxStale != 0 {
-		allowStale = true
-	}
-
-	// Setup our query options
-	opts := &consulapi.QueryOptions{
-		AllowStale: allowStale,
-		WaitTime:   60 * time.Second,
-	}
 
 	var lastData []byte
 	var lastIndex uint64
@@ -343,11 +356,12 @@ START:
 		}
 	}
 
-	// Block for updates on the data key
+	// Block for updates on the data
 	log.Printf("[INFO] (dedup) listing data for template hash %s", t.ID())
-	pair, meta, err := client.KV().Get(path, opts)
+	data, index, err := d.backend.Get(t.ID(), lastIndex)
 	if err != nil {
-		log.Printf("[ERR] (dedup) failed to get '%s': %v", path, err)
+		log.Printf("[ERR] (dedup) failed to get data for template hash %s: %v",
+			t.ID(), err)
 		select {
 		case <-time.After(listRetry):
 			goto START
@@ -355,7 +369,6 @@ START:
 			return
 		}
 	}
-	opts.WaitIndex = meta.LastIndex
 
 	// Stop listening if we're stopped
 	select {
@@ -364,36 +377,24 @@ START:
 	default:
 	}
 
-	// If we've exceeded the maximum staleness, retry without stale
-	if allowStale && meta.LastContact > *d.config.MaxStale {
-		allowStale = false
-		log.Printf("[DEBUG] (dedup) %s stale data (last contact exceeded max_stale)", path)
-		goto START
-	}
-
-	// Re-enable stale queries if allowed
-	if *d.config.MaxStale > 0 {
-		allowStale = true
-	}
+	d.updateStatus(t, func(s *DedupStatus) {
+		s.LastPoll = time.Now()
+	})
 
-	if meta.LastIndex == lastIndex {
-		log.Printf("[TRACE] (dedup) %s no new data (index was the same)", path)
+	if index == lastIndex {
+		log.Printf("[TRACE] (dedup) %s no new data (index was the same)", t.ID())
 		goto START
 	}
 
-	if meta.LastIndex < lastIndex {
-		log.Printf("[TRACE] (dedup) %s had a lower index, resetting", path)
+	if index < lastIndex {
+		log.Printf("[TRACE] (dedup) %s had a lower index, resetting", t.ID())
 		lastIndex = 0
 		goto START
 	}
-	lastIndex = meta.LastIndex
+	lastIndex = index
 
-	var data []byte
-	if pair != nil {
-		data = pair.Value
-	}
 	if bytes.Equal(lastData, data) {
-		log.Printf("[TRACE] (dedup) %s no new data (contents were the same)", path)
+		log.Printf("[TRACE] (dedup) %s no new data (contents were the same)", t.ID())
 		goto START
 	}
 	lastData = data
@@ -412,25 +413,21 @@ START:
 	}
 
 	// Parse the data file
-	if pair != nil && pair.Flags == consulapi.LockFlagValue && !bytes.Equal(pair.Value, templateNoData()) {
-		d.parseData(pair.Key, pair.Value)
+	if data != nil {
+		d.parseData(t, data)
 	}
 	goto START
 }
 
-// parseData is used to update brain from a KV data pair
-func (d *DedupManager) parseData(path string, raw []byte) {
-	// Setup the decompression and decoders
-	r := bytes.NewReader(raw)
-	decompress := lzw.NewReader(r, lzw.LSB, 8)
-	defer decompress.Close()
-	dec := gob.NewDecoder(decompress)
+// parseData is used to update brain from the shared data of a template
+func (d *DedupManager) parseData(t *template.Template, raw []byte) {
+	id := t.ID()
 
 	// Decode the data
-	var td templateData
-	if err := dec.Decode(&td); err != nil {
-		log.Printf("[ERR] (dedup) failed to decode '%s': %v",
-			path, err)
+	td, err := d.codec.decode(raw)
+	if err != nil {
+		log.Printf("[WARN] (dedup) ignoring incompatible data for template hash %s: %v",
+			id, err)
 		return
 	}
 	if td.Version != version.Version {
@@ -438,13 +435,16 @@ func (d *DedupManager) parseData(path string, raw []byte) {
 			td.Version, version.Version)
 		return
 	}
-	log.Printf("[INFO] (dedup) loading %d dependencies from '%s'",
-		len(td.Data), path)
+	log.Printf("[INFO] (dedup) loading %d dependencies for template hash %s",
+		len(td.Data), id)
 
 	// Update the data in the brain
 	for hashCode, value := range td.Data {
 		d.brain.ForceSet(hashCode, value)
 	}
+	d.updateStatus(t, func(s *DedupStatus) {
+		s.LastUpdate = time.Now()
+	})
 
 	// Trigger the updateCh
 	select {
@@ -452,61 +452,3 @@ func (d *DedupManager) parseData(path string, raw []byte) {
 	default:
 	}
 }
-
-func (d *DedupManager) attemptLock(client *consulapi.Client, session string, sessionCh chan struct{}, t *template.Template) {
-	defer d.wg.Done()
-	for {
-		log.Printf("[INFO] (dedup) attempting lock for template hash %s", t.ID())
-		basePath := path.Join(*d.config.Prefix, t.ID())
-		lopts := &consulapi.LockOptions{
-			Key:              path.Join(basePath, "data"),
-			Value:            templateNoData(),
-			Session:          session,
-			MonitorRetries:   3,
-			MonitorRetryTime: 3 * time.Second,
-			LockWaitTime:     lockWaitTime,
-		}
-		lock, err := client.LockOpts(lopts)
-		if err != nil {
-			log.Printf("[ERR] (dedup) failed to create lock '%s': %v",
-				lopts.Key, err)
-			return
-		}
-
-		var retryCh <-chan time.Time
-		leaderCh, err := lock.Lock(sessionCh)
-		if err != nil {
-			log.Printf("[ERR] (dedup) failed to acquire lock '%s': %v",
-				lopts.Key, err)
-			retryCh = time.After(lockRetry)
-		} else {
-			log.Printf("[INFO] (dedup) acquired lock '%s'", lopts.Key)
-			d.setLeader(t, leaderCh)
-		}
-
-		select {
-		case <-retryCh:
-			retryCh = nil
-			continue
-		case <-leaderCh:
-			log.Printf("[WARN] (dedup) lost lock ownership '%s'", lopts.Key)
-			d.setLeader(t, nil)
-			continue
-		case <-sessionCh:
-			log.Printf("[INFO] (dedup) releasing session '%s'", lopts.Key)
-			d.setLeader(t, nil)
-			_, err = client.Session().Destroy(session, nil)
-			if err != nil {
-				log.Printf("[ERROR] (dedup) failed destroying session '%s', %s", session, err)
-			}
-			return
-		case <-d.stopCh:
-			log.Printf("[INFO] (dedup) releasing lock '%s'", lopts.Key)
-			_, err = client.Session().Destroy(session, nil)
-			if err != nil {
-				log.Printf("[ERROR] (dedup) failed destroying session '%s', %s", session, err)
-			}
-			return
-		}
-	}
-}
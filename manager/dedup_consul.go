@@ -0,0 +1,230 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+var (
+	// sessionCreateRetry is the amount of time we wait
+	// to recreate a session when lost.
+	sessionCreateRetry = 15 * time.Second
+
+	// timeout passed through to consul api client Lock
+	// here to override in testing (see ./dedup_test.go)
+	lockWaitTime = 15 * time.Second
+)
+
+const (
+	templateNoDataStr = "__NO_DATA__"
+)
+
+func templateNoData() []byte {
+	return []byte(templateNoDataStr)
+}
+
+// consulDedupBackend is the DedupBackend that uses Consul sessions to elect
+// a leader and the Consul KV store to share the template data.
+type consulDedupBackend struct {
+	// config is the deduplicate configuration
+	config *config.DedupConfig
+
+	// clients is used to access the Consul client
+	clients *dep.ClientSet
+
+	// wg is used to wait for a clean shutdown
+	wg sync.WaitGroup
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func newConsulDedupBackend(config *config.DedupConfig, clients *dep.ClientSet) *consulDedupBackend {
+	return &consulDedupBackend{
+		config:  config,
+		clients: clients,
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Start implements DedupBackend.
+func (b *consulDedupBackend) Start(ids []string, leaderFn func(string, <-chan struct{}), failFn func(string, error)) error {
+	go b.createSession(b.clients.Consul(), ids, leaderFn, failFn)
+	return nil
+}
+
+// Stop implements DedupBackend.
+func (b *consulDedupBackend) Stop() {
+	b.stopOnce.Do(func() {
+		close(b.stopCh)
+	})
+	b.wg.Wait()
+}
+
+// Put implements DedupBackend.
+func (b *consulDedupBackend) Put(id string, data []byte) error {
+	dataPath := b.dataPath(id)
+	kvPair := consulapi.KVPair{
+		Key:   dataPath,
+		Value: data,
+		Flags: consulapi.LockFlagValue,
+	}
+	client := b.clients.Consul()
+	if _, err := client.KV().Put(&kvPair, nil); err != nil {
+		return fmt.Errorf("failed to write '%s': %v", dataPath, err)
+	}
+	return nil
+}
+
+// Get implements DedupBackend.
+func (b *consulDedupBackend) Get(id string, index uint64) ([]byte, uint64, error) {
+	dataPath := b.dataPath(id)
+
+	// Determine if stale queries are allowed
+	allowStale := *b.config.MaxStale != 0
+
+	client := b.clients.Consul()
+	for {
+		opts := &consulapi.QueryOptions{
+			AllowStale: allowStale,
+			WaitIndex:  index,
+			WaitTime:   *b.config.BlockQueryWaitTime,
+		}
+		pair, meta, err := client.KV().Get(dataPath, opts)
+		if err != nil {
+			return nil, index, fmt.Errorf("failed to get '%s': %v", dataPath, err)
+		}
+
+		// If we've exceeded the maximum staleness, retry without stale
+		if allowStale && meta.LastContact > *b.config.MaxStale {
+			allowStale = false
+			log.Printf("[DEBUG] (dedup) %s stale data (last contact exceeded max_stale)", dataPath)
+			continue
+		}
+
+		// Only share data that was written by a leader
+		var data []byte
+		if pair != nil && pair.Flags == consulapi.LockFlagValue &&
+			!bytes.Equal(pair.Value, templateNoData()) {
+			data = pair.Value
+		}
+		return data, meta.LastIndex, nil
+	}
+}
+
+// dataPath returns the KV path of the lock and data for a template.
+func (b *consulDedupBackend) dataPath(id string) string {
+	return path.Join(*b.config.Prefix, id, "data")
+}
+
+// createSession is used to create and maintain a session to Consul
+func (b *consulDedupBackend) createSession(client *consulapi.Client, ids []string, leaderFn func(string, <-chan struct{}), failFn func(string, error)) {
+START:
+	log.Printf("[INFO] (dedup) attempting to create session")
+	session := client.Session()
+	sessionCh := make(chan struct{})
+	ttl := fmt.Sprintf("%.6fs", float64(*b.config.TTL)/float64(time.Second))
+	se := &consulapi.SessionEntry{
+		Name:      "Consul-Template de-duplication",
+		Behavior:  "delete",
+		TTL:       ttl,
+		LockDelay: 1 * time.Millisecond,
+	}
+	id, _, err := session.Create(se, nil)
+	if err != nil {
+		log.Printf("[ERR] (dedup) failed to create session: %v", err)
+		for _, tid := range ids {
+			failFn(tid, fmt.Errorf("failed to create session: %v", err))
+		}
+		goto WAIT
+	}
+	log.Printf("[INFO] (dedup) created session %s", id)
+
+	// Attempt to lock each template
+	for _, tid := range ids {
+		b.wg.Add(1)
+		go b.attemptLock(client, id, sessionCh, tid, leaderFn, failFn)
+	}
+
+	// Renew our session periodically
+	if err := session.RenewPeriodic("15s", id, nil, b.stopCh); err != nil {
+		log.Printf("[ERR] (dedup) failed to renew session: %v", err)
+	}
+	close(sessionCh)
+	b.wg.Wait()
+
+WAIT:
+	select {
+	case <-time.After(sessionCreateRetry):
+		goto START
+	case <-b.stopCh:
+		return
+	}
+}
+
+func (b *consulDedupBackend) attemptLock(client *consulapi.Client, session string, sessionCh chan struct{}, id string, leaderFn func(string, <-chan struct{}), failFn func(string, error)) {
+	defer b.wg.Done()
+	for {
+		log.Printf("[INFO] (dedup) attempting lock for template hash %s", id)
+		lopts := &consulapi.LockOptions{
+			Key:              b.dataPath(id),
+			Value:            templateNoData(),
+			Session:          session,
+			MonitorRetries:   3,
+			MonitorRetryTime: 3 * time.Second,
+			LockWaitTime:     lockWaitTime,
+		}
+		lock, err := client.LockOpts(lopts)
+		if err != nil {
+			log.Printf("[ERR] (dedup) failed to create lock '%s': %v",
+				lopts.Key, err)
+			failFn(id, err)
+			return
+		}
+
+		var retryCh <-chan time.Time
+		leaderCh, err := lock.Lock(sessionCh)
+		if err != nil {
+			log.Printf("[ERR] (dedup) failed to acquire lock '%s': %v",
+				lopts.Key, err)
+			failFn(id, err)
+			retryCh = time.After(lockRetry)
+		} else {
+			log.Printf("[INFO] (dedup) acquired lock '%s'", lopts.Key)
+			leaderFn(id, leaderCh)
+		}
+
+		select {
+		case <-retryCh:
+			retryCh = nil
+			continue
+		case <-leaderCh:
+			log.Printf("[WARN] (dedup) lost lock ownership '%s'", lopts.Key)
+			leaderFn(id, nil)
+			continue
+		case <-sessionCh:
+			log.Printf("[INFO] (dedup) releasing session '%s'", lopts.Key)
+			leaderFn(id, nil)
+			_, err = client.Session().Destroy(session, nil)
+			if err != nil {
+				log.Printf("[ERROR] (dedup) failed destroying session '%s', %s", session, err)
+			}
+			return
+		case <-b.stopCh:
+			log.Printf("[INFO] (dedup) releasing lock '%s'", lopts.Key)
+			_, err = client.Session().Destroy(session, nil)
+			if err != nil {
+				log.Printf("[ERROR] (dedup) failed destroying session '%s', %s", session, err)
+			}
+			return
+		}
+	}
+}
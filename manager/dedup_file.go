@@ -0,0 +1,300 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/renderer"
+)
+
+var (
+	// filePollInterval is the interval on which the file backend checks the
+	// shared data for changes.
+	filePollInterval = 1 * time.Second
+)
+
+const (
+	// fileDedupLock and fileDedupData are the names of the files used for
+	// each template under the template's directory.
+	fileDedupLock = "lock"
+	fileDedupData = "data"
+)
+
+// fileDedupBackend is the DedupBackend that uses files on a shared
+// filesystem (for example NFS or a shared volume) to elect a leader and to
+// share the template data. The leader holds a lock file containing its
+// owner ID and refreshes its modification time; a lock that has not been
+// refreshed within the TTL is considered stale and may be taken over.
+type fileDedupBackend struct {
+	// config is the deduplicate configuration
+	config *config.DedupConfig
+
+	// owner is the unique ID written into lock files held by this instance
+	owner string
+
+	// wg is used to wait for a clean shutdown
+	wg sync.WaitGroup
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func newFileDedupBackend(config *config.DedupConfig) (*fileDedupBackend, error) {
+	if config.Path == nil || *config.Path == "" {
+		return nil, fmt.Errorf("dedup: file backend requires a path")
+	}
+
+	// The lock is refreshed every third of the TTL, which must not be zero.
+	if config.TTL == nil || *config.TTL < time.Second {
+		return nil, fmt.Errorf("dedup: file backend requires a ttl of at least 1s")
+	}
+
+	owner, err := fileDedupOwner()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileDedupBackend{
+		config: config,
+		owner:  owner,
+		stopCh: make(chan struct{}),
+	}, nil
+}
+
+// fileDedupOwner returns a unique ID for this instance.
+func fileDedupOwner() (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("dedup: failed to generate owner id: %v", err)
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b)), nil
+}
+
+// Start implements DedupBackend.
+func (b *fileDedupBackend) Start(ids []string, leaderFn func(string, <-chan struct{}), failFn func(string, error)) error {
+	for _, id := range ids {
+		if err := os.MkdirAll(b.dir(id), 0755); err != nil {
+			return fmt.Errorf("dedup: failed to create '%s': %v", b.dir(id), err)
+		}
+	}
+
+	for _, id := range ids {
+		b.wg.Add(1)
+		go b.attemptLock(id, leaderFn, failFn)
+	}
+	return nil
+}
+
+// Stop implements DedupBackend.
+func (b *fileDedupBackend) Stop() {
+	b.stopOnce.Do(func() {
+		close(b.stopCh)
+	})
+	b.wg.Wait()
+}
+
+// Put implements DedupBackend.
+func (b *fileDedupBackend) Put(id string, data []byte) error {
+	dataPath := filepath.Join(b.dir(id), fileDedupData)
+	if err := renderer.AtomicWrite(dataPath, true, data, 0600, false); err != nil {
+		return fmt.Errorf("failed to write '%s': %v", dataPath, err)
+	}
+	return nil
+}
+
+// Get implements DedupBackend. The index is the modification time of the
+// data file, or 0 if there is no data file.
+func (b *fileDedupBackend) Get(id string, index uint64) ([]byte, uint64, error) {
+	dataPath := filepath.Join(b.dir(id), fileDedupData)
+	timeout := time.After(*b.config.BlockQueryWaitTime)
+
+	for {
+		var current uint64
+		fi, err := os.Stat(dataPath)
+		switch {
+		case err == nil:
+			current = uint64(fi.ModTime().UnixNano())
+		case !os.IsNotExist(err):
+			return nil, index, fmt.Errorf("failed to stat '%s': %v", dataPath, err)
+		}
+
+		if current != index {
+			if current == 0 {
+				return nil, 0, nil
+			}
+			data, err := ioutil.ReadFile(dataPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil, 0, nil
+				}
+				return nil, index, fmt.Errorf("failed to read '%s': %v", dataPath, err)
+			}
+			return data, current, nil
+		}
+
+		select {
+		case <-time.After(filePollInterval):
+		case <-timeout:
+			return nil, index, nil
+		case <-b.stopCh:
+			return nil, index, nil
+		}
+	}
+}
+
+// dir returns the directory holding the lock and data for a template.
+func (b *fileDedupBackend) dir(id string) string {
+	return filepath.Join(*b.config.Path, *b.config.Prefix, id)
+}
+
+func (b *fileDedupBackend) attemptLock(id string, leaderFn func(string, <-chan struct{}), failFn func(string, error)) {
+	defer b.wg.Done()
+
+	lockPath := filepath.Join(b.dir(id), fileDedupLock)
+	ttl := *b.config.TTL
+	for {
+		log.Printf("[INFO] (dedup) attempting lock for template hash %s", id)
+
+		var retryCh <-chan time.Time
+		var leaderCh chan struct{}
+		var heartbeat *time.Ticker
+		var heartbeatCh <-chan time.Time
+		ok, err := b.acquire(lockPath, ttl)
+		switch {
+		case err != nil:
+			log.Printf("[ERR] (dedup) failed to acquire lock '%s': %v", lockPath, err)
+			failFn(id, err)
+			retryCh = time.After(lockRetry)
+		case !ok:
+			retryCh = time.After(lockRetry)
+		default:
+			log.Printf("[INFO] (dedup) acquired lock '%s'", lockPath)
+
+			// Clear any data left by a previous leader
+			dataPath := filepath.Join(b.dir(id), fileDedupData)
+			if err := os.Remove(dataPath); err != nil && !os.IsNotExist(err) {
+				log.Printf("[WARN] (dedup) failed to clear '%s': %v", dataPath, err)
+			}
+
+			leaderCh = make(chan struct{})
+			leaderFn(id, leaderCh)
+			heartbeat = time.NewTicker(ttl / 3)
+			heartbeatCh = heartbeat.C
+		}
+
+	WAIT:
+		select {
+		case <-retryCh:
+			continue
+		case <-heartbeatCh:
+			if err := b.refresh(lockPath); err != nil {
+				log.Printf("[WARN] (dedup) lost lock ownership '%s': %v", lockPath, err)
+				heartbeat.Stop()
+				close(leaderCh)
+				leaderFn(id, nil)
+				continue
+			}
+			goto WAIT
+		case <-b.stopCh:
+			if leaderCh != nil {
+				log.Printf("[INFO] (dedup) releasing lock '%s'", lockPath)
+				heartbeat.Stop()
+				if b.owns(lockPath) {
+					if err := os.Remove(lockPath); err != nil {
+						log.Printf("[ERROR] (dedup) failed releasing lock '%s', %s", lockPath, err)
+					}
+				}
+				close(leaderCh)
+			}
+			return
+		}
+	}
+}
+
+// acquire attempts to create the lock file, taking over the lock if the
+// current holder has not refreshed it within the TTL.
+func (b *fileDedupBackend) acquire(lockPath string, ttl time.Duration) (bool, error) {
+	f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		_, err = f.WriteString(b.owner)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(lockPath)
+			return false, err
+		}
+		return true, nil
+	}
+	if !os.IsExist(err) {
+		return false, err
+	}
+
+	fi, err := os.Stat(lockPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if time.Since(fi.ModTime()) <= ttl {
+		return false, nil
+	}
+
+	// The lock is stale. Move it aside atomically so only one instance takes
+	// it over, then check again that it is still stale in case its holder
+	// refreshed it in between.
+	stalePath := lockPath + "." + b.owner
+	if err := os.Rename(lockPath, stalePath); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer os.Remove(stalePath)
+
+	fi, err = os.Stat(stalePath)
+	if err != nil {
+		return false, err
+	}
+	if time.Since(fi.ModTime()) <= ttl {
+		// Put the fresh lock back unless someone has already replaced it
+		os.Link(stalePath, lockPath)
+		return false, nil
+	}
+
+	log.Printf("[INFO] (dedup) removed stale lock '%s'", lockPath)
+	return b.acquire(lockPath, ttl)
+}
+
+// refresh verifies we still hold the lock file and updates its modification
+// time so other instances do not consider it stale.
+func (b *fileDedupBackend) refresh(lockPath string) error {
+	if !b.owns(lockPath) {
+		return fmt.Errorf("lock is held by another instance")
+	}
+	now := time.Now()
+	return os.Chtimes(lockPath, now, now)
+}
+
+// owns returns true if the lock file contains our owner ID.
+func (b *fileDedupBackend) owns(lockPath string) bool {
+	contents, err := ioutil.ReadFile(lockPath)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(contents, []byte(b.owner))
+}
@@ -0,0 +1,281 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/template"
+)
+
+var fileDedupIntervalsOnce sync.Once
+
+// testFileDedupIntervals shortens the retry and poll intervals once, since
+// goroutines from earlier tests may still be reading them.
+func testFileDedupIntervals() {
+	fileDedupIntervalsOnce.Do(func() {
+		lockRetry = 100 * time.Millisecond
+		filePollInterval = 50 * time.Millisecond
+	})
+}
+
+func testFileDedupManager(t *testing.T, path string, tmpls []*template.Template) *DedupManager {
+	brain := template.NewBrain()
+	dedupConfig := config.TestConfig(&config.Config{
+		Dedup: &config.DedupConfig{
+			Backend: config.String(config.DedupBackendFile),
+			Path:    config.String(path),
+			TTL:     config.TimeDuration(1 * time.Second),
+		},
+	}).Dedup
+	dedup, err := NewDedupManager(dedupConfig, nil, brain, tmpls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dedup
+}
+
+func TestNewDedupManager_Backend(t *testing.T) {
+
+	cases := []struct {
+		name    string
+		backend string
+		path    string
+		ttl     time.Duration
+		err     bool
+	}{
+		{
+			"consul",
+			config.DedupBackendConsul,
+			"",
+			0,
+			false,
+		},
+		{
+			"file",
+			config.DedupBackendFile,
+			"/tmp",
+			0,
+			false,
+		},
+		{
+			"file_no_path",
+			config.DedupBackendFile,
+			"",
+			0,
+			true,
+		},
+		{
+			"file_zero_ttl",
+			config.DedupBackendFile,
+			"/tmp",
+			time.Nanosecond,
+			true,
+		},
+		{
+			"unknown",
+			"nope",
+			"",
+			0,
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dedupConfig := &config.DedupConfig{
+				Backend: config.String(tc.backend),
+				Path:    config.String(tc.path),
+			}
+			if tc.ttl != 0 {
+				dedupConfig.TTL = config.TimeDuration(tc.ttl)
+			}
+			c := config.TestConfig(&config.Config{
+				Dedup: dedupConfig,
+			}).Dedup
+			_, err := NewDedupManager(c, nil, template.NewBrain(), nil)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestDedup_FileFollowerUpdate(t *testing.T) {
+
+	testFileDedupIntervals()
+
+	dir, err := ioutil.TempDir("", "consul-template-dedup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Create a template
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: `template-file {{ range service "consul" }}{{ .Node }}{{ end }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dedup1 := testFileDedupManager(t, dir, []*template.Template{tmpl})
+	if err := dedup1.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer dedup1.Stop()
+
+	dedup2 := testFileDedupManager(t, dir, []*template.Template{tmpl})
+	if err := dedup2.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer dedup2.Stop()
+
+	// Wait until we have a leader
+	var leader, follow *DedupManager
+	select {
+	case <-dedup1.UpdateCh():
+		if dedup1.IsLeader(tmpl) {
+			leader = dedup1
+			follow = dedup2
+		}
+	case <-dedup2.UpdateCh():
+		if dedup2.IsLeader(tmpl) {
+			leader = dedup2
+			follow = dedup1
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout")
+	}
+	if leader == nil {
+		t.Fatalf("no leader")
+	}
+	if follow.IsLeader(tmpl) {
+		t.Fatalf("both instances are leader")
+	}
+
+	lockPath := filepath.Join(dir, config.DefaultDedupPrefix, tmpl.ID(), fileDedupLock)
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create the dependency
+	dep, err := dependency.NewHealthServiceQuery("consul")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Inject data into the brain
+	leader.brain.Remember(dep, 123)
+
+	// Update the dependencies
+	err = leader.UpdateDeps(tmpl, []dependency.Dependency{dep})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Follower should get an update
+	select {
+	case <-follow.UpdateCh():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout")
+	}
+
+	// Recall from the brain
+	data, ok := follow.brain.Recall(dep)
+	if !ok {
+		t.Fatalf("missing data")
+	}
+	if data != 123 {
+		t.Fatalf("bad: %v", data)
+	}
+
+	// Stopping the leader releases the lock to the follower
+	leader.Stop()
+	select {
+	case <-follow.UpdateCh():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout")
+	}
+	if !follow.IsLeader(tmpl) {
+		t.Fatalf("follower should be leader")
+	}
+}
+
+func TestDedup_FileStatus(t *testing.T) {
+
+	testFileDedupIntervals()
+
+	dir, err := ioutil.TempDir("", "consul-template-dedup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tmpl, err := template.NewTemplate(&template.NewTemplateInput{
+		Contents: `template-status {{ key "foo" }}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statusFile := filepath.Join(dir, "status.json")
+	dedup := testFileDedupManager(t, dir, []*template.Template{tmpl})
+	dedup.config.StatusFile = config.String(statusFile)
+	if err := dedup.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer dedup.Stop()
+
+	select {
+	case <-dedup.UpdateCh():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timeout")
+	}
+
+	dedup.lockFailed(tmpl, fmt.Errorf("boom"))
+
+	status := dedup.Status()
+	if len(status) != 1 {
+		t.Fatalf("expected 1 status, got %d", len(status))
+	}
+	s := status[0]
+	if s.TemplateID != tmpl.ID() || !s.Leader || s.LeaderSince.IsZero() {
+		t.Errorf("bad leadership: %#v", s)
+	}
+	if s.LockFailures != 1 || s.LastLockError != "boom" {
+		t.Errorf("bad lock failures: %#v", s)
+	}
+
+	contents, err := ioutil.ReadFile(statusFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fileStatus []DedupStatus
+	if err := json.Unmarshal(contents, &fileStatus); err != nil {
+		t.Fatal(err)
+	}
+	if len(fileStatus) != 1 || !fileStatus[0].Leader || fileStatus[0].LockFailures != 1 {
+		t.Errorf("bad status file: %s", contents)
+	}
+
+	// The status file is only written when the status changes.
+	if err := os.Remove(statusFile); err != nil {
+		t.Fatal(err)
+	}
+	dedup.updateStatus(tmpl, func(*DedupStatus) {})
+	if _, err := os.Stat(statusFile); !os.IsNotExist(err) {
+		t.Errorf("expected unchanged status not to be written, got %v", err)
+	}
+	dedup.lockFailed(tmpl, fmt.Errorf("boom"))
+	if _, err := os.Stat(statusFile); err != nil {
+		t.Errorf("expected changed status to be written: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package manager
+
+import (
+	"bytes"
+	"compress/lzw"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+)
+
+const (
+	// dedupPayloadMagic identifies shared data written with a format header.
+	// Data without it is from an older version and is LZW compressed gob.
+	dedupPayloadMagic = "ctdd"
+
+	// dedupPayloadVersion is the version of the shared data format written by
+	// this version of Consul Template. Data with a newer format version is
+	// rejected instead of decoded.
+	dedupPayloadVersion byte = 1
+
+	// dedupPayloadHeaderLen is the length of the magic, format version and
+	// encryption type that prefix the shared data.
+	dedupPayloadHeaderLen = len(dedupPayloadMagic) + 2
+)
+
+// Encryption types stored in the shared data header.
+const (
+	dedupEncryptNone byte = iota
+	dedupEncryptKeyFile
+	dedupEncryptVaultTransit
+)
+
+// dedupCipher encrypts and decrypts the shared data.
+type dedupCipher interface {
+	// Type is the encryption type stored in the header.
+	Type() byte
+
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// dedupCodec encodes the template data shared between instances. The data is
+// gob encoded, LZW compressed, optionally encrypted and prefixed with a
+// header carrying the format version and encryption type.
+type dedupCodec struct {
+	cipher dedupCipher
+}
+
+// newDedupCodec creates the codec for the encryption in the given
+// configuration.
+func newDedupCodec(c *config.DedupConfig, clients *dep.ClientSet) (*dedupCodec, error) {
+	keyFile := config.StringVal(c.EncryptionKeyFile)
+	transitKey := config.StringVal(c.VaultTransitKey)
+
+	switch {
+	case keyFile != "" && transitKey != "":
+		return nil, fmt.Errorf("dedup: cannot specify both encryption_key_file and vault_transit_key")
+	case keyFile != "":
+		ci, err := newKeyFileCipher(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &dedupCodec{cipher: ci}, nil
+	case transitKey != "":
+		mount := config.StringVal(c.VaultTransitMount)
+		if mount == "" {
+			mount = config.DefaultDedupVaultTransitMount
+		}
+		return &dedupCodec{cipher: &vaultTransitCipher{
+			clients: clients,
+			mount:   mount,
+			key:     transitKey,
+		}}, nil
+	default:
+		return &dedupCodec{}, nil
+	}
+}
+
+// encode returns the shared data for the given template data.
+func (c *dedupCodec) encode(td *templateData) ([]byte, error) {
+	// Encode via GOB and LZW compress
+	var buf bytes.Buffer
+	compress := lzw.NewWriter(&buf, lzw.LSB, 8)
+	enc := gob.NewEncoder(compress)
+	if err := enc.Encode(td); err != nil {
+		return nil, fmt.Errorf("encode failed: %v", err)
+	}
+	compress.Close()
+
+	body := buf.Bytes()
+	encryption := dedupEncryptNone
+	if c.cipher != nil {
+		var err error
+		if body, err = c.cipher.Encrypt(body); err != nil {
+			return nil, fmt.Errorf("encrypt failed: %v", err)
+		}
+		encryption = c.cipher.Type()
+	}
+
+	out := make([]byte, 0, dedupPayloadHeaderLen+len(body))
+	out = append(out, dedupPayloadMagic...)
+	out = append(out, dedupPayloadVersion, encryption)
+	out = append(out, body...)
+	return out, nil
+}
+
+// decode returns the template data in the given shared data. Data that was
+// written in an incompatible format, or with different encryption settings,
+// returns an error.
+func (c *dedupCodec) decode(raw []byte) (td *templateData, err error) {
+	// A corrupt stream must not take down the process.
+	defer func() {
+		if r := recover(); r != nil {
+			td, err = nil, fmt.Errorf("decode failed: %v", r)
+		}
+	}()
+
+	body := raw
+	if bytes.HasPrefix(raw, []byte(dedupPayloadMagic)) && len(raw) >= dedupPayloadHeaderLen {
+		version := raw[len(dedupPayloadMagic)]
+		encryption := raw[len(dedupPayloadMagic)+1]
+		body = raw[dedupPayloadHeaderLen:]
+
+		if version > dedupPayloadVersion {
+			return nil, fmt.Errorf("unsupported data format version %d (max %d)",
+				version, dedupPayloadVersion)
+		}
+
+		expected := dedupEncryptNone
+		if c.cipher != nil {
+			expected = c.cipher.Type()
+		}
+		if encryption != expected {
+			return nil, fmt.Errorf("data encryption type %d does not match configured type %d",
+				encryption, expected)
+		}
+
+		if c.cipher != nil {
+			if body, err = c.cipher.Decrypt(body); err != nil {
+				return nil, fmt.Errorf("decrypt failed: %v", err)
+			}
+		}
+	} else if c.cipher != nil {
+		// Data from before the format header is never encrypted.
+		return nil, fmt.Errorf("data is not encrypted")
+	}
+
+	// Setup the decompression and decoders
+	decompress := lzw.NewReader(bytes.NewReader(body), lzw.LSB, 8)
+	defer decompress.Close()
+	dec := gob.NewDecoder(decompress)
+
+	td = &templateData{}
+	if err := dec.Decode(td); err != nil {
+		return nil, fmt.Errorf("decode failed: %v", err)
+	}
+	return td, nil
+}
+
+// keyFileCipher encrypts with AES-256-GCM using a key read from a file.
+type keyFileCipher struct {
+	aead cipher.AEAD
+}
+
+func newKeyFileCipher(path string) (*keyFileCipher, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("dedup: failed to read encryption key: %v", err)
+	}
+
+	key := contents
+	if len(key) != 32 {
+		key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(contents)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("dedup: encryption key in %q must be 32 bytes, raw or base64 encoded", path)
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("dedup: invalid encryption key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("dedup: invalid encryption key: %v", err)
+	}
+	return &keyFileCipher{aead: aead}, nil
+}
+
+func (c *keyFileCipher) Type() byte {
+	return dedupEncryptKeyFile
+}
+
+func (c *keyFileCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (c *keyFileCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return c.aead.Open(nil, ciphertext[:n], ciphertext[n:], nil)
+}
+
+// vaultTransitCipher encrypts using the Vault Transit secrets engine.
+type vaultTransitCipher struct {
+	clients *dep.ClientSet
+	mount   string
+	key     string
+}
+
+func (c *vaultTransitCipher) Type() byte {
+	return dedupEncryptVaultTransit
+}
+
+func (c *vaultTransitCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	p := path.Join(c.mount, "encrypt", c.key)
+	secret, err := c.clients.Vault().Logical().Write(p, map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no response from %q", p)
+	}
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing ciphertext in response from %q", p)
+	}
+	return []byte(ciphertext), nil
+}
+
+func (c *vaultTransitCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	p := path.Join(c.mount, "decrypt", c.key)
+	secret, err := c.clients.Vault().Logical().Write(p, map[string]interface{}{
+		"ciphertext": string(ciphertext),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no response from %q", p)
+	}
+	plaintext, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing plaintext in response from %q", p)
+	}
+	return base64.StdEncoding.DecodeString(plaintext)
+}
@@ -0,0 +1,206 @@
+package manager
+
+import (
+	"bytes"
+	"compress/lzw"
+	"encoding/base64"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func testKeyFile(t *testing.T, dir, name string, key []byte) string {
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDedupCodec(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "consul-template-dedup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rawKey := testKeyFile(t, dir, "raw", bytes.Repeat([]byte("a"), 32))
+	b64Key := testKeyFile(t, dir, "b64",
+		[]byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("b"), 32))+"\n"))
+
+	td := &templateData{
+		Version: "1.2.3",
+		Data: map[string]interface{}{
+			"key(foo)": "bar",
+			// Data of plugin functions is decoded from JSON.
+			`etcd.etcdKey("app")`: map[string]interface{}{
+				"hosts": []interface{}{"db"},
+			},
+		},
+	}
+
+	// legacy is the format written before the format header was added.
+	var legacy bytes.Buffer
+	compress := lzw.NewWriter(&legacy, lzw.LSB, 8)
+	if err := gob.NewEncoder(compress).Encode(td); err != nil {
+		t.Fatal(err)
+	}
+	compress.Close()
+
+	plain := &config.DedupConfig{}
+	raw := &config.DedupConfig{EncryptionKeyFile: config.String(rawKey)}
+	b64 := &config.DedupConfig{EncryptionKeyFile: config.String(b64Key)}
+
+	encode := func(c *config.DedupConfig) []byte {
+		codec, err := newDedupCodec(c, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := codec.encode(td)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	future := encode(plain)
+	future[len(dedupPayloadMagic)] = dedupPayloadVersion + 1
+
+	cases := []struct {
+		name   string
+		config *config.DedupConfig
+		data   []byte
+		err    bool
+	}{
+		{
+			"plain",
+			plain,
+			encode(plain),
+			false,
+		},
+		{
+			"legacy",
+			plain,
+			legacy.Bytes(),
+			false,
+		},
+		{
+			"key_file_raw",
+			raw,
+			encode(raw),
+			false,
+		},
+		{
+			"key_file_base64",
+			b64,
+			encode(b64),
+			false,
+		},
+		{
+			"wrong_key",
+			b64,
+			encode(raw),
+			true,
+		},
+		{
+			"encrypted_without_key",
+			plain,
+			encode(raw),
+			true,
+		},
+		{
+			"plain_with_key",
+			raw,
+			encode(plain),
+			true,
+		},
+		{
+			"legacy_with_key",
+			raw,
+			legacy.Bytes(),
+			true,
+		},
+		{
+			"future_version",
+			plain,
+			future,
+			true,
+		},
+		{
+			"garbage",
+			plain,
+			[]byte("ctdd\x01\x00not gob"),
+			true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			codec, err := newDedupCodec(tc.config, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			act, err := codec.decode(tc.data)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(td, act) {
+				t.Errorf("\nexp: %#v\nact: %#v", td, act)
+			}
+		})
+	}
+}
+
+func TestNewDedupCodec_Error(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "consul-template-dedup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	short := testKeyFile(t, dir, "short", []byte("too short"))
+
+	cases := []struct {
+		name   string
+		config *config.DedupConfig
+	}{
+		{
+			"missing_key_file",
+			&config.DedupConfig{
+				EncryptionKeyFile: config.String(filepath.Join(dir, "nope")),
+			},
+		},
+		{
+			"short_key",
+			&config.DedupConfig{
+				EncryptionKeyFile: config.String(short),
+			},
+		},
+		{
+			"both",
+			&config.DedupConfig{
+				EncryptionKeyFile: config.String(short),
+				VaultTransitKey:   config.String("ct-dedup"),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := newDedupCodec(tc.config, nil); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/renderer"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// DedupStatus is the de-duplication state of a single template on this
+// instance.
+type DedupStatus struct {
+	// TemplateID is the ID of the template.
+	TemplateID string `json:"template_id"`
+
+	// Source is the source of the template.
+	Source string `json:"source"`
+
+	// Leader is true if this instance is the leader for the template.
+	Leader bool `json:"leader"`
+
+	// LeaderSince is when this instance last acquired or lost leadership. It
+	// is zero if leadership has never changed.
+	LeaderSince time.Time `json:"leader_since"`
+
+	// LastUpdate is when the shared data was last written, as leader, or
+	// loaded, as follower. It is zero if there has been no update.
+	LastUpdate time.Time `json:"last_update"`
+
+	// LastPoll is when a follower last heard back from the backend while
+	// waiting for new data. A follower whose LastPoll is recent but whose
+	// LastUpdate is old is receiving no new data from the leader.
+	LastPoll time.Time `json:"last_poll"`
+
+	// LockFailures is the number of errors while trying to acquire leadership.
+	LockFailures uint64 `json:"lock_failures"`
+
+	// LastLockError is the most recent error while trying to acquire
+	// leadership.
+	LastLockError string `json:"last_lock_error,omitempty"`
+
+	// LastLockErrorTime is when the most recent lock error occurred.
+	LastLockErrorTime time.Time `json:"last_lock_error_time"`
+}
+
+// Status returns the de-duplication state of each template, sorted by
+// template ID.
+func (d *DedupManager) Status() []DedupStatus {
+	d.statusLock.Lock()
+	defer d.statusLock.Unlock()
+
+	statuses := make([]DedupStatus, 0, len(d.status))
+	for _, s := range d.status {
+		statuses = append(statuses, *s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].TemplateID < statuses[j].TemplateID
+	})
+	return statuses
+}
+
+// updateStatus applies fn to the status of the template and writes the status
+// file if the status changed.
+func (d *DedupManager) updateStatus(tmpl *template.Template, fn func(*DedupStatus)) {
+	d.statusLock.Lock()
+	s, ok := d.status[tmpl]
+	if !ok {
+		s = &DedupStatus{
+			TemplateID: tmpl.ID(),
+			Source:     tmpl.Source(),
+		}
+		d.status[tmpl] = s
+	}
+	previous := *s
+	fn(s)
+	changed := !ok || *s != previous
+	d.statusLock.Unlock()
+
+	if changed {
+		d.writeStatusFile()
+	}
+}
+
+// writeStatusFile writes the status of all templates to the status file, if
+// one is configured. The file is only replaced if its contents changed, so its
+// modification time shows when the status last changed.
+func (d *DedupManager) writeStatusFile() {
+	path := config.StringVal(d.config.StatusFile)
+	if path == "" {
+		return
+	}
+
+	d.statusFileLock.Lock()
+	defer d.statusFileLock.Unlock()
+
+	contents, err := json.MarshalIndent(d.Status(), "", "  ")
+	if err != nil {
+		log.Printf("[ERR] (dedup) failed to encode status: %v", err)
+		return
+	}
+	if bytes.Equal(contents, d.statusFileContents) {
+		return
+	}
+	if err := renderer.AtomicWrite(path, true, contents, 0644, false); err != nil {
+		log.Printf("[ERR] (dedup) failed to write status file %q: %v", path, err)
+		return
+	}
+	d.statusFileContents = contents
+}
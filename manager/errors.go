@@ -29,3 +29,38 @@ func (e *ErrChildDied) Error() string {
 func (e *ErrChildDied) ExitStatus() int {
 	return e.code
 }
+
+var _ error = new(ErrOnceTimeout)
+
+// ErrOnceTimeout is the error returned when templates are not all rendered
+// within the once timeout. It is encoded as JSON to report which templates
+// were waiting on which dependencies.
+type ErrOnceTimeout struct {
+	// Timeout is the once timeout that expired.
+	Timeout string `json:"timeout"`
+
+	// Templates are the templates that did not render.
+	Templates []*OnceTimeoutTemplate `json:"templates"`
+}
+
+// OnceTimeoutTemplate is a template that did not render before the once
+// timeout expired.
+type OnceTimeoutTemplate struct {
+	// ID is the ID of the template.
+	ID string `json:"id"`
+
+	// Source is the source of the template.
+	Source string `json:"source"`
+
+	// Destinations are the destinations of the template.
+	Destinations []string `json:"destinations"`
+
+	// MissingDeps are the dependencies that had no data.
+	MissingDeps []string `json:"missing_dependencies"`
+}
+
+// Error implements the error interface.
+func (e *ErrOnceTimeout) Error() string {
+	return fmt.Sprintf("once mode timed out after %s with %d template(s) not rendered",
+		e.Timeout, len(e.Templates))
+}
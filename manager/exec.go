@@ -0,0 +1,572 @@
+package manager
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// execChild is a child process supervised by the runner in exec mode.
+type execChild struct {
+	// name is the name of the exec configuration. It is empty for the single
+	// unnamed exec child.
+	name string
+
+	// config is the exec configuration for the child process.
+	config *config.ExecConfig
+
+	// child is the running child process. It is nil until the process is
+	// spawned.
+	child *child.Child
+
+	// done is true once the child process has exited or been stopped and will
+	// not be spawned again.
+	done bool
+
+	// restarting is true while the child process has exited and is waiting to
+	// be restarted.
+	restarting bool
+
+	// restarts are the times the child process was restarted within the
+	// restart window.
+	restarts []time.Time
+
+	// probe is the readiness probe that is waiting for the child process to
+	// become ready, or nil.
+	probe *readinessProbe
+}
+
+// String returns a human-friendly name for the child process.
+func (c *execChild) String() string {
+	if c.name == "" {
+		return "child process"
+	}
+	return fmt.Sprintf("child process %q", c.name)
+}
+
+// watches returns true if rendering the template config reloads the child
+// process. A child with no templates watches every template.
+func (c *execChild) watches(tc *config.TemplateConfig) bool {
+	if len(c.config.Templates) == 0 {
+		return true
+	}
+	dest, src := config.StringVal(tc.Destination), config.StringVal(tc.Source)
+	for _, t := range c.config.Templates {
+		if t == dest || (src != "" && t == src) {
+			return true
+		}
+	}
+	return false
+}
+
+// restart returns true if the child process should be restarted after exiting
+// with the given code, along with the time to wait before restarting it. Each
+// restart is recorded so that restarts within the window back off and are
+// limited by the restart attempts.
+func (c *execChild) restart(code int, now time.Time) (bool, time.Duration) {
+	rc := c.config.Restart
+	if rc == nil {
+		return false, 0
+	}
+
+	switch config.StringVal(rc.Policy) {
+	case config.RestartPolicyAlways:
+	case config.RestartPolicyOnFailure:
+		if code == 0 {
+			return false, 0
+		}
+	default:
+		return false, 0
+	}
+
+	// Forget restarts that are outside of the window.
+	if window := config.TimeDurationVal(rc.Window); window > 0 {
+		recent := c.restarts[:0]
+		for _, t := range c.restarts {
+			if now.Sub(t) < window {
+				recent = append(recent, t)
+			}
+		}
+		c.restarts = recent
+	}
+
+	ok, sleep := rc.RetryFunc()(len(c.restarts))
+	if !ok {
+		return false, 0
+	}
+	c.restarts = append(c.restarts, now)
+	return true, sleep
+}
+
+// overlaps returns true if reloading the child process for the rendered
+// templates restarts it, and the new child process should be started before
+// the old one is stopped.
+func (c *execChild) overlaps(rendered []*renderedTemplate) bool {
+	if config.StringVal(c.config.RestartStrategy) != config.ExecRestartStrategyOverlap ||
+		config.SignalVal(c.config.ReloadSignal) != nil {
+		return false
+	}
+	for _, rt := range rendered {
+		if config.SignalVal(rt.config.ExecReloadSignal) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// reload reloads the child process for the rendered templates it watches. Each
+// distinct exec_reload_signal of the templates is sent to the child process,
+// and the child process is reloaded with its own reload signal if any of the
+// templates do not name a signal. When the child process has no reload signal
+// and is restarted instead, the template signals are not sent to the new
+// process.
+func (c *execChild) reload(rendered []*renderedTemplate) error {
+	var signals []os.Signal
+	reloadDefault := false
+	for _, rt := range rendered {
+		s := config.SignalVal(rt.config.ExecReloadSignal)
+		if s == nil {
+			reloadDefault = true
+			continue
+		}
+		seen := false
+		for _, o := range signals {
+			if o == s {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			signals = append(signals, s)
+		}
+	}
+
+	defaultSignal := config.SignalVal(c.config.ReloadSignal)
+	if reloadDefault {
+		if err := c.child.Reload(); err != nil {
+			return err
+		}
+		if defaultSignal == nil {
+			return nil
+		}
+	}
+
+	for _, s := range signals {
+		if reloadDefault && s == defaultSignal {
+			continue
+		}
+		log.Printf("[DEBUG] (runner) sending %s to %s", s, c)
+		if err := c.child.ReloadWithSignal(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// childExit is the exit of a supervised child process.
+type childExit struct {
+	exec  *execChild
+	child *child.Child
+	code  int
+}
+
+// newExecChildren returns the child processes to supervise for the given
+// configuration: the unnamed exec child, if it has a command, followed by each
+// named exec child.
+func newExecChildren(c *config.Config) ([]*execChild, error) {
+	var children []*execChild
+	if !c.Exec.Command.Empty() {
+		children = append(children, &execChild{
+			name:   config.StringVal(c.Exec.Name),
+			config: c.Exec,
+		})
+	}
+
+	names := make(map[string]struct{})
+	for _, e := range *c.Execs {
+		name := config.StringVal(e.Name)
+		if name == "" {
+			return nil, fmt.Errorf("runner: exec blocks must each have a name when more than one is given")
+		}
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("runner: duplicate exec name %q", name)
+		}
+		names[name] = struct{}{}
+
+		if e.Command.Empty() {
+			return nil, fmt.Errorf("runner: exec %q is missing a command", name)
+		}
+		children = append(children, &execChild{
+			name:   name,
+			config: e,
+		})
+	}
+
+	for _, ec := range children {
+		switch s := config.StringVal(ec.config.RestartStrategy); s {
+		case "", config.ExecRestartStrategyStopStart,
+			config.ExecRestartStrategyOverlap:
+		default:
+			return nil, fmt.Errorf("runner: unknown restart strategy %q for %s", s, ec)
+		}
+
+		if ec.config.Restart == nil {
+			continue
+		}
+		switch p := config.StringVal(ec.config.Restart.Policy); p {
+		case config.RestartPolicyNever, config.RestartPolicyOnFailure,
+			config.RestartPolicyAlways:
+		default:
+			return nil, fmt.Errorf("runner: unknown restart policy %q for %s", p, ec)
+		}
+	}
+	return children, nil
+}
+
+// spawnChildren spawns each supervised child process that is not yet running
+// and starts waiting for them to become ready.
+func (r *Runner) spawnChildren() error {
+	r.childLock.Lock()
+
+	log.Printf("[TRACE] (runner) acquired child lock for command, spawning")
+
+	var spawned []*execChild
+	for _, ec := range r.children {
+		if ec.child != nil || ec.done {
+			continue
+		}
+		if err := r.spawnExecChild(ec); err != nil {
+			r.childLock.Unlock()
+			return err
+		}
+		spawned = append(spawned, ec)
+	}
+	r.childLock.Unlock()
+
+	for _, ec := range spawned {
+		r.checkReady(ec, nil, nil)
+	}
+	return nil
+}
+
+// spawnExecChild spawns the child process and starts watching for its exit.
+// The caller must hold the child lock.
+func (r *Runner) spawnExecChild(ec *execChild) error {
+	env := ec.config.Env.Copy()
+	env.Custom = append(r.childEnv(), env.Custom...)
+	child, err := spawnChild(&spawnChildInput{
+		Stdin:        r.inStream,
+		Stdout:       r.outStream,
+		Stderr:       r.errStream,
+		Command:      ec.config.Command,
+		Env:          env.Env(),
+		ReloadSignal: config.SignalVal(ec.config.ReloadSignal),
+		KillSignal:   config.SignalVal(ec.config.KillSignal),
+		KillTimeout:  config.TimeDurationVal(ec.config.KillTimeout),
+		Splay:        config.TimeDurationVal(ec.config.Splay),
+	})
+	if err != nil {
+		if ec.name != "" {
+			return fmt.Errorf("exec %q: %s", ec.name, err)
+		}
+		return err
+	}
+	ec.child = child
+	ec.restarting = false
+
+	go r.watchChild(ec, child)
+	return nil
+}
+
+// watchChild waits for the child process to exit and reports the exit on the
+// runner's child exit channel. Exits caused by the child being restarted on
+// reload are ignored, since the child's exit channel changes on restart, as
+// are exits of a child process that was replaced by an overlapping restart.
+func (r *Runner) watchChild(ec *execChild, c *child.Child) {
+	for {
+		exitCh := c.ExitCh()
+		select {
+		case code := <-exitCh:
+			if c.ExitCh() != exitCh {
+				continue
+			}
+
+			r.childLock.RLock()
+			stale := ec.done || ec.child != c
+			r.childLock.RUnlock()
+			if stale {
+				return
+			}
+
+			select {
+			case r.childExitCh <- &childExit{exec: ec, child: c, code: code}:
+			case <-r.DoneCh:
+			}
+			return
+		case <-r.DoneCh:
+			return
+		}
+	}
+}
+
+// childExited handles the exit of a supervised child process. If the restart
+// policy allows it, the child process is restarted after the backoff.
+// Otherwise it returns an error if the child process is critical.
+func (r *Runner) childExited(e *childExit) error {
+	r.childLock.Lock()
+	if e.exec.child != e.child || e.exec.done {
+		r.childLock.Unlock()
+		return nil
+	}
+	ok, sleep := e.exec.restart(e.code, time.Now())
+	if ok {
+		e.exec.restarting = true
+	} else {
+		e.exec.done = true
+	}
+	restarts := len(e.exec.restarts)
+	r.childLock.Unlock()
+
+	if ok {
+		log.Printf("[WARN] (runner) %s exited with code %d, restarting in %s",
+			e.exec, e.code, sleep)
+		go func() {
+			select {
+			case <-time.After(sleep):
+			case <-r.DoneCh:
+				return
+			}
+			select {
+			case r.childRestartCh <- e.exec:
+			case <-r.DoneCh:
+			}
+		}()
+		return nil
+	}
+
+	if restarts > 0 {
+		log.Printf("[ERR] (runner) %s exited with code %d, giving up after %d "+
+			"restarts", e.exec, e.code, restarts)
+	}
+
+	if config.BoolVal(e.exec.config.Critical) {
+		log.Printf("[INFO] (runner) %s died", e.exec)
+		return NewErrChildDied(e.code)
+	}
+
+	log.Printf("[WARN] (runner) non-critical %s exited with code %d",
+		e.exec, e.code)
+	return nil
+}
+
+// restartChild spawns a new child process for a child that exited and is
+// waiting to be restarted.
+func (r *Runner) restartChild(ec *execChild) error {
+	r.childLock.Lock()
+	if ec.done || !ec.restarting {
+		r.childLock.Unlock()
+		return nil
+	}
+
+	log.Printf("[INFO] (runner) restarting %s", ec)
+	err := r.spawnExecChild(ec)
+	r.childLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	r.checkReady(ec, nil, nil)
+	return nil
+}
+
+// waitChildren blocks until a critical child process exits, all child
+// processes have exited, or the runner is stopped.
+func (r *Runner) waitChildren() error {
+	log.Printf("[INFO] (runner) waiting for child process to exit")
+	for {
+		if !r.childrenRunning() {
+			return nil
+		}
+
+		select {
+		case e := <-r.childExitCh:
+			if err := r.childExited(e); err != nil {
+				return err
+			}
+		case ec := <-r.childRestartCh:
+			if err := r.restartChild(ec); err != nil {
+				return err
+			}
+		case res := <-r.readinessCh:
+			r.readinessDone(res)
+		case <-r.DoneCh:
+			return nil
+		}
+	}
+}
+
+// hasChildren returns true if any child process has been spawned.
+func (r *Runner) hasChildren() bool {
+	r.childLock.RLock()
+	defer r.childLock.RUnlock()
+
+	for _, ec := range r.children {
+		if ec.child != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// childrenRunning returns true if any spawned child process has not exited.
+func (r *Runner) childrenRunning() bool {
+	r.childLock.RLock()
+	defer r.childLock.RUnlock()
+
+	for _, ec := range r.children {
+		if ec.child != nil && !ec.done {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadChildren reloads each running child process that watches any of the
+// rendered templates and starts waiting for them to become ready.
+func (r *Runner) reloadChildren(rendered []*renderedTemplate) []error {
+	type reload struct {
+		exec     *execChild
+		rendered []*renderedTemplate
+	}
+
+	r.childLock.RLock()
+	var errs []error
+	var reloaded, overlapped []*reload
+	for _, ec := range r.children {
+		if ec.child == nil || ec.done || ec.restarting {
+			continue
+		}
+
+		var watched []*renderedTemplate
+		for _, rt := range rendered {
+			if ec.watches(rt.config) {
+				watched = append(watched, rt)
+			}
+		}
+		if len(watched) == 0 {
+			continue
+		}
+
+		if ec.overlaps(watched) {
+			overlapped = append(overlapped, &reload{exec: ec, rendered: watched})
+			continue
+		}
+
+		if err := ec.reload(watched); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reloaded = append(reloaded, &reload{exec: ec, rendered: watched})
+	}
+	r.childLock.RUnlock()
+
+	for _, rl := range reloaded {
+		r.checkReady(rl.exec, rl.rendered, nil)
+	}
+	for _, rl := range overlapped {
+		if err := r.overlapChild(rl.exec, rl.rendered); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// overlapChild restarts the child process for the rendered templates by
+// spawning a new child process and, if it has a readiness probe, waiting for it
+// to become ready before stopping the old one. If the new child process does
+// not become ready, it is stopped and the old one keeps running.
+func (r *Runner) overlapChild(ec *execChild, rendered []*renderedTemplate) error {
+	r.childLock.Lock()
+	if ec.done || ec.restarting {
+		r.childLock.Unlock()
+		return nil
+	}
+
+	log.Printf("[INFO] (runner) starting new %s before stopping the old one", ec)
+	old := ec.child
+	if err := r.spawnExecChild(ec); err != nil {
+		r.childLock.Unlock()
+		return err
+	}
+	r.childLock.Unlock()
+
+	if !r.checkReady(ec, rendered, old) {
+		log.Printf("[DEBUG] (runner) stopping the old %s", ec)
+		old.Stop()
+	}
+	return nil
+}
+
+// signalChildren sends the signal to each running child process.
+func (r *Runner) signalChildren(s os.Signal) error {
+	r.childLock.RLock()
+	defer r.childLock.RUnlock()
+
+	var result *multierror.Error
+	for _, ec := range r.children {
+		if ec.child == nil || ec.done || ec.restarting {
+			continue
+		}
+		if err := ec.child.Signal(s); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+func (r *Runner) stopChild(immediately bool) {
+	// Mark the children done before stopping them so their exits are not
+	// reported, but do not hold the lock while waiting on them to stop.
+	r.childLock.Lock()
+	var running []*execChild
+	var old []*child.Child
+	for _, ec := range r.children {
+		// A pending overlap restart leaves the old child process running too.
+		if ec.probe != nil {
+			close(ec.probe.cancelCh)
+			if ec.probe.old != nil {
+				old = append(old, ec.probe.old)
+			}
+			ec.probe = nil
+		}
+
+		if ec.child == nil || ec.done {
+			continue
+		}
+		ec.done = true
+		running = append(running, ec)
+	}
+	r.childLock.Unlock()
+
+	for _, ec := range running {
+		if immediately {
+			log.Printf("[DEBUG] (runner) stopping %s immediately", ec)
+			ec.child.StopImmediately()
+		} else {
+			log.Printf("[DEBUG] (runner) stopping %s", ec)
+			ec.child.Stop()
+		}
+	}
+	for _, c := range old {
+		if immediately {
+			c.StopImmediately()
+		} else {
+			c.Stop()
+		}
+	}
+}
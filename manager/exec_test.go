@@ -0,0 +1,200 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestExecChild_Restart(t *testing.T) {
+
+	now := time.Now()
+
+	cases := []struct {
+		name     string
+		restart  *config.RestartConfig
+		restarts []time.Time
+		code     int
+		ok       bool
+		sleep    time.Duration
+	}{
+		{
+			"never",
+			&config.RestartConfig{},
+			nil,
+			1,
+			false,
+			0,
+		},
+		{
+			"on_failure_success",
+			&config.RestartConfig{Policy: config.String(config.RestartPolicyOnFailure)},
+			nil,
+			0,
+			false,
+			0,
+		},
+		{
+			"on_failure_failure",
+			&config.RestartConfig{Policy: config.String(config.RestartPolicyOnFailure)},
+			nil,
+			1,
+			true,
+			config.DefaultRestartBackoff,
+		},
+		{
+			"always_success",
+			&config.RestartConfig{Policy: config.String(config.RestartPolicyAlways)},
+			nil,
+			0,
+			true,
+			config.DefaultRestartBackoff,
+		},
+		{
+			"backoff",
+			&config.RestartConfig{Policy: config.String(config.RestartPolicyAlways)},
+			[]time.Time{now.Add(-2 * time.Second), now.Add(-1 * time.Second)},
+			0,
+			true,
+			4 * config.DefaultRestartBackoff,
+		},
+		{
+			"attempts_exhausted",
+			&config.RestartConfig{
+				Policy:   config.String(config.RestartPolicyAlways),
+				Attempts: config.Int(2),
+			},
+			[]time.Time{now.Add(-2 * time.Second), now.Add(-1 * time.Second)},
+			0,
+			false,
+			0,
+		},
+		{
+			"window_forgets",
+			&config.RestartConfig{
+				Policy:   config.String(config.RestartPolicyAlways),
+				Attempts: config.Int(2),
+				Window:   config.TimeDuration(1 * time.Minute),
+			},
+			[]time.Time{now.Add(-2 * time.Hour), now.Add(-1 * time.Hour)},
+			0,
+			true,
+			config.DefaultRestartBackoff,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			tc.restart.Finalize()
+			ec := &execChild{
+				config:   &config.ExecConfig{Restart: tc.restart},
+				restarts: tc.restarts,
+			}
+
+			ok, sleep := ec.restart(tc.code, now)
+			if ok != tc.ok {
+				t.Errorf("\nexp ok: %t\nact ok: %t", tc.ok, ok)
+			}
+			if sleep != tc.sleep {
+				t.Errorf("\nexp sleep: %s\nact sleep: %s", tc.sleep, sleep)
+			}
+		})
+	}
+}
+
+func TestExecChild_reload(t *testing.T) {
+
+	usr1 := config.Signal(syscall.SIGUSR1)
+	usr2 := config.Signal(syscall.SIGUSR2)
+
+	cases := []struct {
+		name     string
+		signals  []*os.Signal
+		expected string
+	}{
+		{
+			"default",
+			[]*os.Signal{nil},
+			"hup\n",
+		},
+		{
+			"template_signal",
+			[]*os.Signal{usr1},
+			"usr1\n",
+		},
+		{
+			"distinct_signals",
+			[]*os.Signal{usr1, usr2, usr1},
+			"usr1\nusr2\n",
+		},
+		{
+			"default_and_template_signal",
+			[]*os.Signal{nil, usr2},
+			"hup\nusr2\n",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			dir := t.TempDir()
+			out := filepath.Join(dir, "out")
+			ready := filepath.Join(dir, "ready")
+			c, err := spawnChild(&spawnChildInput{
+				Command: []string{"sh", "-c", fmt.Sprintf("trap 'echo hup >> %[1]s' HUP; "+
+					"trap 'echo usr1 >> %[1]s' USR1; trap 'echo usr2 >> %[1]s' USR2; "+
+					"touch %[2]s; while true; do sleep 0.1; done", out, ready)},
+				ReloadSignal: syscall.SIGHUP,
+				KillSignal:   syscall.SIGKILL,
+				KillTimeout:  time.Second,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.StopImmediately()
+
+			ec := &execChild{
+				config: &config.ExecConfig{ReloadSignal: config.Signal(syscall.SIGHUP)},
+				child:  c,
+			}
+
+			// The shell creates the ready file once its traps are set up.
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				if _, err := os.Stat(ready); err == nil {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("shell did not set up its traps")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			var rendered []*renderedTemplate
+			for _, s := range tc.signals {
+				rendered = append(rendered, &renderedTemplate{
+					config: &config.TemplateConfig{ExecReloadSignal: s},
+				})
+			}
+			if err := ec.reload(rendered); err != nil {
+				t.Fatal(err)
+			}
+
+			var act string
+			deadline = time.Now().Add(2 * time.Second)
+			for act != tc.expected && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+				b, _ := ioutil.ReadFile(out)
+				act = string(b)
+			}
+			if act != tc.expected {
+				t.Errorf("\nexp: %q\nact: %q", tc.expected, act)
+			}
+		})
+	}
+}
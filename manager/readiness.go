@@ -0,0 +1,324 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/template"
+)
+
+// readinessProbe is a readiness probe of a child process that runs in the
+// background, so that templates keep rendering while it waits.
+type readinessProbe struct {
+	// cancelCh is closed when the probe is superseded by a newer probe of the
+	// same child process.
+	cancelCh chan struct{}
+
+	// rendered are the templates the child process was reloaded for, or nil if
+	// it was started.
+	rendered []*renderedTemplate
+
+	// old is the child process that keeps running until the probe passes when
+	// the child process was restarted with the overlap strategy, or nil.
+	old *child.Child
+}
+
+// readinessResult is the result of a readiness probe, reported on the runner's
+// readiness channel.
+type readinessResult struct {
+	exec  *execChild
+	probe *readinessProbe
+	err   error
+}
+
+// checkReady starts waiting in the background for the child process to become
+// ready after it was started, or reloaded for the given rendered templates. The
+// result is reported on the readiness channel and handled by readinessDone. It
+// returns false if the child process has no readiness probe.
+//
+// A probe that is still waiting is superseded: its rendered templates are
+// carried over so that a failure restores the contents from before either
+// reload, and its old child process keeps running in place of the one it was
+// waiting on.
+func (r *Runner) checkReady(ec *execChild, rendered []*renderedTemplate, old *child.Child) bool {
+	rc := ec.config.Readiness
+	if rc == nil || !config.BoolVal(rc.Enabled) {
+		return false
+	}
+
+	probe := &readinessProbe{
+		cancelCh: make(chan struct{}),
+		rendered: rendered,
+		old:      old,
+	}
+
+	var superseded *child.Child
+	r.childLock.Lock()
+	if prev := ec.probe; prev != nil {
+		close(prev.cancelCh)
+		probe.rendered = mergeRendered(prev.rendered, rendered)
+		if prev.old != nil {
+			superseded, probe.old = old, prev.old
+		}
+	}
+	ec.probe = probe
+	r.childLock.Unlock()
+
+	if superseded != nil {
+		log.Printf("[DEBUG] (runner) stopping %s that did not become ready "+
+			"before it was replaced", ec)
+		superseded.Stop()
+	}
+
+	go func() {
+		err := r.waitReady(ec, probe.cancelCh)
+		select {
+		case r.readinessCh <- &readinessResult{exec: ec, probe: probe, err: err}:
+		case <-probe.cancelCh:
+		case <-r.DoneCh:
+		}
+	}()
+	return true
+}
+
+// readinessDone handles the result of a readiness probe. If the child process
+// did not become ready, the old child process is put back in place of the new
+// one when it was restarted with the overlap strategy, and, if the probe is
+// configured to, the previous contents of the rendered templates are restored
+// and kept from being rendered again. Failures are recorded in the render
+// events of the templates involved.
+func (r *Runner) readinessDone(res *readinessResult) {
+	ec, probe := res.exec, res.probe
+
+	r.childLock.Lock()
+	if ec.probe != probe {
+		r.childLock.Unlock()
+		return
+	}
+	ec.probe = nil
+
+	var next *child.Child
+	if res.err != nil && probe.old != nil {
+		next = ec.child
+		ec.child = probe.old
+		ec.done = false
+		ec.restarting = false
+	}
+	r.childLock.Unlock()
+
+	if res.err == nil {
+		if probe.old != nil {
+			log.Printf("[DEBUG] (runner) new %s is ready, stopping the old one", ec)
+			probe.old.Stop()
+		} else {
+			log.Printf("[DEBUG] (runner) %s is ready", ec)
+		}
+		return
+	}
+
+	if next != nil {
+		log.Printf("[ERR] (runner) new %s did not become ready, keeping the "+
+			"old one: %v", ec, res.err)
+		next.Stop()
+	} else {
+		log.Printf("[ERR] (runner) %s did not become ready: %v", ec, res.err)
+	}
+
+	var restored bool
+	if len(probe.rendered) > 0 && config.BoolVal(ec.config.Readiness.RestoreOnFailure) && !r.dry {
+		if next != nil {
+			restored = r.restoreFiles(probe.rendered)
+		} else {
+			restored = r.restoreTemplates(ec, probe.rendered)
+		}
+	}
+	if restored {
+		for _, rt := range probe.rendered {
+			r.rejected[rt.config] = checksum(rt.contents)
+		}
+	}
+	r.recordReadiness(ec, probe.rendered, res.err, restored)
+}
+
+// waitReady runs the readiness checks of the child process on the interval
+// until they pass, returning an error if they do not pass within the timeout.
+// It stops waiting when the cancel channel is closed.
+func (r *Runner) waitReady(ec *execChild, cancelCh <-chan struct{}) error {
+	rc := ec.config.Readiness
+	interval := config.TimeDurationVal(rc.Interval)
+	timeout := config.TimeDurationVal(rc.Timeout)
+	timeoutCh := time.After(timeout)
+
+	for {
+		err := r.checkReadiness(ec, interval)
+		if err == nil {
+			return nil
+		}
+		log.Printf("[TRACE] (runner) %s is not ready: %v", ec, err)
+
+		select {
+		case <-time.After(interval):
+		case <-timeoutCh:
+			return fmt.Errorf("not ready after %s: %v", timeout, err)
+		case <-cancelCh:
+			return nil
+		case <-r.DoneCh:
+			return nil
+		}
+	}
+}
+
+// mergeRendered returns the rendered templates of a superseded readiness probe
+// combined with those of the probe replacing it. Templates rendered by both
+// keep their previous contents from before the first render.
+func mergeRendered(prev, rendered []*renderedTemplate) []*renderedTemplate {
+	merged := append([]*renderedTemplate(nil), prev...)
+NEXT_RENDERED:
+	for _, rt := range rendered {
+		for i, p := range merged {
+			if p.config == rt.config {
+				merged[i] = &renderedTemplate{
+					config:   rt.config,
+					previous: p.previous,
+					contents: rt.contents,
+				}
+				continue NEXT_RENDERED
+			}
+		}
+		merged = append(merged, rt)
+	}
+	return merged
+}
+
+// checkReadiness runs each configured readiness check of the child process
+// once, giving each check up to the timeout.
+func (r *Runner) checkReadiness(ec *execChild, timeout time.Duration) error {
+	rc := ec.config.Readiness
+
+	if addr := config.StringVal(rc.TCP); addr != "" {
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err != nil {
+			return fmt.Errorf("tcp %s: %v", addr, err)
+		}
+		conn.Close()
+	}
+
+	if url := config.StringVal(rc.HTTP); url != "" {
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Get(url)
+		if err != nil {
+			return fmt.Errorf("http: %v", err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("http %s: unexpected status %s", url, resp.Status)
+		}
+	}
+
+	if !rc.Command.Empty() {
+		env := ec.config.Env.Copy()
+		env.Custom = append(r.childEnv(), env.Custom...)
+		if _, err := spawnChild(&spawnChildInput{
+			Command: rc.Command,
+			Env:     env.Env(),
+			Timeout: timeout,
+		}); err != nil {
+			return fmt.Errorf("command %q: %v", []string(rc.Command), err)
+		}
+	}
+
+	return nil
+}
+
+// restoreTemplates writes back the previous contents of the rendered templates
+// and reloads the child process. It returns true if the templates were
+// restored.
+func (r *Runner) restoreTemplates(ec *execChild, rendered []*renderedTemplate) bool {
+	if !r.restoreFiles(rendered) {
+		return false
+	}
+
+	r.childLock.RLock()
+	defer r.childLock.RUnlock()
+	if ec.done || ec.restarting {
+		return true
+	}
+	if err := ec.child.Reload(); err != nil {
+		log.Printf("[ERR] (runner) failed to reload %s after restoring "+
+			"templates: %v", ec, err)
+	}
+	return true
+}
+
+// restoreFiles writes back the previous contents of the rendered templates. It
+// returns true if the templates were restored.
+func (r *Runner) restoreFiles(rendered []*renderedTemplate) bool {
+	for _, rt := range rendered {
+		log.Printf("[WARN] (runner) restoring previous contents of %s",
+			rt.config.Display())
+
+		sink, err := r.sinkFor(rt.config)
+		if err == nil {
+			if rt.previous == nil {
+				err = sink.Remove()
+			} else {
+				err = sink.Write(rt.previous)
+			}
+		}
+		if err != nil {
+			log.Printf("[ERR] (runner) failed to restore %s: %v",
+				rt.config.Display(), err)
+			return false
+		}
+	}
+	return true
+}
+
+// recordReadiness records the readiness error in the render events of the
+// rendered templates, or of every template the child process watches if it
+// was started rather than reloaded.
+func (r *Runner) recordReadiness(ec *execChild, rendered []*renderedTemplate, err error, restored bool) {
+	r.renderEventsLock.Lock()
+	defer r.renderEventsLock.Unlock()
+
+	for _, tmpl := range r.templates {
+		event, ok := r.renderEvents[tmpl.ID()]
+		if !ok || !r.readinessApplies(ec, tmpl, rendered) {
+			continue
+		}
+
+		// Replace the event rather than modifying it, since callers of
+		// RenderEvents may be holding on to it.
+		e := *event
+		e.ReadinessError = err
+		e.Restored = restored
+		r.renderEvents[tmpl.ID()] = &e
+	}
+}
+
+// readinessApplies returns true if the readiness of the child process applies
+// to the template: it is one of the rendered templates, or the child process
+// watches it when there are no rendered templates.
+func (r *Runner) readinessApplies(ec *execChild, tmpl *template.Template, rendered []*renderedTemplate) bool {
+	for _, tc := range r.templateConfigsFor(tmpl) {
+		if rendered == nil {
+			if ec.watches(tc) {
+				return true
+			}
+			continue
+		}
+		for _, rt := range rendered {
+			if rt.config == tc {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package manager
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul-template/config"
+)
+
+func TestRunner_checkReadiness(t *testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// Reserve an address with no listener
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/ready" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		name      string
+		readiness *config.ReadinessConfig
+		err       bool
+	}{
+		{
+			"tcp",
+			&config.ReadinessConfig{TCP: config.String(ln.Addr().String())},
+			false,
+		},
+		{
+			"tcp_closed",
+			&config.ReadinessConfig{TCP: config.String(closedAddr)},
+			true,
+		},
+		{
+			"http",
+			&config.ReadinessConfig{HTTP: config.String(ts.URL + "/ready")},
+			false,
+		},
+		{
+			"http_unavailable",
+			&config.ReadinessConfig{HTTP: config.String(ts.URL + "/nope")},
+			true,
+		},
+		{
+			"command",
+			&config.ReadinessConfig{Command: []string{"true"}},
+			false,
+		},
+		{
+			"command_failure",
+			&config.ReadinessConfig{Command: []string{"false"}},
+			true,
+		},
+		{
+			"all_must_pass",
+			&config.ReadinessConfig{
+				TCP:     config.String(ln.Addr().String()),
+				Command: []string{"false"},
+			},
+			true,
+		},
+	}
+
+	r, err := NewRunner(config.TestConfig(&config.Config{}), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			ec := &execChild{
+				config: &config.ExecConfig{Readiness: tc.readiness},
+			}
+			ec.config.Finalize()
+
+			err := r.checkReadiness(ec, 1*time.Second)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
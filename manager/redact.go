@@ -0,0 +1,37 @@
+package manager
+
+import (
+	"io"
+
+	"github.com/hashicorp/consul-template/template"
+)
+
+// redactWriter masks sensitive values in the brain before writing to the
+// underlying writer. Each write must contain whole values, such as a whole
+// rendered template.
+type redactWriter struct {
+	out   io.Writer
+	brain *template.Brain
+}
+
+func (w *redactWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(w.out, w.brain.Redact(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// redactEvent returns the render event with sensitive values in its contents
+// masked. The event is copied, rather than modified, if anything is masked.
+func (r *Runner) redactEvent(e *RenderEvent) *RenderEvent {
+	if e == nil || len(e.Contents) == 0 {
+		return e
+	}
+	contents := r.brain.Redact(string(e.Contents))
+	if contents == string(e.Contents) {
+		return e
+	}
+	c := *e
+	c.Contents = []byte(contents)
+	return &c
+}
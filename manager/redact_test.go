@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/logging"
+	"github.com/hashicorp/consul-template/renderer"
+	"github.com/hashicorp/consul-template/template"
+	"github.com/hashicorp/consul/api"
+)
+
+func testRedactBrain(t *testing.T) *template.Brain {
+	secret, err := dep.NewVaultReadQuery("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := dep.NewConnectLeafQuery("web")
+	kv, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	brain := template.NewBrain()
+	brain.Remember(secret, &dep.Secret{
+		Data: map[string]interface{}{
+			"password": "hunter2",
+			"nested":   map[string]interface{}{"pin": 1234},
+			"enabled":  true,
+		},
+	})
+	brain.Remember(leaf, &api.LeafCert{
+		PrivateKeyPEM: "-----BEGIN KEY-----\nabcdef\nghijkl\n-----END KEY-----\n",
+	})
+	brain.Remember(kv, "hunter3")
+	return brain
+}
+
+func TestRedactWriter(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		in       string
+		expected string
+	}{
+		{
+			"secret",
+			"+password = hunter2\n",
+			"+password = <redacted>\n",
+		},
+		{
+			"nested_number",
+			"-pin = 1234\n",
+			"-pin = 1234\n",
+		},
+		{
+			"multi_line",
+			"+abcdef\n+ghijkl\n",
+			"+<redacted>\n+<redacted>\n",
+		},
+		{
+			"not_secret",
+			"+value = hunter3\n+enabled = true\n",
+			"+value = hunter3\n+enabled = true\n",
+		},
+	}
+
+	brain := testRedactBrain(t)
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			var out bytes.Buffer
+			w := &redactWriter{out: &out, brain: brain}
+			n, err := w.Write([]byte(tc.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(tc.in) {
+				t.Errorf("expected %d bytes written, got %d", len(tc.in), n)
+			}
+			if act := out.String(); act != tc.expected {
+				t.Errorf("\nexp: %q\nact: %q", tc.expected, act)
+			}
+		})
+	}
+}
+
+func TestRunner_redactEvent(t *testing.T) {
+
+	r := &Runner{brain: testRedactBrain(t)}
+
+	t.Run("redacted", func(t *testing.T) {
+		e := &RenderEvent{Contents: []byte("password = hunter2")}
+		act := r.redactEvent(e)
+		if string(act.Contents) != "password = <redacted>" {
+			t.Errorf("expected contents to be redacted, got %q", act.Contents)
+		}
+		if string(e.Contents) != "password = hunter2" {
+			t.Errorf("expected event to not be modified, got %q", e.Contents)
+		}
+	})
+
+	t.Run("not_redacted", func(t *testing.T) {
+		e := &RenderEvent{Contents: []byte("value = hunter3")}
+		if act := r.redactEvent(e); act != e {
+			t.Errorf("expected the same event, got %#v", act)
+		}
+	})
+}
+
+func TestRunner_logDiff(t *testing.T) {
+	brain := testRedactBrain(t)
+
+	var buf bytes.Buffer
+	if err := logging.Setup(&logging.Config{Level: "INFO", Writer: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	logging.SetRedact(brain.Redact)
+	defer func() {
+		logging.SetRedact(nil)
+		logging.Setup(&logging.Config{Level: "INFO", Writer: ioutil.Discard})
+	}()
+
+	tmpl := config.DefaultTemplateConfig()
+	tmpl.Destination = config.String(filepath.Join(t.TempDir(), "out"))
+	tmpl.Finalize()
+	result := &renderer.RenderResult{
+		Previous: []byte("password = changeme\n"),
+		Contents: []byte("password = hunter2\n"),
+	}
+
+	cases := []struct {
+		name     string
+		redact   bool
+		expected string
+	}{
+		{
+			"redacted",
+			true,
+			"+password = <redacted>",
+		},
+		{
+			"not_redacted",
+			false,
+			"+password = hunter2",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			buf.Reset()
+			c := config.DefaultConfig()
+			c.RenderDiff.Redact = config.Bool(tc.redact)
+			c.Finalize()
+			r := &Runner{
+				config: c,
+				brain:  brain,
+				sinks:  make(map[*config.TemplateConfig]renderer.Sink),
+			}
+
+			r.logDiff(tmpl, result)
+			if act := buf.String(); !strings.Contains(act, tc.expected) {
+				t.Errorf("expected %q in:\n%s", tc.expected, act)
+			}
+		})
+	}
+}
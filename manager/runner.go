@@ -16,6 +16,7 @@ import (
 	"github.com/hashicorp/consul-template/child"
 	"github.com/hashicorp/consul-template/config"
 	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/logging"
 	"github.com/hashicorp/consul-template/renderer"
 	"github.com/hashicorp/consul-template/template"
 	"github.com/hashicorp/consul-template/watch"
@@ -81,16 +82,53 @@ type Runner struct {
 	// watcher is the watcher this runner is using.
 	watcher *watch.Watcher
 
+	// clients is the set of API clients, used by sinks that render templates
+	// to Consul or Vault.
+	clients *dep.ClientSet
+
+	// sinks is a mapping of template configs to the sinks they render to,
+	// created when the template is first rendered.
+	sinks map[*config.TemplateConfig]renderer.Sink
+
+	// sinksLock is a lock around touching the sinks map.
+	sinksLock sync.Mutex
+
+	// kubernetes is the client of the Kubernetes API, created when a template
+	// first renders to a Secret or ConfigMap.
+	kubernetes *renderer.KubernetesClient
+
 	// brain is the internal storage database of returned dependency data.
 	brain *template.Brain
 
-	// child is the child process under management. This may be nil if not running
-	// in exec mode.
-	child *child.Child
+	// renderedData is a mapping of a template ID to the hash of the data of
+	// each dependency it was last rendered with, used to find the dependencies
+	// that changed. The data itself is only kept for templates with a diff
+	// file, for as long as the runner lives.
+	renderedData map[string]map[string]*renderedDependency
 
-	// childLock is the internal lock around the child process.
+	// children are the child processes under management. This is empty if not
+	// running in exec mode.
+	children []*execChild
+
+	// childLock is the internal lock around the child processes.
 	childLock sync.RWMutex
 
+	// childExitCh is where the exits of child processes are reported.
+	childExitCh chan *childExit
+
+	// childRestartCh is where child processes waiting to be restarted are sent
+	// once their backoff has elapsed.
+	childRestartCh chan *execChild
+
+	// readinessCh is where the results of the readiness probes of child
+	// processes are reported.
+	readinessCh chan *readinessResult
+
+	// rejected is a mapping of template configs to the checksum of contents
+	// that were restored after the child process did not become ready with
+	// them. Those contents are not rendered again until they change.
+	rejected map[*config.TemplateConfig]string
+
 	// quiescenceMap is the map of templates to their quiescence timers.
 	// quiescenceCh is the channel where templates report returns from quiescence
 	// fires.
@@ -172,6 +210,15 @@ type RenderEvent struct {
 
 	// Error contains the error encountered while rendering the template.
 	Error error
+
+	// ReadinessError is the error from the readiness probe of a child process
+	// that was started or reloaded for this template. It is nil if the child
+	// process became ready, and is cleared when the template renders again.
+	ReadinessError error
+
+	// Restored is true if the previous contents of the template were restored
+	// because a reloaded child process did not become ready.
+	Restored bool
 }
 
 // NewRunner accepts a slice of TemplateConfigs and returns a pointer to the new
@@ -214,9 +261,6 @@ func (r *Runner) Start() {
 		dedupCh = r.dedup.UpdateCh()
 	}
 
-	// Setup the child process exit channel
-	var childExitCh <-chan int
-
 	// Fire an initial run to parse all the templates and setup the first-pass
 	// dependencies. This also forces any templates that have no dependencies to
 	// be rendered immediately (since they are already renderable).
@@ -228,17 +272,13 @@ func (r *Runner) Start() {
 	if r.config.ParseOnly {
 		log.Printf("[INFO] (runner) ParseOnly mode and all templates parsed")
 
-		if r.child != nil {
+		if r.hasChildren() {
 			r.stopDedup()
 			r.stopWatcher()
 
-			log.Printf("[INFO] (runner) waiting for child process to exit")
-			select {
-			case c := <-childExitCh:
-				log.Printf("[INFO] (runner) child process died")
-				r.ErrCh <- NewErrChildDied(c)
+			if err := r.waitChildren(); err != nil {
+				r.ErrCh <- err
 				return
-			case <-r.DoneCh:
 			}
 		}
 
@@ -246,6 +286,12 @@ func (r *Runner) Start() {
 		return
 	}
 
+	// In once mode, give up waiting on templates after the once timeout.
+	var onceTimeoutCh <-chan time.Time
+	if r.config.Once && r.config.OnceTimeout > 0 {
+		onceTimeoutCh = time.After(r.config.OnceTimeout)
+	}
+
 	for {
 		// Warn the user if they are watching too many dependencies.
 		if r.watcher.Size() > saneViewLimit {
@@ -284,48 +330,11 @@ func (r *Runner) Start() {
 				}
 			}
 
-			// If an exec command was given and a command is not currently running,
-			// spawn the child process for supervision.
-			if !r.config.Exec.Command.Empty() {
-				// Lock the child because we are about to check if it exists.
-				r.childLock.Lock()
-
-				log.Printf("[TRACE] (runner) acquired child lock for command, spawning")
-
-				if r.child == nil {
-					env := r.config.Exec.Env.Copy()
-					env.Custom = append(r.childEnv(), env.Custom...)
-					child, err := spawnChild(&spawnChildInput{
-						Stdin:        r.inStream,
-						Stdout:       r.outStream,
-						Stderr:       r.errStream,
-						Command:      r.config.Exec.Command,
-						Env:          env.Env(),
-						ReloadSignal: config.SignalVal(r.config.Exec.ReloadSignal),
-						KillSignal:   config.SignalVal(r.config.Exec.KillSignal),
-						KillTimeout:  config.TimeDurationVal(r.config.Exec.KillTimeout),
-						Splay:        config.TimeDurationVal(r.config.Exec.Splay),
-					})
-					if err != nil {
-						r.ErrCh <- err
-						r.childLock.Unlock()
-						return
-					}
-					r.child = child
-				}
-
-				// Unlock the child, we are done now.
-				r.childLock.Unlock()
-
-				// It's possible that we didn't start a process, in which case no
-				// channel is returned. If we did get a new exitCh, that means a child
-				// was spawned, so we need to watch a new exitCh. It is also possible
-				// that during a run, the child process was restarted, which means a
-				// new exit channel should be used.
-				nexitCh := r.child.ExitCh()
-				if nexitCh != nil {
-					childExitCh = nexitCh
-				}
+			// If exec commands were given and are not currently running, spawn the
+			// child processes for supervision.
+			if err := r.spawnChildren(); err != nil {
+				r.ErrCh <- err
+				return
 			}
 
 			// If we are running in once mode and all our templates are rendered,
@@ -333,17 +342,13 @@ func (r *Runner) Start() {
 			if r.config.Once {
 				log.Printf("[INFO] (runner) once mode and all templates rendered")
 
-				if r.child != nil {
+				if r.hasChildren() {
 					r.stopDedup()
 					r.stopWatcher()
 
-					log.Printf("[INFO] (runner) waiting for child process to exit")
-					select {
-					case c := <-childExitCh:
-						log.Printf("[INFO] (runner) child process died")
-						r.ErrCh <- NewErrChildDied(c)
+					if err := r.waitChildren(); err != nil {
+						r.ErrCh <- err
 						return
-					case <-r.DoneCh:
 					}
 				}
 
@@ -395,9 +400,32 @@ func (r *Runner) Start() {
 			log.Printf("[DEBUG] (runner) received template %q from quiescence", tmpl.ID())
 			delete(r.quiescenceMap, tmpl.ID())
 
-		case c := <-childExitCh:
-			log.Printf("[INFO] (runner) child process died")
-			r.ErrCh <- NewErrChildDied(c)
+		case e := <-r.childExitCh:
+			if err := r.childExited(e); err != nil {
+				r.ErrCh <- err
+				return
+			}
+			continue
+
+		case ec := <-r.childRestartCh:
+			if err := r.restartChild(ec); err != nil {
+				r.ErrCh <- err
+				return
+			}
+			continue
+
+		case res := <-r.readinessCh:
+			r.readinessDone(res)
+			continue
+
+		case <-onceTimeoutCh:
+			log.Printf("[ERR] (runner) once mode timed out after %s",
+				r.config.OnceTimeout)
+			err := r.onceTimeoutError()
+			if out, jerr := json.MarshalIndent(err, "", "  "); jerr == nil {
+				fmt.Fprintf(r.outStream, "%s\n", out)
+			}
+			r.ErrCh <- err
 			return
 
 		case <-r.DoneCh:
@@ -445,11 +473,27 @@ func (r *Runner) RenderEvents() map[string]*RenderEvent {
 
 	times := make(map[string]*RenderEvent, len(r.renderEvents))
 	for k, v := range r.renderEvents {
-		times[k] = v
+		times[k] = r.redactEvent(v)
 	}
 	return times
 }
 
+// Redact returns the string with sensitive values, such as secrets from Vault,
+// masked. It is used for output that is not written to a template's
+// destination, such as logs.
+func (r *Runner) Redact(s string) string {
+	return r.brain.Redact(s)
+}
+
+// DedupStatus returns the de-duplication state of each template, or nil if
+// de-duplication is not enabled.
+func (r *Runner) DedupStatus() []DedupStatus {
+	if r.dedup == nil {
+		return nil
+	}
+	return r.dedup.Status()
+}
+
 func (r *Runner) internalStop(immediately bool) {
 	r.stopLock.Lock()
 	defer r.stopLock.Unlock()
@@ -487,21 +531,6 @@ func (r *Runner) stopWatcher() {
 	}
 }
 
-func (r *Runner) stopChild(immediately bool) {
-	r.childLock.RLock()
-	defer r.childLock.RUnlock()
-
-	if r.child != nil {
-		if immediately {
-			log.Printf("[DEBUG] (runner) stopping child process immediately")
-			r.child.StopImmediately()
-		} else {
-			log.Printf("[DEBUG] (runner) stopping child process")
-			r.child.Stop()
-		}
-	}
-}
-
 // Receive accepts a Dependency and data for that dep. This data is
 // cached on the Runner. This data is then used to determine if a Template
 // is "renderable" (i.e. all its Dependencies have been downloaded at least
@@ -532,12 +561,7 @@ func (r *Runner) Receive(d dep.Dependency, data interface{}) {
 // Signal sends a signal to the child process, if it exists. Any errors that
 // occur are returned.
 func (r *Runner) Signal(s os.Signal) error {
-	r.childLock.RLock()
-	defer r.childLock.RUnlock()
-	if r.child == nil {
-		return nil
-	}
-	return r.child.Signal(s)
+	return r.signalChildren(s)
 }
 
 // Run iterates over each template in this Runner and conditionally executes
@@ -553,6 +577,7 @@ func (r *Runner) Run() error {
 	var newRenderEvent, wouldRenderAny, renderedAny bool
 	runCtx := &templateRunCtx{
 		depsMap: make(map[string]dep.Dependency),
+		changes: make(map[*config.TemplateConfig]*templateChange),
 	}
 
 	for _, tmpl := range r.templates {
@@ -591,9 +616,27 @@ func (r *Runner) Run() error {
 	for _, t := range runCtx.commands {
 		log.Printf("[INFO] (runner) executing command %q from %s",
 			fmt.Sprintf("%q", t.Exec.Command), t.Display())
+		s := fmt.Sprintf("failed to execute command %q from %s",
+			fmt.Sprintf("%q", t.Exec.Command), t.Display())
+
 		env := t.Exec.Env.Copy()
-		env.Custom = append(r.childEnv(), env.Custom...)
-		if _, err := spawnChild(&spawnChildInput{
+		custom := r.childEnv()
+		change := runCtx.changes[t]
+		var diffFile string
+		if change != nil {
+			custom = append(custom, change.env()...)
+			if config.BoolVal(t.ExecDiffFile) {
+				var err error
+				if diffFile, err = change.writeFile(); err != nil {
+					errs = append(errs, errors.Wrap(err, s))
+					continue
+				}
+				custom = append(custom, "CT_DIFF_FILE="+diffFile)
+			}
+		}
+		env.Custom = append(custom, env.Custom...)
+
+		c, err := spawnChild(&spawnChildInput{
 			Stdin:        r.inStream,
 			Stdout:       r.outStream,
 			Stderr:       r.errStream,
@@ -604,11 +647,23 @@ func (r *Runner) Run() error {
 			KillSignal:   config.SignalVal(t.Exec.KillSignal),
 			KillTimeout:  config.TimeDurationVal(t.Exec.KillTimeout),
 			Splay:        config.TimeDurationVal(t.Exec.Splay),
-		}); err != nil {
-			s := fmt.Sprintf("failed to execute command %q from %s",
-				fmt.Sprintf("%q", t.Exec.Command), t.Display())
+		})
+		if err != nil {
 			errs = append(errs, errors.Wrap(err, s))
 		}
+
+		// Remove the diff file once the command exits, which it has already
+		// done unless the command has no timeout.
+		if diffFile != "" {
+			if c == nil {
+				os.Remove(diffFile)
+			} else {
+				go func(c *child.Child, path string) {
+					<-c.ExitCh()
+					os.Remove(path)
+				}(c, diffFile)
+			}
+		}
 	}
 
 	// Check if we need to deliver any rendered signals
@@ -628,14 +683,10 @@ func (r *Runner) Run() error {
 		}
 	}
 
-	// If we got this far and have a child process, we need to send the reload
-	// signal to the child process.
-	if renderedAny && r.child != nil {
-		r.childLock.RLock()
-		if err := r.child.Reload(); err != nil {
-			errs = append(errs, err)
-		}
-		r.childLock.RUnlock()
+	// If we got this far and have child processes, we need to send the reload
+	// signal to the child processes watching the rendered templates.
+	if renderedAny {
+		errs = append(errs, r.reloadChildren(runCtx.rendered)...)
 	}
 
 	// If any errors were returned, convert them to an ErrorList for human
@@ -659,6 +710,27 @@ type templateRunCtx struct {
 
 	// depsMap is the set of dependencies shared across all templates.
 	depsMap map[string]dep.Dependency
+
+	// rendered is the list of template configs that were rendered during this
+	// run, used to reload the child processes watching them.
+	rendered []*renderedTemplate
+
+	// changes describe what changed for each template config that was rendered
+	// during this run, for the environment of its command.
+	changes map[*config.TemplateConfig]*templateChange
+}
+
+// renderedTemplate is a template config that was rendered, along with the
+// previous contents of its destination.
+type renderedTemplate struct {
+	config *config.TemplateConfig
+
+	// previous are the contents of the destination before it was rendered, or
+	// nil if it did not exist.
+	previous []byte
+
+	// contents are the rendered contents.
+	contents []byte
 }
 
 // runTemplate is used to run a particular template. It takes as input the
@@ -684,6 +756,8 @@ func (r *Runner) runTemplate(tmpl *template.Template, runCtx *templateRunCtx) (*
 	if lastEvent != nil {
 		event.LastWouldRender = lastEvent.LastWouldRender
 		event.LastDidRender = lastEvent.LastDidRender
+		event.ReadinessError = lastEvent.ReadinessError
+		event.Restored = lastEvent.Restored
 	}
 
 	// Check if we are currently the leader instance
@@ -808,23 +882,30 @@ func (r *Runner) runTemplate(tmpl *template.Template, runCtx *templateRunCtx) (*
 		return event, nil
 	}
 
+	// Find the dependencies that changed since the template was last rendered,
+	// to describe the change to commands.
+	changed := r.changedDependencies(tmpl, used)
+
 	// For each template configuration that is tied to this template, attempt to
 	// render it to disk and accumulate commands for later use.
 	for _, templateConfig := range r.templateConfigsFor(tmpl) {
+		// Do not render contents that were restored after the child process
+		// did not become ready with them, until the contents change.
+		if sum, ok := r.rejected[templateConfig]; ok {
+			if sum == checksum(result.Output) {
+				log.Printf("[DEBUG] (runner) not rendering %s with contents that "+
+					"were restored", templateConfig.Display())
+				event.WouldRender = true
+				event.LastWouldRender = time.Now().UTC()
+				continue
+			}
+			delete(r.rejected, templateConfig)
+		}
+
 		log.Printf("[DEBUG] (runner) rendering %s", templateConfig.Display())
 
 		// Render the template, taking dry mode into account
-		result, err := renderer.Render(&renderer.RenderInput{
-			Backup:         config.BoolVal(templateConfig.Backup),
-			Contents:       result.Output,
-			CreateDestDirs: config.BoolVal(templateConfig.CreateDestDirs),
-			Dry:            r.dry,
-			DryStream:      r.outStream,
-			Path:           config.StringVal(templateConfig.Destination),
-			Perms:          config.FileModeVal(templateConfig.Perms),
-			User:           config.StringVal(templateConfig.User),
-			Group:          config.StringVal(templateConfig.Group),
-		})
+		result, err := r.render(templateConfig, result.Output)
 		if err != nil {
 			if tmpl.ErrFatal() {
 				return nil, errors.Wrap(err, "error rendering "+templateConfig.Display())
@@ -855,10 +936,29 @@ func (r *Runner) runTemplate(tmpl *template.Template, runCtx *templateRunCtx) (*
 			// This event did render
 			event.DidRender = true
 			event.LastDidRender = renderTime
+			event.ReadinessError = nil
+			event.Restored = false
 
 			// Update the contents
 			event.Contents = result.Contents
 
+			runCtx.rendered = append(runCtx.rendered, &renderedTemplate{
+				config:   templateConfig,
+				previous: result.Previous,
+				contents: result.Contents,
+			})
+			runCtx.changes[templateConfig] = &templateChange{
+				ID:               tmpl.ID(),
+				Destination:      config.StringVal(templateConfig.Destination),
+				PreviousChecksum: checksum(result.Previous),
+				Checksum:         checksum(result.Contents),
+				Dependencies:     changed,
+			}
+
+			if !r.dry && config.BoolVal(r.config.RenderDiff.Log) {
+				r.logDiff(templateConfig, result)
+			}
+
 			if !r.dry {
 				// If the template was rendered (changed) and we are not in dry-run mode,
 				// aggregate commands, ignoring previously known commands
@@ -884,9 +984,36 @@ func (r *Runner) runTemplate(tmpl *template.Template, runCtx *templateRunCtx) (*
 		}
 	}
 
+	if event.DidRender {
+		r.rememberRendered(tmpl, used)
+	}
+
 	return event, nil
 }
 
+// logDiff logs a diff of the rendered template, masking sensitive values
+// unless redaction is disabled.
+func (r *Runner) logDiff(tc *config.TemplateConfig, result *renderer.RenderResult) {
+	// The sink was created when the template was rendered.
+	sink, err := r.sinkFor(tc)
+	if err != nil {
+		return
+	}
+	diff := renderer.Diff(sink.String(), result.Previous, result.Contents,
+		config.IntVal(r.config.RenderDiff.Context))
+	if diff == "" {
+		return
+	}
+	// Log lines are always redacted, so the diff is logged past the redaction
+	// when it is disabled.
+	printf := logging.Unredacted().Printf
+	if config.BoolVal(r.config.RenderDiff.Redact) {
+		diff = r.brain.Redact(diff)
+		printf = log.Printf
+	}
+	printf("[INFO] (runner) diff of %s:\n%s", tc.Display(), diff)
+}
+
 // init() creates the Runner's underlying data structures and returns an error
 // if any problems occur.
 func (r *Runner) init() error {
@@ -909,6 +1036,7 @@ func (r *Runner) init() error {
 	if err != nil {
 		return fmt.Errorf("runner: %s", err)
 	}
+	r.clients = clients
 
 	// Create the watcher
 	watcher, err := newWatcher(r.config, clients, r.config.Once)
@@ -921,11 +1049,29 @@ func (r *Runner) init() error {
 	templates := make([]*template.Template, 0, numTemplates)
 	ctemplatesMap := make(map[string]config.TemplateConfigs)
 
+	plugins := make([]string, 0, len(*r.config.Plugins))
+	pluginFunctions := make(map[string]string)
+	for _, p := range *r.config.Plugins {
+		name := config.StringVal(p.Name)
+		plugins = append(plugins, name)
+		for _, f := range p.Functions {
+			if other, ok := pluginFunctions[f]; ok && other != name {
+				return fmt.Errorf("runner: function %q is provided by plugins %q and %q",
+					f, other, name)
+			}
+			pluginFunctions[f] = name
+		}
+	}
+
 	// Iterate over each TemplateConfig, creating a new Template resource for each
 	// entry. Templates are parsed and saved, and a map of templates to their
 	// config templates is kept so templates can lookup their commands and output
 	// destinations.
 	for _, ctmpl := range *r.config.Templates {
+		if err := checkDestinationType(ctmpl); err != nil {
+			return err
+		}
+
 		leftDelim := config.StringVal(ctmpl.LeftDelim)
 		if leftDelim == "" {
 			leftDelim = config.StringVal(r.config.DefaultDelims.Left)
@@ -944,6 +1090,9 @@ func (r *Runner) init() error {
 			RightDelim:       rightDelim,
 			FunctionDenylist: ctmpl.FunctionDenylist,
 			SandboxPath:      config.StringVal(ctmpl.SandboxPath),
+			Plugins:          plugins,
+			PluginFunctions:  pluginFunctions,
+			Sprig:            config.BoolVal(ctmpl.Sprig),
 		})
 		if err != nil {
 			return err
@@ -974,6 +1123,8 @@ func (r *Runner) init() error {
 	r.outStream = os.Stdout
 	r.errStream = os.Stderr
 	r.brain = template.NewBrain()
+	r.renderedData = make(map[string]map[string]*renderedDependency)
+	r.sinks = make(map[*config.TemplateConfig]renderer.Sink)
 
 	r.ErrCh = make(chan error)
 	r.DoneCh = make(chan struct{})
@@ -981,6 +1132,15 @@ func (r *Runner) init() error {
 	r.quiescenceMap = make(map[string]*quiescence)
 	r.quiescenceCh = make(chan *template.Template)
 
+	r.children, err = newExecChildren(r.config)
+	if err != nil {
+		return err
+	}
+	r.childExitCh = make(chan *childExit)
+	r.childRestartCh = make(chan *execChild)
+	r.readinessCh = make(chan *readinessResult)
+	r.rejected = make(map[*config.TemplateConfig]string)
+
 	if *r.config.Dedup.Enabled {
 		if r.config.Once {
 			log.Printf("[INFO] (runner) disabling de-duplication in once mode")
@@ -1043,6 +1203,41 @@ func (r *Runner) TemplateConfigMapping() map[string][]*config.TemplateConfig {
 	return m
 }
 
+// onceTimeoutError returns the error reporting the templates that have not
+// rendered and the dependencies they are missing.
+func (r *Runner) onceTimeoutError() *ErrOnceTimeout {
+	r.renderEventsLock.RLock()
+	defer r.renderEventsLock.RUnlock()
+
+	e := &ErrOnceTimeout{
+		Timeout:   r.config.OnceTimeout.String(),
+		Templates: make([]*OnceTimeoutTemplate, 0),
+	}
+	for _, tmpl := range r.templates {
+		event, rendered := r.renderEvents[tmpl.ID()]
+		if rendered && (event.DidRender || event.WouldRender) {
+			continue
+		}
+
+		t := &OnceTimeoutTemplate{
+			ID:           tmpl.ID(),
+			Source:       tmpl.Source(),
+			Destinations: make([]string, 0),
+			MissingDeps:  make([]string, 0),
+		}
+		for _, c := range r.templateConfigsFor(tmpl) {
+			t.Destinations = append(t.Destinations, config.StringVal(c.Destination))
+		}
+		if event != nil && event.MissingDeps != nil {
+			for _, d := range event.MissingDeps.List() {
+				t.MissingDeps = append(t.MissingDeps, d.String())
+			}
+		}
+		e.Templates = append(e.Templates, t)
+	}
+	return e
+}
+
 // allTemplatesRendered returns true if all the templates in this Runner have
 // been rendered at least one time.
 func (r *Runner) allTemplatesRendered() bool {
@@ -1355,6 +1550,36 @@ func newClientSet(c *config.Config) (*dep.ClientSet, error) {
 		return nil, fmt.Errorf("runner: %s", err)
 	}
 
+	if err := clients.CreateHTTPClient(&dep.CreateHTTPClientInput{
+		Headers:      c.HTTP.Headers,
+		PollInterval: config.TimeDurationVal(c.HTTP.PollInterval),
+		Timeout:      config.TimeDurationVal(c.HTTP.Timeout),
+		SSLEnabled:   config.BoolVal(c.HTTP.SSL.Enabled),
+		SSLVerify:    config.BoolVal(c.HTTP.SSL.Verify),
+		SSLCert:      config.StringVal(c.HTTP.SSL.Cert),
+		SSLKey:       config.StringVal(c.HTTP.SSL.Key),
+		SSLCACert:    config.StringVal(c.HTTP.SSL.CaCert),
+		SSLCAPath:    config.StringVal(c.HTTP.SSL.CaPath),
+		ServerName:   config.StringVal(c.HTTP.SSL.ServerName),
+	}); err != nil {
+		return nil, fmt.Errorf("runner: %s", err)
+	}
+
+	for _, p := range *c.Plugins {
+		command, err := prepCommand(p.Command)
+		if err != nil {
+			return nil, fmt.Errorf("runner: plugin %q: %s", config.StringVal(p.Name), err)
+		}
+		if err := clients.CreatePlugin(&dep.CreatePluginInput{
+			Name:     config.StringVal(p.Name),
+			Command:  command,
+			Timeout:  config.TimeDurationVal(p.Timeout),
+			CacheTTL: config.TimeDurationVal(p.CacheTTL),
+		}); err != nil {
+			return nil, fmt.Errorf("runner: %s", err)
+		}
+	}
+
 	return clients, nil
 }
 
@@ -2,6 +2,7 @@ package manager
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -565,6 +567,141 @@ func TestRunner_Start(t *testing.T) {
 		}
 	})
 
+	t.Run("plugin", func(t *testing.T) {
+
+		// The plugin answers the first call with "v1" and sends an update,
+		// after which it answers "v2".
+		script, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(script.Name())
+		if _, err := script.WriteString(`
+n=0
+while IFS= read -r line; do
+	n=$((n+1))
+	id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+	echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":\"v$n\"}"
+	if [ "$n" = 1 ]; then
+		( sleep 0.1; echo '{"jsonrpc":"2.0","method":"update"}' ) &
+	fi
+done
+`); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Plugins: &config.PluginConfigs{
+				&config.PluginConfig{
+					Name:    config.String("version"),
+					Command: []string{"sh", script.Name()},
+				},
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`{{ plugin "version" }}`),
+					Destination: config.String(out.Name()),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		for _, exp := range []string{"v1", "v2"} {
+			select {
+			case err := <-r.ErrCh:
+				t.Fatal(err)
+			case <-r.renderedCh:
+				act, err := ioutil.ReadFile(out.Name())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if exp != string(act) {
+					t.Errorf("\nexp: %#v\nact: %#v", exp, string(act))
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("timeout")
+			}
+		}
+	})
+
+	t.Run("plugin_function", func(t *testing.T) {
+
+		script, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(script.Name())
+		if _, err := script.WriteString(`
+while IFS= read -r line; do
+	id=$(echo "$line" | sed 's/.*"id":\([0-9]*\).*/\1/')
+	arg=$(echo "$line" | sed 's/.*"args":\["\([^"]*\)".*/\1/')
+	echo "{\"jsonrpc\":\"2.0\",\"id\":$id,\"result\":{\"data\":{\"name\":\"$arg\"}}}"
+done
+`); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Plugins: &config.PluginConfigs{
+				&config.PluginConfig{
+					Name:      config.String("lookup"),
+					Command:   []string{"sh", script.Name()},
+					Functions: []string{"lookup"},
+				},
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`{{ (lookup "web").name }}`),
+					Destination: config.String(out.Name()),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.renderedCh:
+			act, err := ioutil.ReadFile(out.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exp := "web"; exp != string(act) {
+				t.Errorf("\nexp: %#v\nact: %#v", exp, string(act))
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
 	t.Run("single_dependency", func(t *testing.T) {
 
 		testConsul.SetKVString(t, "single-dep-foo", "bar")
@@ -705,7 +842,7 @@ func TestRunner_Start(t *testing.T) {
 				time.Sleep(100 * time.Millisecond)
 
 				r.childLock.RLock()
-				if r.child != nil {
+				if len(r.children) > 0 && r.children[0].child != nil {
 					found = true
 				}
 				r.childLock.RUnlock()
@@ -761,7 +898,7 @@ func TestRunner_Start(t *testing.T) {
 				time.Sleep(100 * time.Millisecond)
 
 				r.childLock.RLock()
-				if r.child != nil {
+				if len(r.children) > 0 && r.children[0].child != nil {
 					found = true
 				}
 				r.childLock.RUnlock()
@@ -774,6 +911,575 @@ func TestRunner_Start(t *testing.T) {
 		}
 	})
 
+	t.Run("exec_named", func(t *testing.T) {
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Execs: &config.ExecConfigs{
+				&config.ExecConfig{
+					Name:    config.String("web"),
+					Command: []string{`sleep 30`},
+				},
+				&config.ExecConfig{
+					Name:     config.String("setup"),
+					Command:  []string{`true`},
+					Critical: config.Bool(false),
+				},
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`test`),
+					Destination: config.String(out.Name()),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		// The non-critical child exiting must not stop the runner.
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-time.After(1 * time.Second):
+		}
+
+		r.childLock.RLock()
+		defer r.childLock.RUnlock()
+		if len(r.children) != 2 {
+			t.Fatalf("expected 2 children, got %d", len(r.children))
+		}
+		if web := r.children[0]; web.child == nil || web.done {
+			t.Errorf("expected %s to be running", web)
+		}
+		if setup := r.children[1]; setup.child == nil || !setup.done {
+			t.Errorf("expected %s to have exited", setup)
+		}
+	})
+
+	t.Run("exec_restart", func(t *testing.T) {
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		runs, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(runs.Name())
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Exec: &config.ExecConfig{
+				Command: []string{fmt.Sprintf(`echo run >> %s; exit 3`, runs.Name())},
+				Restart: &config.RestartConfig{
+					Policy:   config.String(config.RestartPolicyOnFailure),
+					Attempts: config.Int(2),
+					Backoff:  config.TimeDuration(10 * time.Millisecond),
+				},
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`test`),
+					Destination: config.String(out.Name()),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			if err, ok := err.(*ErrChildDied); !ok || err.ExitStatus() != 3 {
+				t.Fatalf("expected child died with 3, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		contents, err := ioutil.ReadFile(runs.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(contents), "run"); n != 3 {
+			t.Errorf("expected 3 runs, got %d", n)
+		}
+	})
+
+	t.Run("exec_readiness_restore", func(t *testing.T) {
+
+		src, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(src.Name())
+		if err := ioutil.WriteFile(src.Name(), []byte("good"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		dir := t.TempDir()
+		otherSrc := filepath.Join(dir, "src")
+		if err := ioutil.WriteFile(otherSrc, []byte("one"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		otherOut := filepath.Join(dir, "out")
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Exec: &config.ExecConfig{
+				Command: []string{`sleep 30`},
+				Readiness: &config.ReadinessConfig{
+					Command:          []string{fmt.Sprintf(`grep -q good %s`, out.Name())},
+					Interval:         config.TimeDuration(20 * time.Millisecond),
+					Timeout:          config.TimeDuration(200 * time.Millisecond),
+					RestoreOnFailure: config.Bool(true),
+				},
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(fmt.Sprintf(`{{ file %q }}`, src.Name())),
+					Destination: config.String(out.Name()),
+				},
+				&config.TemplateConfig{
+					Contents:    config.String(fmt.Sprintf(`{{ file %q }}`, otherSrc)),
+					Destination: config.String(otherOut),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.renderedCh:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		if err := ioutil.WriteFile(src.Name(), []byte("bad"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var event *RenderEvent
+		for i := 0; i < 50; i++ {
+			time.Sleep(100 * time.Millisecond)
+			for _, e := range r.RenderEvents() {
+				if e.Restored {
+					event = e
+				}
+			}
+			if event != nil {
+				break
+			}
+		}
+		if event == nil {
+			t.Fatal("template was not restored")
+		}
+		if event.ReadinessError == nil {
+			t.Error("expected readiness error")
+		}
+
+		contents, err := ioutil.ReadFile(out.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != "good" {
+			t.Errorf("expected restored contents, got %q", contents)
+		}
+
+		// Rendering another template must not render the restored contents
+		// again, but new contents are rendered.
+		waitContents := func(path, expected string) {
+			t.Helper()
+			var contents []byte
+			for i := 0; i < 50; i++ {
+				if contents, _ = ioutil.ReadFile(path); string(contents) == expected {
+					return
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			t.Fatalf("expected %q in %s, got %q", expected, path, contents)
+		}
+
+		if err := ioutil.WriteFile(otherSrc, []byte("two"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		waitContents(otherOut, "two")
+
+		contents, err = ioutil.ReadFile(out.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != "good" {
+			t.Errorf("expected restored contents to be kept, got %q", contents)
+		}
+
+		if err := ioutil.WriteFile(src.Name(), []byte("good again"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		waitContents(out.Name(), "good again")
+	})
+
+	t.Run("exec_readiness_async", func(t *testing.T) {
+
+		dir := t.TempDir()
+		src := filepath.Join(dir, "src")
+		if err := ioutil.WriteFile(src, []byte("one"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(dir, "out")
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Exec: &config.ExecConfig{
+				Command: []string{`sleep 30`},
+				Readiness: &config.ReadinessConfig{
+					Command:  []string{`false`},
+					Interval: config.TimeDuration(20 * time.Millisecond),
+					Timeout:  config.TimeDuration(30 * time.Second),
+				},
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(fmt.Sprintf(`{{ file %q }}`, src)),
+					Destination: config.String(out),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		var probing bool
+		for i := 0; i < 50 && !probing; i++ {
+			time.Sleep(50 * time.Millisecond)
+			r.childLock.RLock()
+			probing = len(r.children) > 0 && r.children[0].probe != nil
+			r.childLock.RUnlock()
+		}
+		if !probing {
+			t.Fatal("readiness probe was not started")
+		}
+
+		// The child process never becomes ready, which must not hold up
+		// rendering.
+		if err := ioutil.WriteFile(src, []byte("two"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var contents []byte
+		for i := 0; i < 50; i++ {
+			time.Sleep(100 * time.Millisecond)
+			if contents, _ = ioutil.ReadFile(out); string(contents) == "two" {
+				break
+			}
+		}
+		if string(contents) != "two" {
+			t.Errorf("expected template to render during the readiness probe, got %q", contents)
+		}
+	})
+
+	t.Run("exec_overlap", func(t *testing.T) {
+
+		src, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(src.Name())
+		if err := ioutil.WriteFile(src.Name(), []byte("one"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Exec: &config.ExecConfig{
+				Command:         []string{`sleep 30`},
+				RestartStrategy: config.String(config.ExecRestartStrategyOverlap),
+				Readiness: &config.ReadinessConfig{
+					Command:  []string{`true`},
+					Interval: config.TimeDuration(20 * time.Millisecond),
+				},
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(fmt.Sprintf(`{{ file %q }}`, src.Name())),
+					Destination: config.String(out.Name()),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		pid := func() int {
+			r.childLock.RLock()
+			defer r.childLock.RUnlock()
+			if len(r.children) == 0 || r.children[0].child == nil {
+				return 0
+			}
+			return r.children[0].child.Pid()
+		}
+
+		var oldPid int
+		for i := 0; i < 50 && oldPid == 0; i++ {
+			time.Sleep(50 * time.Millisecond)
+			oldPid = pid()
+		}
+		if oldPid == 0 {
+			t.Fatal("child process was not started")
+		}
+
+		if err := ioutil.WriteFile(src.Name(), []byte("two"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var newPid int
+		for i := 0; i < 50; i++ {
+			time.Sleep(100 * time.Millisecond)
+			if p := pid(); p != oldPid && p != 0 {
+				newPid = p
+				break
+			}
+		}
+		if newPid == 0 {
+			t.Fatal("child process was not replaced")
+		}
+
+		var stopped bool
+		for i := 0; i < 50 && !stopped; i++ {
+			stopped = syscall.Kill(oldPid, 0) != nil
+			time.Sleep(100 * time.Millisecond)
+		}
+		if !stopped {
+			t.Error("old child process was not stopped")
+		}
+
+		// Writing the file may be seen as more than one change, each of which
+		// replaces the child process, so check whichever one is current.
+		var running bool
+		for i := 0; i < 50 && !running; i++ {
+			p := pid()
+			running = p != 0 && p != oldPid && syscall.Kill(p, 0) == nil
+			time.Sleep(100 * time.Millisecond)
+		}
+		if !running {
+			t.Error("new child process is not running")
+		}
+	})
+
+	t.Run("command_env", func(t *testing.T) {
+
+		dir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		src := filepath.Join(dir, "src")
+		if err := ioutil.WriteFile(src, []byte("one"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(dir, "out")
+		envOut := filepath.Join(dir, "env")
+		diffOut := filepath.Join(dir, "diff")
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(fmt.Sprintf(`{{ file %q }}`, src)),
+					Destination: config.String(out),
+					Exec: &config.ExecConfig{
+						// The diff file is copied first, so it is complete
+						// once the environment shows the second render.
+						Command: []string{fmt.Sprintf(
+							`cp "$CT_DIFF_FILE" %s; env | grep ^CT_ | sort > %s`,
+							diffOut, envOut)},
+					},
+					ExecDiffFile: config.Bool(true),
+				},
+			},
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.renderedCh:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+
+		if err := ioutil.WriteFile(src, []byte("two"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var env []byte
+		for i := 0; i < 50; i++ {
+			time.Sleep(100 * time.Millisecond)
+			env, err = ioutil.ReadFile(envOut)
+			if err == nil && bytes.Contains(env, []byte("CT_CHECKSUM=3fc4")) {
+				break
+			}
+		}
+
+		id := r.templates[0].ID()
+		dependency := fmt.Sprintf("file(%s)", src)
+		expected := strings.Join([]string{
+			"CT_CHANGED_DEPENDENCIES=" + dependency,
+			// sha256 of "two"
+			"CT_CHECKSUM=3fc4ccfe745870e2c0d99f71f30ff0656c8dedd41cc1d7d3d376b0dbe685e2f3",
+			"CT_DESTINATION=" + out,
+			"CT_DIFF_FILE=",
+			// sha256 of "one"
+			"CT_PREVIOUS_CHECKSUM=7692c3ad3540bb803c020b3aee66cd8887123234ea0c6e7143c0add73ff431ed",
+			"CT_TEMPLATE_ID=" + id,
+		}, "\n")
+		var lines []string
+		for _, l := range strings.Split(strings.TrimSpace(string(env)), "\n") {
+			if strings.HasPrefix(l, "CT_DIFF_FILE=") {
+				l = "CT_DIFF_FILE="
+			}
+			lines = append(lines, l)
+		}
+		if act := strings.Join(lines, "\n"); act != expected {
+			t.Errorf("\nexp: %s\nact: %s", expected, act)
+		}
+
+		diff, err := ioutil.ReadFile(diffOut)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var change templateChange
+		if err := json.Unmarshal(diff, &change); err != nil {
+			t.Fatal(err)
+		}
+		if len(change.Dependencies) != 1 {
+			t.Fatalf("expected 1 changed dependency, got %d", len(change.Dependencies))
+		}
+		d := change.Dependencies[0]
+		if d.Dependency != dependency || d.Previous != "one" || d.Current != "two" {
+			t.Errorf("unexpected dependency change: %#v", d)
+		}
+	})
+
+	t.Run("once_timeout", func(t *testing.T) {
+
+		out, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(out.Name())
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Consul: &config.ConsulConfig{
+				Address: config.String(testConsul.HTTPAddr),
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:    config.String(`{{ key "once-timeout-missing" }}`),
+					Destination: config.String(out.Name()),
+				},
+			},
+			Once:        true,
+			OnceTimeout: 500 * time.Millisecond,
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var stdout bytes.Buffer
+		r.SetOutStream(&stdout)
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			typed, ok := err.(*ErrOnceTimeout)
+			if !ok {
+				t.Fatalf("expected *ErrOnceTimeout, got %T: %s", err, err)
+			}
+			if len(typed.Templates) != 1 {
+				t.Fatalf("expected 1 template, got %d", len(typed.Templates))
+			}
+			exp := []string{"kv.block(once-timeout-missing)"}
+			if !reflect.DeepEqual(exp, typed.Templates[0].MissingDeps) {
+				t.Errorf("\nexp: %#v\nact: %#v", exp, typed.Templates[0].MissingDeps)
+			}
+
+			var report ErrOnceTimeout
+			if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+				t.Fatalf("bad output %q: %s", stdout.String(), err)
+			}
+			if !reflect.DeepEqual(typed, &report) {
+				t.Errorf("\nexp: %#v\nact: %#v", typed, &report)
+			}
+		case <-r.DoneCh:
+			t.Fatal("expected once timeout error")
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
 	// Exec would run before template rendering if Wait was defined.
 	t.Run("exec-wait", func(t *testing.T) {
 
@@ -828,7 +1534,7 @@ func TestRunner_Start(t *testing.T) {
 				time.Sleep(100 * time.Millisecond)
 
 				r.childLock.RLock()
-				if r.child != nil {
+				if len(r.children) > 0 && r.children[0].child != nil {
 					found = true
 				}
 				r.childLock.RUnlock()
@@ -905,7 +1611,7 @@ func TestRunner_Start(t *testing.T) {
 				time.Sleep(100 * time.Millisecond)
 
 				r.childLock.RLock()
-				if r.child != nil {
+				if len(r.children) > 0 && r.children[0].child != nil {
 					found = true
 				}
 				r.childLock.RUnlock()
@@ -975,6 +1681,98 @@ func TestRunner_Start(t *testing.T) {
 		}
 	})
 
+	t.Run("destination_type_stdout", func(t *testing.T) {
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:        config.String(`test`),
+					DestinationType: config.String(config.DestinationTypeStdout),
+				},
+			},
+			Once: true,
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var o bytes.Buffer
+		r.SetOutStream(&o)
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.renderedCh:
+			exp := "test"
+			if exp != o.String() {
+				t.Errorf("\nexp: %#v\nact: %#v", exp, o.String())
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
+	t.Run("destination_type_consul_kv", func(t *testing.T) {
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Consul: &config.ConsulConfig{
+				Address: config.String(testConsul.HTTPAddr),
+			},
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:        config.String(`test`),
+					Destination:     config.String("destination-type-consul-kv"),
+					DestinationType: config.String(config.DestinationTypeConsulKV),
+				},
+			},
+			Once: true,
+		})
+		c.Finalize()
+
+		r, err := NewRunner(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go r.Start()
+		defer r.Stop()
+
+		select {
+		case err := <-r.ErrCh:
+			t.Fatal(err)
+		case <-r.renderedCh:
+			act := testConsul.GetKVString(t, "destination-type-consul-kv")
+			exp := "test"
+			if exp != act {
+				t.Errorf("\nexp: %#v\nact: %#v", exp, act)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout")
+		}
+	})
+
+	t.Run("destination_type_unknown", func(t *testing.T) {
+
+		c := config.DefaultConfig().Merge(&config.Config{
+			Templates: &config.TemplateConfigs{
+				&config.TemplateConfig{
+					Contents:        config.String(`test`),
+					DestinationType: config.String("nope"),
+				},
+			},
+		})
+		c.Finalize()
+
+		if _, err := NewRunner(c, false); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
 	t.Run("parse_only", func(t *testing.T) {
 
 		out, err := ioutil.TempFile("", "")
@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/hashicorp/consul-template/config"
+	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul-template/renderer"
+)
+
+// render renders the contents to the sink of the template config, taking dry
+// mode into account.
+func (r *Runner) render(tc *config.TemplateConfig, contents []byte) (*renderer.RenderResult, error) {
+	sink, err := r.sinkFor(tc)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderer.Render(&renderer.RenderInput{
+		Contents:    contents,
+		Dry:         r.dry,
+		DryDiff:     config.BoolVal(r.config.RenderDiff.Dry),
+		DryStream:   &redactWriter{out: r.outStream, brain: r.brain},
+		DiffContext: config.IntVal(r.config.RenderDiff.Context),
+		Sink:        sink,
+	})
+}
+
+// sinkFor returns the sink the template config renders to, creating it the
+// first time it is needed. Sinks are kept so that sinks which cannot be read
+// back, such as stdout, remember what they last wrote.
+func (r *Runner) sinkFor(tc *config.TemplateConfig) (renderer.Sink, error) {
+	r.sinksLock.Lock()
+	defer r.sinksLock.Unlock()
+
+	if sink, ok := r.sinks[tc]; ok {
+		return sink, nil
+	}
+
+	sink, err := r.newSink(tc)
+	if err != nil {
+		return nil, err
+	}
+	r.sinks[tc] = sink
+	return sink, nil
+}
+
+// newSink creates the sink for the destination type of the template config.
+func (r *Runner) newSink(tc *config.TemplateConfig) (renderer.Sink, error) {
+	dest := config.StringVal(tc.Destination)
+
+	switch t := config.StringVal(tc.DestinationType); t {
+	case "", config.DestinationTypeFile:
+		return &renderer.FileSink{
+			Backup:         config.BoolVal(tc.Backup),
+			CreateDestDirs: config.BoolVal(tc.CreateDestDirs),
+			Path:           dest,
+			Perms:          config.FileModeVal(tc.Perms),
+			User:           config.StringVal(tc.User),
+			Group:          config.StringVal(tc.Group),
+		}, nil
+	case config.DestinationTypeStdout:
+		return &renderer.StdoutSink{Out: r.outStream}, nil
+	}
+
+	if dest == "" {
+		return nil, renderer.ErrMissingDest
+	}
+
+	switch t := config.StringVal(tc.DestinationType); t {
+	case config.DestinationTypePipe:
+		return &renderer.PipeSink{
+			Path:  dest,
+			Perms: config.FileModeVal(tc.Perms),
+		}, nil
+	case config.DestinationTypeConsulKV:
+		return &renderer.ConsulKVSink{
+			KV:  r.clients.Consul().KV(),
+			Key: strings.TrimPrefix(dest, "/"),
+		}, nil
+	case config.DestinationTypeVaultKV:
+		path, isV2, err := dep.VaultKVPath(r.clients.Vault(), dest)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed looking up vault secrets engine")
+		}
+		return &renderer.VaultKVSink{
+			Logical: r.clients.Vault().Logical(),
+			Path:    path,
+			KVv2:    isV2,
+		}, nil
+	case config.DestinationTypeKubernetesSecret:
+		return r.newKubernetesSink(tc, renderer.KubernetesKindSecret)
+	case config.DestinationTypeKubernetesConfigMap:
+		return r.newKubernetesSink(tc, renderer.KubernetesKindConfigMap)
+	default:
+		return nil, fmt.Errorf("unknown destination type %q", t)
+	}
+}
+
+// newKubernetesSink creates a sink for a Secret or ConfigMap, whose destination
+// is its name, optionally prefixed with its namespace and a slash. The client of
+// the Kubernetes API is created the first time it is needed.
+func (r *Runner) newKubernetesSink(tc *config.TemplateConfig, kind string) (renderer.Sink, error) {
+	if r.kubernetes == nil {
+		kc := r.config.Kubernetes
+		client, err := renderer.NewKubernetesClient(&renderer.NewKubernetesClientInput{
+			Kubeconfig: config.StringVal(kc.Kubeconfig),
+			Context:    config.StringVal(kc.Context),
+			Namespace:  config.StringVal(kc.Namespace),
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.kubernetes = client
+	}
+
+	var namespace string
+	name := config.StringVal(tc.Destination)
+	if i := strings.Index(name, "/"); i >= 0 {
+		namespace, name = name[:i], name[i+1:]
+	}
+
+	oc := tc.Kubernetes
+	if oc == nil {
+		oc = config.DefaultKubernetesObjectConfig()
+		oc.Finalize()
+	}
+	refs := make([]renderer.KubernetesOwnerReference, 0, len(oc.OwnerReferences))
+	for _, ref := range oc.OwnerReferences {
+		owner := renderer.KubernetesOwnerReference{
+			APIVersion: config.StringVal(ref.APIVersion),
+			Kind:       config.StringVal(ref.Kind),
+			Name:       config.StringVal(ref.Name),
+			UID:        config.StringVal(ref.UID),
+		}
+		if config.BoolVal(ref.Controller) {
+			owner.Controller = ref.Controller
+		}
+		if config.BoolVal(ref.BlockOwnerDeletion) {
+			owner.BlockOwnerDeletion = ref.BlockOwnerDeletion
+		}
+		refs = append(refs, owner)
+	}
+
+	return &renderer.KubernetesSink{
+		Client:          r.kubernetes,
+		Kind:            kind,
+		Namespace:       namespace,
+		Name:            name,
+		Key:             config.StringVal(oc.Key),
+		Labels:          oc.Labels,
+		Annotations:     oc.Annotations,
+		OwnerReferences: refs,
+	}, nil
+}
+
+// checkDestinationType returns an error if the destination type of the
+// template config is unknown.
+func checkDestinationType(tc *config.TemplateConfig) error {
+	switch t := config.StringVal(tc.DestinationType); t {
+	case "", config.DestinationTypeFile, config.DestinationTypeStdout,
+		config.DestinationTypePipe, config.DestinationTypeConsulKV,
+		config.DestinationTypeVaultKV, config.DestinationTypeKubernetesSecret,
+		config.DestinationTypeKubernetesConfigMap:
+		return nil
+	default:
+		return fmt.Errorf("runner: unknown destination type %q for %s", t, tc.Display())
+	}
+}
@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/renderer"
+)
+
+func TestRunner_newKubernetesSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	if err := ioutil.WriteFile(kubeconfig, []byte(`
+current-context: test
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    user: test
+    namespace: apps
+users:
+- name: test
+  user:
+    token: token
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.DefaultConfig().Merge(&config.Config{
+		Kubernetes: &config.KubernetesConfig{
+			Kubeconfig: config.String(kubeconfig),
+		},
+		Templates: &config.TemplateConfigs{
+			&config.TemplateConfig{
+				Contents:        config.String("test"),
+				Destination:     config.String("app"),
+				DestinationType: config.String(config.DestinationTypeKubernetesConfigMap),
+			},
+			&config.TemplateConfig{
+				Contents:        config.String("test"),
+				Destination:     config.String("ct/app"),
+				DestinationType: config.String(config.DestinationTypeKubernetesSecret),
+				Kubernetes: &config.KubernetesObjectConfig{
+					Key:    config.String("app.conf"),
+					Labels: map[string]string{"app": "web"},
+					OwnerReferences: []*config.KubernetesOwnerReference{
+						{
+							APIVersion: config.String("apps/v1"),
+							Kind:       config.String("Deployment"),
+							Name:       config.String("web"),
+							UID:        config.String("1234"),
+							Controller: config.Bool(true),
+						},
+					},
+				},
+			},
+		},
+	})
+	c.Finalize()
+
+	r, err := NewRunner(c, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := r.sinkFor((*c.Templates)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "kubernetes-configmap:apps/app#contents"; sink.String() != exp {
+		t.Errorf("\nexp: %q\nact: %q", exp, sink.String())
+	}
+
+	sink, err = r.sinkFor((*c.Templates)[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := sink.(*renderer.KubernetesSink)
+	if exp := "kubernetes-secret:ct/app#app.conf"; ks.String() != exp {
+		t.Errorf("\nexp: %q\nact: %q", exp, ks.String())
+	}
+	if !reflect.DeepEqual(map[string]string{"app": "web"}, ks.Labels) {
+		t.Errorf("bad labels: %#v", ks.Labels)
+	}
+	expRefs := []renderer.KubernetesOwnerReference{
+		{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       "web",
+			UID:        "1234",
+			Controller: config.Bool(true),
+		},
+	}
+	if !reflect.DeepEqual(expRefs, ks.OwnerReferences) {
+		t.Errorf("\nexp: %#v\nact: %#v", expRefs, ks.OwnerReferences)
+	}
+}
@@ -0,0 +1,46 @@
+package renderer
+
+import (
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Diff returns a unified diff of the previous and new contents of the
+// destination path, with the given number of unchanged lines around each
+// change. The previous contents are nil if the destination did not exist. The
+// diff is empty if the contents are the same.
+func Diff(path string, previous, contents []byte, context int) string {
+	from := path
+	if previous == nil {
+		from = "/dev/null"
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(previous),
+		B:        splitLines(contents),
+		FromFile: from,
+		ToFile:   path,
+		Context:  context,
+	})
+	if err != nil {
+		// The diff is written to a buffer, which never fails.
+		return ""
+	}
+	return diff
+}
+
+// splitLines splits the contents into lines, each ending in a newline. A
+// missing newline at the end of the contents is marked the same way as diff.
+func splitLines(contents []byte) []string {
+	if len(contents) == 0 {
+		return nil
+	}
+
+	lines := difflib.SplitLines(string(contents))
+	if contents[len(contents)-1] == '\n' {
+		// SplitLines adds an empty line after the final newline.
+		return lines[:len(lines)-1]
+	}
+	last := len(lines) - 1
+	lines[last] += "\\ No newline at end of file\n"
+	return lines
+}
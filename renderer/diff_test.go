@@ -0,0 +1,62 @@
+package renderer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		previous []byte
+		contents []byte
+		context  int
+		expected string
+	}{
+		{
+			"same",
+			[]byte("one\n"),
+			[]byte("one\n"),
+			3,
+			"",
+		},
+		{
+			"changed",
+			[]byte("one\ntwo\nthree\n"),
+			[]byte("one\n2\nthree\n"),
+			3,
+			"--- out\n+++ out\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			"context",
+			[]byte("one\ntwo\nthree\n"),
+			[]byte("one\n2\nthree\n"),
+			0,
+			"--- out\n+++ out\n@@ -2 +2 @@\n-two\n+2\n",
+		},
+		{
+			"not_exists",
+			nil,
+			[]byte("one\n"),
+			3,
+			"--- /dev/null\n+++ out\n@@ -0,0 +1 @@\n+one\n",
+		},
+		{
+			"no_newline",
+			[]byte("one"),
+			[]byte("one\n"),
+			3,
+			"--- out\n+++ out\n@@ -1 +1 @@\n-one\n\\ No newline at end of file\n+one\n",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			act := Diff("out", tc.previous, tc.contents, tc.context)
+			if act != tc.expected {
+				t.Errorf("\nexp: %q\nact: %q", tc.expected, act)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package renderer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// DefaultKubernetesNamespace is the namespace used when neither the
+	// configuration, the kubeconfig context nor the service account gives one.
+	DefaultKubernetesNamespace = "default"
+
+	// kubernetesTimeout is the timeout of requests to the API server.
+	kubernetesTimeout = 30 * time.Second
+)
+
+// serviceAccountDir is where the service account of a pod is mounted. It is a
+// variable for testing.
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// NewKubernetesClientInput is used as input to the NewKubernetesClient
+// function.
+type NewKubernetesClientInput struct {
+	// Kubeconfig is the path to the kubeconfig file. If it is a list of paths,
+	// like the KUBECONFIG environment variable, the first one is used. The
+	// in-cluster configuration is used if it is empty.
+	Kubeconfig string
+
+	// Context is the kubeconfig context to use instead of its current context.
+	Context string
+
+	// Namespace overrides the namespace of the kubeconfig context or service
+	// account.
+	Namespace string
+}
+
+// NewKubernetesClient creates a client of the Kubernetes API from a kubeconfig
+// file, or from the service account of the pod it runs in.
+func NewKubernetesClient(i *NewKubernetesClientInput) (*KubernetesClient, error) {
+	var c *KubernetesClient
+	var err error
+	if path := firstPath(i.Kubeconfig); path != "" {
+		c, err = kubeconfigClient(path, i.Context)
+	} else {
+		c, err = inClusterClient()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if i.Namespace != "" {
+		c.Namespace = i.Namespace
+	}
+	if c.Namespace == "" {
+		c.Namespace = DefaultKubernetesNamespace
+	}
+	return c, nil
+}
+
+// inClusterClient creates a client that authenticates as the service account
+// of the pod it runs in.
+func inClusterClient() (*KubernetesClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("kubernetes: not running in a cluster and " +
+			"no kubeconfig is set")
+	}
+
+	tlsConfig := &tls.Config{}
+	ca, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes: failed reading service account CA")
+	}
+	if tlsConfig.RootCAs, err = certPool(ca); err != nil {
+		return nil, err
+	}
+
+	c := &KubernetesClient{
+		Host:       "https://" + net.JoinHostPort(host, port),
+		TokenFile:  filepath.Join(serviceAccountDir, "token"),
+		HTTPClient: kubernetesHTTPClient(tlsConfig),
+	}
+	if ns, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		c.Namespace = strings.TrimSpace(string(ns))
+	}
+	return c, nil
+}
+
+// kubeconfig is the part of a kubeconfig file that is supported.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string      `yaml:"token"`
+			TokenFile             string      `yaml:"tokenFile"`
+			ClientCertificate     string      `yaml:"client-certificate"`
+			ClientCertificateData string      `yaml:"client-certificate-data"`
+			ClientKey             string      `yaml:"client-key"`
+			ClientKeyData         string      `yaml:"client-key-data"`
+			Username              string      `yaml:"username"`
+			Password              string      `yaml:"password"`
+			Exec                  interface{} `yaml:"exec"`
+			AuthProvider          interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// kubeconfigClient creates a client from the context of the kubeconfig file at
+// the path, or from its current context if the context is empty.
+func kubeconfigClient(path, context string) (*KubernetesClient, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubernetes: failed reading kubeconfig")
+	}
+	var kc kubeconfig
+	if err := yaml.Unmarshal(b, &kc); err != nil {
+		return nil, errors.Wrap(err, "kubernetes: failed parsing kubeconfig")
+	}
+
+	// Relative paths in the kubeconfig are relative to its directory.
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	if context == "" {
+		context = kc.CurrentContext
+	}
+	var c KubernetesClient
+	var clusterName, userName string
+	found := false
+	for _, ctx := range kc.Contexts {
+		if ctx.Name == context {
+			clusterName, userName = ctx.Context.Cluster, ctx.Context.User
+			c.Namespace = ctx.Context.Namespace
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("kubernetes: context %q not found in kubeconfig", context)
+	}
+
+	tlsConfig := &tls.Config{}
+	found = false
+	for _, cl := range kc.Clusters {
+		if cl.Name != clusterName {
+			continue
+		}
+		found = true
+		c.Host = cl.Cluster.Server
+		tlsConfig.InsecureSkipVerify = cl.Cluster.InsecureSkipTLSVerify
+		tlsConfig.ServerName = cl.Cluster.TLSServerName
+
+		ca, err := fileOrData(resolve(cl.Cluster.CertificateAuthority),
+			cl.Cluster.CertificateAuthorityData)
+		if err != nil {
+			return nil, errors.Wrap(err, "kubernetes: failed reading cluster CA")
+		}
+		if ca != nil {
+			if tlsConfig.RootCAs, err = certPool(ca); err != nil {
+				return nil, err
+			}
+		}
+		break
+	}
+	if !found {
+		return nil, fmt.Errorf("kubernetes: cluster %q not found in kubeconfig", clusterName)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		if u.User.Exec != nil || u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("kubernetes: user %q uses an exec or auth "+
+				"provider plugin, which is not supported", userName)
+		}
+		c.Token = u.User.Token
+		c.TokenFile = resolve(u.User.TokenFile)
+		c.Username, c.Password = u.User.Username, u.User.Password
+
+		cert, err := fileOrData(resolve(u.User.ClientCertificate), u.User.ClientCertificateData)
+		if err != nil {
+			return nil, errors.Wrap(err, "kubernetes: failed reading client certificate")
+		}
+		key, err := fileOrData(resolve(u.User.ClientKey), u.User.ClientKeyData)
+		if err != nil {
+			return nil, errors.Wrap(err, "kubernetes: failed reading client key")
+		}
+		if cert != nil || key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, errors.Wrap(err, "kubernetes: invalid client certificate")
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+		break
+	}
+
+	c.HTTPClient = kubernetesHTTPClient(tlsConfig)
+	return &c, nil
+}
+
+// kubernetesHTTPClient returns an HTTP client with the TLS configuration.
+func kubernetesHTTPClient(tlsConfig *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{
+		Transport: transport,
+		Timeout:   kubernetesTimeout,
+	}
+}
+
+// certPool returns a pool of the PEM encoded certificates.
+func certPool(pem []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("kubernetes: no valid CA certificates found")
+	}
+	return pool, nil
+}
+
+// fileOrData returns the base64 decoded data if it is set, the contents of the
+// file if it is set, or nil.
+func fileOrData(path, data string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path != "" {
+		return ioutil.ReadFile(path)
+	}
+	return nil, nil
+}
+
+// firstPath returns the first path of a list of paths.
+func firstPath(list string) string {
+	for _, p := range filepath.SplitList(list) {
+		if p != "" {
+			return p
+		}
+	}
+	return ""
+}
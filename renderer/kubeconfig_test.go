@@ -0,0 +1,161 @@
+package renderer
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewKubernetesClient(t *testing.T) {
+	var auth string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("kubeconfig", func(t *testing.T) {
+		kubeconfig := filepath.Join(dir, "kubeconfig")
+		if err := ioutil.WriteFile(filepath.Join(dir, "token"), []byte("file-token\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(kubeconfig, []byte(fmt.Sprintf(`
+apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: test
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+contexts:
+- name: dev
+  context:
+    cluster: test
+    user: dev
+    namespace: dev
+- name: prod
+  context:
+    cluster: test
+    user: prod
+users:
+- name: dev
+  user:
+    token: dev-token
+- name: prod
+  user:
+    tokenFile: token
+`, srv.URL, base64.StdEncoding.EncodeToString(ca))), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		cases := []struct {
+			name      string
+			input     *NewKubernetesClientInput
+			namespace string
+			auth      string
+		}{
+			{
+				"current_context",
+				&NewKubernetesClientInput{Kubeconfig: kubeconfig},
+				"dev",
+				"Bearer dev-token",
+			},
+			{
+				"context",
+				&NewKubernetesClientInput{Kubeconfig: kubeconfig, Context: "prod"},
+				DefaultKubernetesNamespace,
+				"Bearer file-token",
+			},
+			{
+				"namespace",
+				&NewKubernetesClientInput{
+					Kubeconfig: kubeconfig + string(filepath.ListSeparator) + "nope",
+					Namespace:  "ct",
+				},
+				"ct",
+				"Bearer dev-token",
+			},
+		}
+
+		for i, tc := range cases {
+			t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+				client, err := NewKubernetesClient(tc.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if client.Namespace != tc.namespace {
+					t.Errorf("\nexp: %q\nact: %q", tc.namespace, client.Namespace)
+				}
+				if _, err := client.do(http.MethodGet, "/", "", nil, nil); err != nil {
+					t.Fatal(err)
+				}
+				if auth != tc.auth {
+					t.Errorf("\nexp: %q\nact: %q", tc.auth, auth)
+				}
+			})
+		}
+
+		if _, err := NewKubernetesClient(&NewKubernetesClientInput{
+			Kubeconfig: kubeconfig,
+			Context:    "nope",
+		}); err == nil {
+			t.Error("expected error for missing context")
+		}
+	})
+
+	t.Run("in_cluster", func(t *testing.T) {
+		saDir := filepath.Join(dir, "serviceaccount")
+		if err := os.Mkdir(saDir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		for name, contents := range map[string][]byte{
+			"ca.crt":    ca,
+			"token":     []byte("sa-token"),
+			"namespace": []byte("ct\n"),
+		} {
+			if err := ioutil.WriteFile(filepath.Join(saDir, name), contents, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		defer func(d string) { serviceAccountDir = d }(serviceAccountDir)
+		serviceAccountDir = saDir
+
+		host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+		defer os.Unsetenv("KUBERNETES_SERVICE_PORT")
+		os.Setenv("KUBERNETES_SERVICE_HOST", host)
+		os.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+		client, err := NewKubernetesClient(&NewKubernetesClientInput{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if client.Namespace != "ct" {
+			t.Errorf("\nexp: %q\nact: %q", "ct", client.Namespace)
+		}
+		if _, err := client.do(http.MethodGet, "/", "", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if exp := "Bearer sa-token"; auth != exp {
+			t.Errorf("\nexp: %q\nact: %q", exp, auth)
+		}
+	})
+}
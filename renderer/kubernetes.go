@@ -0,0 +1,379 @@
+package renderer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// KubernetesChecksumAnnotationPrefix is the prefix of the annotations of
+	// Secrets and ConfigMaps that hold the checksum of the contents rendered to
+	// each key and the metadata. Each key has its own annotation, so templates
+	// rendering to different keys of the same object do not overwrite each
+	// other's checksum.
+	KubernetesChecksumAnnotationPrefix = "consul-template.hashicorp.com/checksum-"
+
+	// KubernetesCreatedAnnotation is the annotation of Secrets and ConfigMaps
+	// that were created by Consul Template. Only those objects are deleted when
+	// their last key is removed.
+	KubernetesCreatedAnnotation = "consul-template.hashicorp.com/created"
+
+	// KubernetesKindSecret and KubernetesKindConfigMap are the kinds of
+	// objects templates can be rendered to.
+	KubernetesKindSecret    = "Secret"
+	KubernetesKindConfigMap = "ConfigMap"
+)
+
+// KubernetesClient is a minimal client of the Kubernetes API, used to read and
+// write Secrets and ConfigMaps.
+type KubernetesClient struct {
+	// Host is the URL of the API server.
+	Host string
+
+	// Namespace is the namespace of objects that are not given one.
+	Namespace string
+
+	// Token is the bearer token to authenticate with. If TokenFile is set, the
+	// token is read from it on each request instead, since service account
+	// tokens are rotated.
+	Token     string
+	TokenFile string
+
+	// Username and Password are used for basic authentication if there is no
+	// token.
+	Username, Password string
+
+	// HTTPClient is the client used to make requests.
+	HTTPClient *http.Client
+}
+
+// kubeStatus is the error returned by the API server.
+type kubeStatus struct {
+	Message string `json:"message"`
+}
+
+// do makes a request to the API server, decoding the response into out if it
+// is not nil. It returns the status code of the response. Responses with a
+// status code of 404 are not errors, so callers can handle missing objects.
+func (c *KubernetesClient) do(method, path, contentType string, in, out interface{}) (int, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.Host, "/")+path, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	token := c.Token
+	if c.TokenFile != "" {
+		b, err := ioutil.ReadFile(c.TokenFile)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed reading token file")
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	switch {
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return resp.StatusCode, nil
+	}
+	if resp.StatusCode >= 300 {
+		var status kubeStatus
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil || status.Message == "" {
+			return resp.StatusCode, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status,
+			status.Message)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, errors.Wrap(err, "failed decoding response")
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// KubernetesOwnerReference is an owner of a Secret or ConfigMap.
+type KubernetesOwnerReference struct {
+	APIVersion         string `json:"apiVersion"`
+	Kind               string `json:"kind"`
+	Name               string `json:"name"`
+	UID                string `json:"uid"`
+	Controller         *bool  `json:"controller,omitempty"`
+	BlockOwnerDeletion *bool  `json:"blockOwnerDeletion,omitempty"`
+}
+
+// kubeObject is the part of a Secret or ConfigMap that is read and written.
+// The data of Secrets is base64 encoded.
+type kubeObject struct {
+	APIVersion string            `json:"apiVersion,omitempty"`
+	Kind       string            `json:"kind,omitempty"`
+	Metadata   kubeObjectMeta    `json:"metadata"`
+	Type       string            `json:"type,omitempty"`
+	Data       map[string]string `json:"data,omitempty"`
+}
+
+type kubeObjectMeta struct {
+	Name            string                     `json:"name,omitempty"`
+	Namespace       string                     `json:"namespace,omitempty"`
+	ResourceVersion string                     `json:"resourceVersion,omitempty"`
+	Labels          map[string]string          `json:"labels,omitempty"`
+	Annotations     map[string]string          `json:"annotations,omitempty"`
+	OwnerReferences []KubernetesOwnerReference `json:"ownerReferences,omitempty"`
+}
+
+// KubernetesSink renders templates to a key of a Kubernetes Secret or
+// ConfigMap, creating the object if it does not exist. Other keys, labels and
+// annotations of the object are left alone. The object is annotated with a
+// checksum of the contents of the key and the configured metadata, so the
+// object is only written when either changed.
+type KubernetesSink struct {
+	Client *KubernetesClient
+
+	// Kind is KubernetesKindSecret or KubernetesKindConfigMap.
+	Kind string
+
+	// Namespace is the namespace of the object. The namespace of the client is
+	// used if it is empty.
+	Namespace string
+	Name      string
+	Key       string
+
+	Labels      map[string]string
+	Annotations map[string]string
+
+	// OwnerReferences replace the owner references of the object if any are
+	// given.
+	OwnerReferences []KubernetesOwnerReference
+
+	// observed is the checksum annotation of the key when it was last read.
+	observed     string
+	observedLock sync.Mutex
+}
+
+// Read returns the value of the key, or nil if the object or the key does not
+// exist.
+func (s *KubernetesSink) Read() ([]byte, error) {
+	var obj kubeObject
+	code, err := s.Client.do(http.MethodGet, s.path(), "", nil, &obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading "+s.kind())
+	}
+
+	s.observedLock.Lock()
+	defer s.observedLock.Unlock()
+	s.observed = ""
+	if code == http.StatusNotFound {
+		return nil, nil
+	}
+	s.observed = obj.Metadata.Annotations[s.checksumAnnotation()]
+
+	value, ok := obj.Data[s.Key]
+	if !ok {
+		return nil, nil
+	}
+	if s.Kind != KubernetesKindSecret {
+		return []byte(value), nil
+	}
+	contents, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed decoding "+s.kind())
+	}
+	return contents, nil
+}
+
+// Stale returns true if the checksum annotation of the key does not match
+// its contents and the configured metadata, which means the metadata changed.
+func (s *KubernetesSink) Stale(existing []byte) (bool, error) {
+	if existing == nil {
+		return false, nil
+	}
+
+	s.observedLock.Lock()
+	defer s.observedLock.Unlock()
+	return s.observed != s.checksum(existing), nil
+}
+
+// Write sets the value of the key and the configured metadata, creating the
+// object if it does not exist.
+func (s *KubernetesSink) Write(contents []byte) error {
+	value := string(contents)
+	if s.Kind == KubernetesKindSecret {
+		value = base64.StdEncoding.EncodeToString(contents)
+	}
+
+	annotations := make(map[string]string, len(s.Annotations)+1)
+	for k, v := range s.Annotations {
+		annotations[k] = v
+	}
+	annotations[s.checksumAnnotation()] = s.checksum(contents)
+
+	obj := kubeObject{
+		Metadata: kubeObjectMeta{
+			Labels:          s.Labels,
+			Annotations:     annotations,
+			OwnerReferences: s.OwnerReferences,
+		},
+		Data: map[string]string{s.Key: value},
+	}
+
+	// A merge patch leaves the other keys and metadata of the object alone.
+	code, err := s.Client.do(http.MethodPatch, s.path(),
+		"application/merge-patch+json", obj, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed updating "+s.kind())
+	}
+	if code != http.StatusNotFound {
+		return nil
+	}
+
+	obj.APIVersion = "v1"
+	obj.Kind = s.Kind
+	obj.Metadata.Name = s.Name
+	obj.Metadata.Namespace = s.namespace()
+	obj.Metadata.Annotations[KubernetesCreatedAnnotation] = "true"
+	if s.Kind == KubernetesKindSecret {
+		obj.Type = "Opaque"
+	}
+	if _, err := s.Client.do(http.MethodPost, s.collectionPath(),
+		"application/json", obj, nil); err != nil {
+		return errors.Wrap(err, "failed creating "+s.kind())
+	}
+	return nil
+}
+
+// Remove removes the key and its checksum annotation from the object. The
+// object is deleted instead if Consul Template created it and the key is its
+// only one, so objects that are also written by others are left alone.
+func (s *KubernetesSink) Remove() error {
+	var obj kubeObject
+	code, err := s.Client.do(http.MethodGet, s.path(), "", nil, &obj)
+	if err != nil {
+		return errors.Wrap(err, "failed reading "+s.kind())
+	}
+	if code == http.StatusNotFound {
+		return nil
+	}
+	if _, ok := obj.Data[s.Key]; !ok {
+		return nil
+	}
+
+	if obj.Metadata.Annotations[KubernetesCreatedAnnotation] == "true" && len(obj.Data) == 1 {
+		// The precondition fails the request if the object changed since it
+		// was read, such as another template adding a key.
+		var options interface{}
+		if v := obj.Metadata.ResourceVersion; v != "" {
+			options = map[string]interface{}{
+				"apiVersion":    "v1",
+				"kind":          "DeleteOptions",
+				"preconditions": map[string]string{"resourceVersion": v},
+			}
+		}
+		if _, err := s.Client.do(http.MethodDelete, s.path(),
+			"application/json", options, nil); err != nil {
+			return errors.Wrap(err, "failed deleting "+s.kind())
+		}
+		return nil
+	}
+
+	// A null value in a merge patch removes the field.
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{s.checksumAnnotation(): nil},
+		},
+		"data": map[string]interface{}{s.Key: nil},
+	}
+	if _, err := s.Client.do(http.MethodPatch, s.path(),
+		"application/merge-patch+json", patch, nil); err != nil {
+		return errors.Wrap(err, "failed updating "+s.kind())
+	}
+	return nil
+}
+
+// String returns the kind, namespace, name and key of the object.
+func (s *KubernetesSink) String() string {
+	return fmt.Sprintf("%s:%s/%s#%s", s.kind(), s.namespace(), s.Name, s.Key)
+}
+
+// checksum returns the checksum of the contents and the configured metadata.
+func (s *KubernetesSink) checksum(contents []byte) string {
+	b, _ := json.Marshal(struct {
+		Contents        []byte
+		Labels          map[string]string
+		Annotations     map[string]string
+		OwnerReferences []KubernetesOwnerReference
+	}{contents, s.Labels, s.Annotations, s.OwnerReferences})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// checksumAnnotation returns the checksum annotation of the key. The name of
+// an annotation, after the prefix, is limited to 63 characters and must end
+// with an alphanumeric character, so other keys are hashed.
+func (s *KubernetesSink) checksumAnnotation() string {
+	name := KubernetesChecksumAnnotationPrefix[strings.Index(KubernetesChecksumAnnotationPrefix, "/")+1:] + s.Key
+	if last := name[len(name)-1]; len(name) <= 63 && ((last >= 'a' && last <= 'z') ||
+		(last >= 'A' && last <= 'Z') || (last >= '0' && last <= '9')) {
+		return KubernetesChecksumAnnotationPrefix + s.Key
+	}
+	sum := sha256.Sum256([]byte(s.Key))
+	return KubernetesChecksumAnnotationPrefix + hex.EncodeToString(sum[:16])
+}
+
+func (s *KubernetesSink) kind() string {
+	return "kubernetes-" + strings.ToLower(s.Kind)
+}
+
+func (s *KubernetesSink) namespace() string {
+	if s.Namespace != "" {
+		return s.Namespace
+	}
+	return s.Client.Namespace
+}
+
+func (s *KubernetesSink) collectionPath() string {
+	resource := "configmaps"
+	if s.Kind == KubernetesKindSecret {
+		resource = "secrets"
+	}
+	return fmt.Sprintf("/api/v1/namespaces/%s/%s",
+		url.PathEscape(s.namespace()), resource)
+}
+
+func (s *KubernetesSink) path() string {
+	return s.collectionPath() + "/" + url.PathEscape(s.Name)
+}
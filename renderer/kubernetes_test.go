@@ -0,0 +1,373 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeKubernetes is a fake API server that stores Secrets and ConfigMaps.
+type fakeKubernetes struct {
+	sync.Mutex
+	objects  map[string]*kubeObject
+	requests []string
+	version  int
+}
+
+func newFakeKubernetes(t *testing.T) (*fakeKubernetes, *KubernetesClient) {
+	f := &fakeKubernetes{objects: make(map[string]*kubeObject)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, &KubernetesClient{
+		Host:       srv.URL,
+		Namespace:  "default",
+		Token:      "token",
+		HTTPClient: srv.Client(),
+	}
+}
+
+func (f *fakeKubernetes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = append(f.requests, r.Method)
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(kubeStatus{Message: "Unauthorized"})
+		return
+	}
+
+	path := r.URL.Path
+	switch r.Method {
+	case http.MethodGet:
+		obj, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(obj)
+	case http.MethodPost:
+		var obj kubeObject
+		json.NewDecoder(r.Body).Decode(&obj)
+		f.version++
+		obj.Metadata.ResourceVersion = fmt.Sprint(f.version)
+		f.objects[path+"/"+obj.Metadata.Name] = &obj
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodPatch:
+		obj, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		// A nil value is a null in the patch, which removes the key.
+		var patch struct {
+			Metadata struct {
+				Labels          map[string]*string
+				Annotations     map[string]*string
+				OwnerReferences []KubernetesOwnerReference
+			}
+			Data map[string]*string
+		}
+		json.NewDecoder(r.Body).Decode(&patch)
+		merge := func(dst *map[string]string, src map[string]*string) {
+			if *dst == nil {
+				*dst = make(map[string]string)
+			}
+			for k, v := range src {
+				if v == nil {
+					delete(*dst, k)
+				} else {
+					(*dst)[k] = *v
+				}
+			}
+		}
+		merge(&obj.Metadata.Labels, patch.Metadata.Labels)
+		merge(&obj.Metadata.Annotations, patch.Metadata.Annotations)
+		merge(&obj.Data, patch.Data)
+		if patch.Metadata.OwnerReferences != nil {
+			obj.Metadata.OwnerReferences = patch.Metadata.OwnerReferences
+		}
+		f.version++
+		obj.Metadata.ResourceVersion = fmt.Sprint(f.version)
+	case http.MethodDelete:
+		obj, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var options struct {
+			Preconditions struct {
+				ResourceVersion string
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&options)
+		if v := options.Preconditions.ResourceVersion; v != "" && v != obj.Metadata.ResourceVersion {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(kubeStatus{Message: "Precondition failed"})
+			return
+		}
+		delete(f.objects, path)
+	}
+}
+
+// writes returns the number of requests that wrote objects, and resets the
+// requests.
+func (f *fakeKubernetes) writes() int {
+	f.Lock()
+	defer f.Unlock()
+	n := 0
+	for _, m := range f.requests {
+		if m != http.MethodGet {
+			n++
+		}
+	}
+	f.requests = nil
+	return n
+}
+
+func (f *fakeKubernetes) object(path string) *kubeObject {
+	f.Lock()
+	defer f.Unlock()
+	return f.objects[path]
+}
+
+func TestKubernetesSink(t *testing.T) {
+	t.Run("secret", func(t *testing.T) {
+		f, client := newFakeKubernetes(t)
+		controller := true
+		sink := &KubernetesSink{
+			Client:      client,
+			Kind:        KubernetesKindSecret,
+			Name:        "app",
+			Key:         "app.conf",
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{"team": "infra"},
+			OwnerReferences: []KubernetesOwnerReference{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "web",
+					UID: "1234", Controller: &controller},
+			},
+		}
+
+		rr, err := Render(&RenderInput{Contents: []byte("first"), Sink: sink})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rr.DidRender || rr.Previous != nil {
+			t.Errorf("expected create; did: %v, previous: %q", rr.DidRender, rr.Previous)
+		}
+		if n := f.writes(); n != 2 {
+			t.Errorf("expected a patch and a create, got %d writes", n)
+		}
+
+		obj := f.object("/api/v1/namespaces/default/secrets/app")
+		if obj == nil {
+			t.Fatal("expected secret to be created")
+		}
+		if obj.Kind != "Secret" || obj.Type != "Opaque" || obj.Metadata.Namespace != "default" {
+			t.Errorf("bad secret: %#v", obj)
+		}
+		if exp := "Zmlyc3Q="; obj.Data["app.conf"] != exp {
+			t.Errorf("\nexp: %q\nact: %q", exp, obj.Data["app.conf"])
+		}
+		if obj.Metadata.Labels["app"] != "web" || obj.Metadata.Annotations["team"] != "infra" {
+			t.Errorf("bad metadata: %#v", obj.Metadata)
+		}
+		if obj.Metadata.Annotations[KubernetesChecksumAnnotationPrefix+"app.conf"] == "" {
+			t.Error("expected checksum annotation")
+		}
+		if obj.Metadata.Annotations[KubernetesCreatedAnnotation] != "true" {
+			t.Error("expected created annotation")
+		}
+		if !reflect.DeepEqual(sink.OwnerReferences, obj.Metadata.OwnerReferences) {
+			t.Errorf("\nexp: %#v\nact: %#v", sink.OwnerReferences, obj.Metadata.OwnerReferences)
+		}
+
+		rr, err = Render(&RenderInput{Contents: []byte("first"), Sink: sink})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rr.DidRender {
+			t.Error("expected unchanged secret not to be written")
+		}
+		if n := f.writes(); n != 0 {
+			t.Errorf("expected no writes, got %d", n)
+		}
+
+		// Changing the metadata changes the checksum.
+		sink.Labels = map[string]string{"app": "api"}
+		rr, err = Render(&RenderInput{Contents: []byte("first"), Sink: sink})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rr.DidRender || string(rr.Previous) != "first" {
+			t.Errorf("expected update; did: %v, previous: %q", rr.DidRender, rr.Previous)
+		}
+		if n := f.writes(); n != 1 {
+			t.Errorf("expected a patch, got %d writes", n)
+		}
+		if l := obj.Metadata.Labels["app"]; l != "api" {
+			t.Errorf("expected label to be updated, got %q", l)
+		}
+
+		if err := sink.Remove(); err != nil {
+			t.Fatal(err)
+		}
+		if f.object("/api/v1/namespaces/default/secrets/app") != nil {
+			t.Error("expected secret to be deleted")
+		}
+	})
+
+	t.Run("configmap", func(t *testing.T) {
+		f, client := newFakeKubernetes(t)
+		f.objects["/api/v1/namespaces/ct/configmaps/app"] = &kubeObject{
+			Metadata: kubeObjectMeta{Name: "app", Labels: map[string]string{"keep": "me"}},
+			Data:     map[string]string{"other": "value"},
+		}
+		sink := &KubernetesSink{
+			Client:    client,
+			Kind:      KubernetesKindConfigMap,
+			Namespace: "ct",
+			Name:      "app",
+			Key:       "app.conf",
+		}
+
+		if _, err := Render(&RenderInput{Contents: []byte("first"), Sink: sink}); err != nil {
+			t.Fatal(err)
+		}
+		obj := f.object("/api/v1/namespaces/ct/configmaps/app")
+		exp := map[string]string{"other": "value", "app.conf": "first"}
+		if !reflect.DeepEqual(exp, obj.Data) {
+			t.Errorf("\nexp: %#v\nact: %#v", exp, obj.Data)
+		}
+		if obj.Metadata.Labels["keep"] != "me" {
+			t.Errorf("expected other labels to be kept: %#v", obj.Metadata.Labels)
+		}
+
+		existing, err := sink.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(existing) != "first" {
+			t.Errorf("\nexp: %q\nact: %q", "first", existing)
+		}
+		if expected := "kubernetes-configmap:ct/app#app.conf"; sink.String() != expected {
+			t.Errorf("\nexp: %q\nact: %q", expected, sink.String())
+		}
+
+		// The object was not created by Consul Template, so only the key is
+		// removed.
+		if err := sink.Remove(); err != nil {
+			t.Fatal(err)
+		}
+		obj = f.object("/api/v1/namespaces/ct/configmaps/app")
+		if obj == nil {
+			t.Fatal("expected configmap to be kept")
+		}
+		exp = map[string]string{"other": "value"}
+		if !reflect.DeepEqual(exp, obj.Data) {
+			t.Errorf("\nexp: %#v\nact: %#v", exp, obj.Data)
+		}
+		if _, ok := obj.Metadata.Annotations[KubernetesChecksumAnnotationPrefix+"app.conf"]; ok {
+			t.Error("expected checksum annotation to be removed")
+		}
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		f, client := newFakeKubernetes(t)
+		sinks := []*KubernetesSink{
+			{Client: client, Kind: KubernetesKindSecret, Name: "app", Key: "a.conf"},
+			{Client: client, Kind: KubernetesKindSecret, Name: "app", Key: "b.conf"},
+		}
+		path := "/api/v1/namespaces/default/secrets/app"
+
+		for _, sink := range sinks {
+			if _, err := Render(&RenderInput{Contents: []byte(sink.Key), Sink: sink}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		f.writes()
+
+		// Each key has its own checksum, so neither sink is stale.
+		for _, sink := range sinks {
+			rr, err := Render(&RenderInput{Contents: []byte(sink.Key), Sink: sink})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rr.DidRender {
+				t.Errorf("expected %s not to be written", sink)
+			}
+		}
+		if n := f.writes(); n != 0 {
+			t.Errorf("expected no writes, got %d", n)
+		}
+
+		// Removing a key keeps the object while it has other keys.
+		if err := sinks[0].Remove(); err != nil {
+			t.Fatal(err)
+		}
+		obj := f.object(path)
+		if obj == nil {
+			t.Fatal("expected secret to be kept")
+		}
+		if _, ok := obj.Data["a.conf"]; ok || len(obj.Data) != 1 {
+			t.Errorf("expected only a.conf to be removed: %#v", obj.Data)
+		}
+
+		// Removing a missing key does nothing.
+		if err := sinks[0].Remove(); err != nil {
+			t.Fatal(err)
+		}
+		if f.object(path) == nil {
+			t.Fatal("expected secret to be kept")
+		}
+
+		if err := sinks[1].Remove(); err != nil {
+			t.Fatal(err)
+		}
+		if f.object(path) != nil {
+			t.Error("expected secret to be deleted")
+		}
+	})
+
+	t.Run("checksum_annotation", func(t *testing.T) {
+		cases := []struct {
+			key      string
+			expected string
+		}{
+			{"app.conf", KubernetesChecksumAnnotationPrefix + "app.conf"},
+			{"app.", KubernetesChecksumAnnotationPrefix + "1ef4a5d1b64f7326ee4ca361e4cb36eb"},
+			{strings.Repeat("a", 60), KubernetesChecksumAnnotationPrefix + "11ee391211c6256460b6ed375957fadd"},
+		}
+		for _, tc := range cases {
+			sink := &KubernetesSink{Key: tc.key}
+			if act := sink.checksumAnnotation(); act != tc.expected {
+				t.Errorf("%s:\nexp: %q\nact: %q", tc.key, tc.expected, act)
+			}
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, client := newFakeKubernetes(t)
+		client.Token = "nope"
+		sink := &KubernetesSink{
+			Client: client,
+			Kind:   KubernetesKindSecret,
+			Name:   "app",
+			Key:    "app.conf",
+		}
+
+		_, err := Render(&RenderInput{Contents: []byte("first"), Sink: sink})
+		if err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+			t.Errorf("expected unauthorized error, got %v", err)
+		}
+	})
+}
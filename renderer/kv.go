@@ -0,0 +1,133 @@
+package renderer
+
+import (
+	"fmt"
+
+	consulapi "github.com/hashicorp/consul/api"
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+// VaultKVField is the field of the Vault secret that templates are rendered to.
+const VaultKVField = "value"
+
+// ConsulKV is the part of the Consul KV API used by ConsulKVSink. It is
+// implemented by *api.KV.
+type ConsulKV interface {
+	Get(key string, q *consulapi.QueryOptions) (*consulapi.KVPair, *consulapi.QueryMeta, error)
+	Put(p *consulapi.KVPair, q *consulapi.WriteOptions) (*consulapi.WriteMeta, error)
+	Delete(key string, w *consulapi.WriteOptions) (*consulapi.WriteMeta, error)
+}
+
+// ConsulKVSink renders templates to a key in Consul's KV store.
+type ConsulKVSink struct {
+	KV  ConsulKV
+	Key string
+}
+
+// Read returns the value of the key, or nil if it does not exist.
+func (s *ConsulKVSink) Read() ([]byte, error) {
+	pair, _, err := s.KV.Get(s.Key, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading consul key")
+	}
+	if pair == nil {
+		return nil, nil
+	}
+	if pair.Value == nil {
+		return []byte{}, nil
+	}
+	return pair.Value, nil
+}
+
+// Write sets the value of the key.
+func (s *ConsulKVSink) Write(contents []byte) error {
+	if _, err := s.KV.Put(&consulapi.KVPair{Key: s.Key, Value: contents}, nil); err != nil {
+		return errors.Wrap(err, "failed writing consul key")
+	}
+	return nil
+}
+
+// Remove deletes the key.
+func (s *ConsulKVSink) Remove() error {
+	if _, err := s.KV.Delete(s.Key, nil); err != nil {
+		return errors.Wrap(err, "failed deleting consul key")
+	}
+	return nil
+}
+
+// String returns the key, prefixed with "consul-kv:".
+func (s *ConsulKVSink) String() string {
+	return "consul-kv:" + s.Key
+}
+
+// VaultLogical is the part of the Vault logical API used by VaultKVSink. It is
+// implemented by *api.Logical.
+type VaultLogical interface {
+	Read(path string) (*vaultapi.Secret, error)
+	Write(path string, data map[string]interface{}) (*vaultapi.Secret, error)
+	Delete(path string) (*vaultapi.Secret, error)
+}
+
+// VaultKVSink renders templates to the VaultKVField field of a secret in one of
+// Vault's KV secrets engines. The secret is replaced on each write, so it has
+// no other fields. For version 2 of the engine, Path must be the API path of
+// the secret, including the "data/" segment, and KVv2 must be set.
+type VaultKVSink struct {
+	Logical VaultLogical
+	Path    string
+	KVv2    bool
+}
+
+// Read returns the value of the field, or nil if the secret or the field does
+// not exist.
+func (s *VaultKVSink) Read() ([]byte, error) {
+	secret, err := s.Logical.Read(s.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading vault secret")
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	data := secret.Data
+	if s.KVv2 {
+		data, _ = secret.Data["data"].(map[string]interface{})
+	}
+	raw, ok := data[VaultKVField]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("vault secret field %q is a %T, not a string",
+			VaultKVField, raw)
+	}
+	return []byte(value), nil
+}
+
+// Write replaces the secret with one that has the contents in its field.
+func (s *VaultKVSink) Write(contents []byte) error {
+	data := map[string]interface{}{VaultKVField: string(contents)}
+	if s.KVv2 {
+		data = map[string]interface{}{"data": data}
+	}
+	if _, err := s.Logical.Write(s.Path, data); err != nil {
+		return errors.Wrap(err, "failed writing vault secret")
+	}
+	return nil
+}
+
+// Remove deletes the secret. For version 2 of the engine, only the latest
+// version is deleted.
+func (s *VaultKVSink) Remove() error {
+	if _, err := s.Logical.Delete(s.Path); err != nil {
+		return errors.Wrap(err, "failed deleting vault secret")
+	}
+	return nil
+}
+
+// String returns the path of the secret, prefixed with "vault-kv:".
+func (s *VaultKVSink) String() string {
+	return "vault-kv:" + s.Path
+}
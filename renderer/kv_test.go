@@ -0,0 +1,135 @@
+package renderer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+type fakeConsulKV map[string][]byte
+
+func (kv fakeConsulKV) Get(key string, q *consulapi.QueryOptions) (*consulapi.KVPair, *consulapi.QueryMeta, error) {
+	v, ok := kv[key]
+	if !ok {
+		return nil, nil, nil
+	}
+	return &consulapi.KVPair{Key: key, Value: v}, nil, nil
+}
+
+func (kv fakeConsulKV) Put(p *consulapi.KVPair, q *consulapi.WriteOptions) (*consulapi.WriteMeta, error) {
+	kv[p.Key] = p.Value
+	return nil, nil
+}
+
+func (kv fakeConsulKV) Delete(key string, w *consulapi.WriteOptions) (*consulapi.WriteMeta, error) {
+	delete(kv, key)
+	return nil, nil
+}
+
+type fakeVaultLogical map[string]map[string]interface{}
+
+func (l fakeVaultLogical) Read(path string) (*vaultapi.Secret, error) {
+	data, ok := l[path]
+	if !ok {
+		return nil, nil
+	}
+	return &vaultapi.Secret{Data: data}, nil
+}
+
+func (l fakeVaultLogical) Write(path string, data map[string]interface{}) (*vaultapi.Secret, error) {
+	l[path] = data
+	return nil, nil
+}
+
+func (l fakeVaultLogical) Delete(path string) (*vaultapi.Secret, error) {
+	delete(l, path)
+	return nil, nil
+}
+
+func TestConsulKVSink(t *testing.T) {
+	kv := fakeConsulKV{}
+	sink := &ConsulKVSink{KV: kv, Key: "foo/bar"}
+
+	for i, contents := range []string{"first", "first", "second"} {
+		rr, err := Render(&RenderInput{
+			Contents: []byte(contents),
+			Sink:     sink,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := i != 1; rr.DidRender != expected {
+			t.Errorf("%d: expected did render to be %v", i, expected)
+		}
+	}
+
+	if string(kv["foo/bar"]) != "second" {
+		t.Errorf("\nexp: %q\nact: %q", "second", kv["foo/bar"])
+	}
+
+	if err := sink.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := kv["foo/bar"]; ok {
+		t.Error("expected key to be deleted")
+	}
+}
+
+func TestVaultKVSink(t *testing.T) {
+	cases := []struct {
+		name     string
+		kvv2     bool
+		expected map[string]interface{}
+	}{
+		{
+			"v1",
+			false,
+			map[string]interface{}{"value": "second"},
+		},
+		{
+			"v2",
+			true,
+			map[string]interface{}{
+				"data": map[string]interface{}{"value": "second"},
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			logical := fakeVaultLogical{}
+			sink := &VaultKVSink{Logical: logical, Path: "secret/foo", KVv2: tc.kvv2}
+
+			for i, contents := range []string{"first", "first", "second"} {
+				rr, err := Render(&RenderInput{
+					Contents: []byte(contents),
+					Sink:     sink,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected := i != 1; rr.DidRender != expected {
+					t.Errorf("%d: expected did render to be %v", i, expected)
+				}
+			}
+
+			if !reflect.DeepEqual(tc.expected, logical["secret/foo"]) {
+				t.Errorf("\nexp: %#v\nact: %#v", tc.expected, logical["secret/foo"])
+			}
+
+			if err := sink.Remove(); err != nil {
+				t.Fatal(err)
+			}
+			existing, err := sink.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if existing != nil {
+				t.Errorf("expected secret to be deleted, got %q", existing)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+// +build !windows
+
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// DefaultPipePerms are the default permissions for named pipes created when a
+// specific permission has not been specified.
+const DefaultPipePerms = 0600
+
+// openPipe opens the named pipe at the path for writing, creating it if it
+// does not exist. It returns an error rather than blocking if no reader has
+// the pipe open.
+func openPipe(path string, perms os.FileMode) (*os.File, error) {
+	if perms == 0 {
+		perms = DefaultPipePerms
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		if err := syscall.Mkfifo(path, uint32(perms.Perm())); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case info.Mode()&os.ModeNamedPipe == 0:
+		return nil, fmt.Errorf("%s is not a named pipe", path)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.ENXIO {
+			return nil, fmt.Errorf("no reader has %s open", path)
+		}
+		return nil, err
+	}
+	return f, nil
+}
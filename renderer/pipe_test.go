@@ -0,0 +1,102 @@
+//go:build !windows
+// +build !windows
+
+package renderer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestPipeSink(t *testing.T) {
+	t.Run("no_reader", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		sink := &PipeSink{Path: filepath.Join(outDir, "pipe")}
+		if err := sink.Write([]byte("first")); err == nil {
+			t.Fatal("expected error")
+		}
+
+		info, err := os.Stat(sink.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode()&os.ModeNamedPipe == 0 {
+			t.Errorf("expected a named pipe, got %s", info.Mode())
+		}
+		if info.Mode().Perm() != DefaultPipePerms {
+			t.Errorf("expected %s, got %s", os.FileMode(DefaultPipePerms), info.Mode().Perm())
+		}
+	})
+
+	t.Run("reader", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+
+		path := filepath.Join(outDir, "pipe")
+		if err := syscall.Mkfifo(path, 0600); err != nil {
+			t.Fatal(err)
+		}
+		reader, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+
+		sink := &PipeSink{Path: path}
+		rr, err := Render(&RenderInput{
+			Contents: []byte("first"),
+			Sink:     sink,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rr.DidRender {
+			t.Error("expected render")
+		}
+
+		buf := make([]byte, 16)
+		n, err := reader.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != "first" {
+			t.Errorf("\nexp: %q\nact: %q", "first", buf[:n])
+		}
+
+		rr, err = Render(&RenderInput{
+			Contents: []byte("first"),
+			Sink:     sink,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rr.DidRender {
+			t.Error("expected unchanged contents not to be written")
+		}
+	})
+
+	t.Run("not_a_pipe", func(t *testing.T) {
+		outFile, err := ioutil.TempFile("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		outFile.Close()
+		defer os.Remove(outFile.Name())
+
+		sink := &PipeSink{Path: outFile.Name()}
+		if err := sink.Write([]byte("first")); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
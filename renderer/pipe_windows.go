@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package renderer
+
+import (
+	"fmt"
+	"os"
+)
+
+// DefaultPipePerms are the default permissions for named pipes created when a
+// specific permission has not been specified.
+const DefaultPipePerms = 0600
+
+func openPipe(path string, perms os.FileMode) (*os.File, error) {
+	return nil, fmt.Errorf("named pipes are not supported on Windows")
+}
@@ -27,15 +27,19 @@ var (
 	ErrMissingDest = errors.New("missing destination")
 )
 
-// RenderInput is used as input to the render function.
+// RenderInput is used as input to the render function. The template is
+// rendered to Sink, or to the file at Path if Sink is nil.
 type RenderInput struct {
 	Backup         bool
 	Contents       []byte
 	CreateDestDirs bool
 	Dry            bool
+	DryDiff        bool
 	DryStream      io.Writer
+	DiffContext    int
 	Path           string
 	Perms          os.FileMode
+	Sink           Sink
 	User, Group    string
 }
 
@@ -55,37 +59,43 @@ type RenderResult struct {
 	// Contents are the actual contents of the resulting template from the render
 	// operation.
 	Contents []byte
+
+	// Previous are the contents of the destination before the render
+	// operation. It is nil if the destination did not exist.
+	Previous []byte
 }
 
-// Render atomically renders a file contents to disk, returning a result of
-// whether it would have rendered and actually did render.
+// Render renders the contents to the sink, atomically for files, returning a
+// result of whether it would have rendered and actually did render.
 func Render(i *RenderInput) (*RenderResult, error) {
-	existing, err := ioutil.ReadFile(i.Path)
-	fileExists := !os.IsNotExist(err)
-	if err != nil && fileExists {
-		return nil, errors.Wrap(err, "failed reading file")
+	sink := i.Sink
+	if sink == nil {
+		sink = &FileSink{
+			Backup:         i.Backup,
+			CreateDestDirs: i.CreateDestDirs,
+			Path:           i.Path,
+			Perms:          i.Perms,
+			User:           i.User,
+			Group:          i.Group,
+		}
 	}
 
-	uid, err := lookupUser(i.User)
+	existing, err := sink.Read()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed looking up user")
+		return nil, err
 	}
-	gid, err := lookupGroup(i.Group)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed looking up group")
-	}
-
-	var chownNeeded bool
+	exists := existing != nil
 
-	if fileExists {
-		chownNeeded, err = isChownNeeded(i.Path, uid, gid)
-		if err != nil {
-			log.Printf("[WARN] (runner) could not determine existing output file's permissions")
-			chownNeeded = true
+	// Some destinations must be rendered even if their contents are unchanged,
+	// such as a file that is owned by the wrong user or group.
+	var stale bool
+	if ss, ok := sink.(StaleSink); ok {
+		if stale, err = ss.Stale(existing); err != nil {
+			return nil, err
 		}
 	}
 
-	if bytes.Equal(existing, i.Contents) && fileExists && !chownNeeded {
+	if bytes.Equal(existing, i.Contents) && exists && !stale {
 		return &RenderResult{
 			DidRender:   false,
 			WouldRender: true,
@@ -93,22 +103,19 @@ func Render(i *RenderInput) (*RenderResult, error) {
 		}, nil
 	}
 
-	if i.Dry {
-		fmt.Fprintf(i.DryStream, "> %s\n%s", i.Path, i.Contents)
-	} else {
-		if err := AtomicWrite(i.Path, i.CreateDestDirs, i.Contents, i.Perms, i.Backup); err != nil {
-			return nil, errors.Wrap(err, "failed writing file")
-		}
-
-		if err = setFileOwnership(i.Path, uid, gid); err != nil {
-			return nil, errors.Wrap(err, "failed setting file ownership")
-		}
+	if i.Dry && i.DryDiff {
+		fmt.Fprint(i.DryStream, Diff(sink.String(), existing, i.Contents, i.DiffContext))
+	} else if i.Dry {
+		fmt.Fprintf(i.DryStream, "> %s\n%s", sink, i.Contents)
+	} else if err := sink.Write(i.Contents); err != nil {
+		return nil, err
 	}
 
 	return &RenderResult{
 		DidRender:   true,
 		WouldRender: true,
 		Contents:    i.Contents,
+		Previous:    existing,
 	}, nil
 }
 
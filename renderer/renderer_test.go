@@ -268,6 +268,10 @@ func TestRender(t *testing.T) {
 			t.Fatalf("Bad render results; would: %v, did: %v",
 				rr.WouldRender, rr.DidRender)
 		}
+		if !bytes.Equal(rr.Previous, contents) {
+			t.Errorf("Bad previous contents; expected %q, got %q",
+				contents, rr.Previous)
+		}
 	})
 	t.Run("file-no-exists", func(t *testing.T) {
 		outDir, err := ioutil.TempDir("", "")
@@ -291,6 +295,51 @@ func TestRender(t *testing.T) {
 			t.Fatalf("Bad render results; would: %v, did: %v",
 				rr.WouldRender, rr.DidRender)
 		}
+		if rr.Previous != nil {
+			t.Errorf("Bad previous contents; expected nil, got %q", rr.Previous)
+		}
+	})
+	t.Run("dry-diff", func(t *testing.T) {
+		outDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(outDir)
+		path := path.Join(outDir, "out")
+		if err := ioutil.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var out bytes.Buffer
+		rr, err := Render(&RenderInput{
+			Path:        path,
+			Contents:    []byte("one\nthree\n"),
+			Dry:         true,
+			DryDiff:     true,
+			DryStream:   &out,
+			DiffContext: 3,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rr.WouldRender || !rr.DidRender {
+			t.Fatalf("Bad render results; would: %v, did: %v",
+				rr.WouldRender, rr.DidRender)
+		}
+
+		expected := "--- " + path + "\n+++ " + path + "\n" +
+			"@@ -1,2 +1,2 @@\n one\n-two\n+three\n"
+		if out.String() != expected {
+			t.Errorf("\nexp: %q\nact: %q", expected, out.String())
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(contents) != "one\ntwo\n" {
+			t.Errorf("dry mode changed the destination: %q", contents)
+		}
 	})
 	t.Run("empty-file-no-exists", func(t *testing.T) {
 		outDir, err := ioutil.TempDir("", "")
@@ -0,0 +1,236 @@
+package renderer
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Sink is a destination that templates are rendered to.
+type Sink interface {
+	// Read returns the current contents of the destination, or nil if the
+	// destination does not exist.
+	Read() ([]byte, error)
+
+	// Write replaces the contents of the destination.
+	Write(contents []byte) error
+
+	// Remove removes the destination. It is used to restore a destination that
+	// did not exist before the template was rendered.
+	Remove() error
+
+	// String returns the destination in a human-readable form, for logs and
+	// dry mode output.
+	String() string
+}
+
+// StaleSink is implemented by sinks whose destination can need to be written
+// even when its contents are unchanged.
+type StaleSink interface {
+	Sink
+
+	// Stale returns true if the destination must be written even though it
+	// has the given contents, which are the contents last returned by Read.
+	Stale(existing []byte) (bool, error)
+}
+
+// FileSink renders templates atomically to a file on disk. It is the default
+// sink.
+type FileSink struct {
+	Backup         bool
+	CreateDestDirs bool
+	Path           string
+	Perms          os.FileMode
+	User, Group    string
+}
+
+// Read returns the contents of the file, or nil if it does not exist.
+func (s *FileSink) Read() ([]byte, error) {
+	existing, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed reading file")
+	}
+	if existing == nil {
+		existing = []byte{}
+	}
+	return existing, nil
+}
+
+// Write atomically writes the contents to the file and sets its ownership.
+func (s *FileSink) Write(contents []byte) error {
+	uid, gid, err := s.ownership()
+	if err != nil {
+		return err
+	}
+
+	if err := AtomicWrite(s.Path, s.CreateDestDirs, contents, s.Perms, s.Backup); err != nil {
+		return errors.Wrap(err, "failed writing file")
+	}
+
+	if err := setFileOwnership(s.Path, uid, gid); err != nil {
+		return errors.Wrap(err, "failed setting file ownership")
+	}
+	return nil
+}
+
+// Remove removes the file, if it exists.
+func (s *FileSink) Remove() error {
+	if err := os.Remove(s.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// String returns the path of the file.
+func (s *FileSink) String() string {
+	return s.Path
+}
+
+// ownership looks up the uid and gid the file should be owned by. They are -1
+// if the user or group is not set.
+func (s *FileSink) ownership() (int, int, error) {
+	uid, err := lookupUser(s.User)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed looking up user")
+	}
+	gid, err := lookupGroup(s.Group)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed looking up group")
+	}
+	return uid, gid, nil
+}
+
+// Stale returns true if the file exists and is not owned by the configured
+// user and group.
+func (s *FileSink) Stale(existing []byte) (bool, error) {
+	uid, gid, err := s.ownership()
+	if err != nil {
+		return false, err
+	}
+	if existing == nil {
+		return false, nil
+	}
+
+	changed, err := isChownNeeded(s.Path, uid, gid)
+	if err != nil {
+		log.Printf("[WARN] (runner) could not determine existing output file's permissions")
+		return true, nil
+	}
+	return changed, nil
+}
+
+// written remembers the contents last written to a sink that cannot be read
+// back, such as a stream.
+type written struct {
+	sync.Mutex
+	contents []byte
+}
+
+// read returns the contents last written, or nil if nothing was written.
+func (w *written) read() []byte {
+	w.Lock()
+	defer w.Unlock()
+	return w.contents
+}
+
+// set remembers the contents as the contents last written.
+func (w *written) set(contents []byte) {
+	w.Lock()
+	defer w.Unlock()
+	w.contents = append([]byte{}, contents...)
+}
+
+// forget forgets the contents last written, as if nothing was written.
+func (w *written) forget() {
+	w.Lock()
+	defer w.Unlock()
+	w.contents = nil
+}
+
+// StdoutSink renders templates to a stream, usually standard out. Since the
+// stream cannot be read back, the contents are only written when they differ
+// from the contents last written.
+type StdoutSink struct {
+	Out io.Writer
+
+	last written
+}
+
+// Read returns the contents last written, or nil if nothing was written.
+func (s *StdoutSink) Read() ([]byte, error) {
+	return s.last.read(), nil
+}
+
+// Write writes the contents to the stream.
+func (s *StdoutSink) Write(contents []byte) error {
+	if _, err := s.Out.Write(contents); err != nil {
+		return errors.Wrap(err, "failed writing to stdout")
+	}
+	s.last.set(contents)
+	return nil
+}
+
+// Remove forgets the contents last written. Contents written to the stream
+// cannot be removed.
+func (s *StdoutSink) Remove() error {
+	s.last.forget()
+	return nil
+}
+
+// String returns "stdout".
+func (s *StdoutSink) String() string {
+	return "stdout"
+}
+
+// PipeSink renders templates to a named pipe, creating the pipe if it does not
+// exist. A reader must have the pipe open when the template is rendered, since
+// the contents are not buffered. Like StdoutSink, the contents are only written
+// when they differ from the contents last written.
+type PipeSink struct {
+	Path  string
+	Perms os.FileMode
+
+	last written
+}
+
+// Read returns the contents last written, or nil if nothing was written.
+func (s *PipeSink) Read() ([]byte, error) {
+	return s.last.read(), nil
+}
+
+// Write writes the contents to the named pipe.
+func (s *PipeSink) Write(contents []byte) error {
+	f, err := openPipe(s.Path, s.Perms)
+	if err != nil {
+		return errors.Wrap(err, "failed opening named pipe")
+	}
+	defer f.Close()
+
+	if _, err := f.Write(contents); err != nil {
+		return errors.Wrap(err, "failed writing named pipe")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "failed writing named pipe")
+	}
+	s.last.set(contents)
+	return nil
+}
+
+// Remove forgets the contents last written. Contents written to the pipe
+// cannot be removed.
+func (s *PipeSink) Remove() error {
+	s.last.forget()
+	return nil
+}
+
+// String returns the path of the named pipe.
+func (s *PipeSink) String() string {
+	return s.Path
+}
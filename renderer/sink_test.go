@@ -0,0 +1,107 @@
+package renderer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSink(t *testing.T) {
+	outDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	sink := &FileSink{Path: filepath.Join(outDir, "out"), Perms: 0600}
+
+	existing, err := sink.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing != nil {
+		t.Fatalf("expected nil contents, got %q", existing)
+	}
+
+	if err := sink.Write(nil); err != nil {
+		t.Fatal(err)
+	}
+	existing, err = sink.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing == nil || len(existing) != 0 {
+		t.Fatalf("expected empty contents, got %#v", existing)
+	}
+
+	if err := sink.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(sink.Path); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed: %v", sink.Path, err)
+	}
+	if err := sink.Remove(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStdoutSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := &StdoutSink{Out: &out}
+
+	for _, contents := range []string{"first", "first", "second"} {
+		if _, err := Render(&RenderInput{
+			Contents: []byte(contents),
+			Sink:     sink,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := "firstsecond"
+	if out.String() != expected {
+		t.Errorf("\nexp: %q\nact: %q", expected, out.String())
+	}
+
+	if err := sink.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	rr, err := Render(&RenderInput{
+		Contents: []byte("second"),
+		Sink:     sink,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rr.DidRender || rr.Previous != nil {
+		t.Errorf("expected render after remove; did: %v, previous: %q",
+			rr.DidRender, rr.Previous)
+	}
+}
+
+func TestRender_Sink(t *testing.T) {
+	t.Run("dry", func(t *testing.T) {
+		var out, dry bytes.Buffer
+		rr, err := Render(&RenderInput{
+			Contents:  []byte("first"),
+			Dry:       true,
+			DryStream: &dry,
+			Sink:      &StdoutSink{Out: &out},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rr.DidRender {
+			t.Error("expected dry render")
+		}
+		if out.Len() != 0 {
+			t.Errorf("expected nothing written to the sink, got %q", out.String())
+		}
+		expected := "> stdout\nfirst"
+		if dry.String() != expected {
+			t.Errorf("\nexp: %q\nact: %q", expected, dry.String())
+		}
+	})
+}
@@ -1,11 +1,23 @@
 package template
 
 import (
+	"sort"
+	"strings"
 	"sync"
 
 	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul/api"
 )
 
+// Redacted replaces sensitive values in output that is not written to a
+// template's destination.
+const Redacted = "<redacted>"
+
+// sensitiveMinLength is the minimum length of a sensitive value to be
+// redacted. Shorter values, such as "1" or "true", would mask unrelated parts
+// of the output.
+const sensitiveMinLength = 6
+
 // Brain is what Template uses to determine the values that are
 // available for template parsing.
 type Brain struct {
@@ -18,6 +30,15 @@ type Brain struct {
 	// receivedData is an internal tracker of which dependencies have stored data
 	// in the brain.
 	receivedData map[string]struct{}
+
+	// sensitive is the map of dependencies whose data is sensitive to the
+	// values in that data that must be masked.
+	sensitive map[string][]string
+
+	// redactor replaces all the values in sensitive with Redacted. It is
+	// rebuilt when sensitive changes, so redacting does not sort the values on
+	// every call.
+	redactor *strings.Replacer
 }
 
 // NewBrain creates a new Brain with empty values for each
@@ -26,6 +47,7 @@ func NewBrain() *Brain {
 	return &Brain{
 		data:         make(map[string]interface{}),
 		receivedData: make(map[string]struct{}),
+		sensitive:    make(map[string][]string),
 	}
 }
 
@@ -33,11 +55,7 @@ func NewBrain() *Brain {
 // dep. This function converts the given data to a proper type and stores
 // it interally.
 func (b *Brain) Remember(d dep.Dependency, data interface{}) {
-	b.Lock()
-	defer b.Unlock()
-
-	b.data[d.String()] = data
-	b.receivedData[d.String()] = struct{}{}
+	b.ForceSet(d.String(), data)
 }
 
 // Recall gets the current value for the given dependency in the Brain.
@@ -61,6 +79,14 @@ func (b *Brain) ForceSet(hashCode string, data interface{}) {
 
 	b.data[hashCode] = data
 	b.receivedData[hashCode] = struct{}{}
+
+	if values, ok := sensitiveValues(data); ok {
+		b.sensitive[hashCode] = values
+		b.redactor = nil
+	} else if _, ok := b.sensitive[hashCode]; ok {
+		delete(b.sensitive, hashCode)
+		b.redactor = nil
+	}
 }
 
 // Forget accepts a dependency and removes all associated data with this
@@ -71,4 +97,127 @@ func (b *Brain) Forget(d dep.Dependency) {
 
 	delete(b.data, d.String())
 	delete(b.receivedData, d.String())
+	if _, ok := b.sensitive[d.String()]; ok {
+		delete(b.sensitive, d.String())
+		b.redactor = nil
+	}
+}
+
+// Sensitive returns true if the data of the dependency is sensitive: it is a
+// Vault secret or a Connect leaf certificate.
+func (b *Brain) Sensitive(d dep.Dependency) bool {
+	b.RLock()
+	defer b.RUnlock()
+
+	_, ok := b.sensitive[d.String()]
+	return ok
+}
+
+// Redact returns the string with the values in sensitive data replaced by
+// Redacted. It is used for output that is not written to a template's
+// destination, such as logs, the dry stream and diffs.
+func (b *Brain) Redact(s string) string {
+	if b == nil {
+		return s
+	}
+	return b.getRedactor().Replace(s)
+}
+
+// getRedactor returns the replacer of the sensitive values, building it if the
+// sensitive values changed since it was last built.
+func (b *Brain) getRedactor() *strings.Replacer {
+	b.RLock()
+	redactor := b.redactor
+	b.RUnlock()
+	if redactor != nil {
+		return redactor
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	if b.redactor != nil {
+		return b.redactor
+	}
+
+	var values []string
+	for _, v := range b.sensitive {
+		values = append(values, v...)
+	}
+
+	// Replace the longest values first, so that values containing other
+	// values are fully masked. The replacer prefers earlier pairs when several
+	// values match at the same position.
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	pairs := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		pairs = append(pairs, v, Redacted)
+	}
+	b.redactor = strings.NewReplacer(pairs...)
+	return b.redactor
+}
+
+// sensitiveValues returns the values to mask in the data, and true if the data
+// is sensitive.
+func sensitiveValues(data interface{}) ([]string, bool) {
+	var values []string
+	switch v := data.(type) {
+	case *dep.Secret:
+		if v == nil {
+			return nil, true
+		}
+		values = appendSensitive(values, secretData(v))
+		if v.Auth != nil {
+			values = appendSensitive(values, v.Auth.ClientToken)
+		}
+	case *api.LeafCert:
+		if v == nil {
+			return nil, true
+		}
+		values = appendSensitive(values, v.PrivateKeyPEM)
+	default:
+		return nil, false
+	}
+	return values, true
+}
+
+// secretData returns the data of the secret without its KV v2 metadata, such as
+// its version and creation time, which is not sensitive.
+func secretData(s *dep.Secret) interface{} {
+	if _, ok := s.Data["metadata"].(map[string]interface{}); ok {
+		if data, ok := s.Data["data"]; ok {
+			return data
+		}
+	}
+	return s.Data
+}
+
+// appendSensitive appends the string values in the data that are at least
+// sensitiveMinLength long to the values. Each long enough line of a multi-line
+// value is also appended, since output such as diffs splits values over lines.
+func appendSensitive(values []string, data interface{}) []string {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for _, e := range v {
+			values = appendSensitive(values, e)
+		}
+	case []interface{}:
+		for _, e := range v {
+			values = appendSensitive(values, e)
+		}
+	case string:
+		if len(v) < sensitiveMinLength {
+			break
+		}
+		values = append(values, v)
+		if strings.Contains(v, "\n") {
+			for _, l := range strings.Split(v, "\n") {
+				if l = strings.TrimSpace(l); len(l) >= sensitiveMinLength {
+					values = append(values, l)
+				}
+			}
+		}
+	}
+	return values
 }
@@ -1,10 +1,12 @@
 package template
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
 	dep "github.com/hashicorp/consul-template/dependency"
+	"github.com/hashicorp/consul/api"
 )
 
 func TestNewBrain(t *testing.T) {
@@ -97,3 +99,138 @@ func TestForget(t *testing.T) {
 		t.Errorf("expected %#v to not be forgotten", d)
 	}
 }
+
+func TestSensitive(t *testing.T) {
+	b := NewBrain()
+
+	secret, err := dep.NewVaultReadQuery("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := dep.NewCatalogNodesQuery("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Remember(secret, &dep.Secret{})
+	b.Remember(nodes, []*dep.Node{})
+
+	if !b.Sensitive(secret) {
+		t.Errorf("expected %s to be sensitive", secret)
+	}
+	if b.Sensitive(nodes) {
+		t.Errorf("expected %s to not be sensitive", nodes)
+	}
+
+	b.Forget(secret)
+	if b.Sensitive(secret) {
+		t.Errorf("expected %s to be forgotten", secret)
+	}
+}
+
+func TestRedact(t *testing.T) {
+
+	secret, err := dep.NewVaultReadQuery("secret/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := dep.NewConnectLeafQuery("web")
+	kvv2, err := dep.NewVaultReadQuery("kv/data/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := dep.NewKVGetQuery("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBrain()
+	b.Remember(secret, &dep.Secret{
+		Data: map[string]interface{}{
+			"password": "hunter2",
+			"nested":   map[string]interface{}{"pin": 1234, "user": "admin1"},
+			"enabled":  true,
+			"short":    "abc",
+		},
+	})
+	b.Remember(kvv2, &dep.Secret{
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"token": "s3cr3t-token"},
+			"metadata": map[string]interface{}{
+				"version":      1,
+				"created_time": "2018-03-22T02:24:06.945319214Z",
+			},
+		},
+	})
+	b.Remember(leaf, &api.LeafCert{
+		PrivateKeyPEM: "-----BEGIN KEY-----\nabcdef\nghijkl\nmn\n-----END KEY-----\n",
+	})
+	b.Remember(kv, "hunter3")
+
+	cases := []struct {
+		name     string
+		in       string
+		expected string
+	}{
+		{
+			"secret",
+			"password = hunter2",
+			"password = <redacted>",
+		},
+		{
+			"nested",
+			"user = admin1",
+			"user = <redacted>",
+		},
+		{
+			"number",
+			"pin = 1234, version = 1",
+			"pin = 1234, version = 1",
+		},
+		{
+			"short",
+			"short = abc",
+			"short = abc",
+		},
+		{
+			"kv_v2",
+			"token = s3cr3t-token",
+			"token = <redacted>",
+		},
+		{
+			"kv_v2_metadata",
+			"created = 2018-03-22T02:24:06.945319214Z",
+			"created = 2018-03-22T02:24:06.945319214Z",
+		},
+		{
+			"multi_line",
+			"-----BEGIN KEY-----\nabcdef\nghijkl\nmn\n-----END KEY-----\n",
+			"<redacted>",
+		},
+		{
+			"multi_line_diff",
+			"+abcdef\n+ghijkl\n+mn\n",
+			"+<redacted>\n+<redacted>\n+mn\n",
+		},
+		{
+			"not_sensitive",
+			"value = hunter3, enabled = true",
+			"value = hunter3, enabled = true",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			if act := b.Redact(tc.in); act != tc.expected {
+				t.Errorf("\nexp: %q\nact: %q", tc.expected, act)
+			}
+		})
+	}
+
+	t.Run("forget", func(t *testing.T) {
+		b.Forget(secret)
+		if act := b.Redact("password = hunter2"); act != "password = hunter2" {
+			t.Errorf("expected forgotten value to not be redacted, got %q", act)
+		}
+	})
+}
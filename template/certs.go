@@ -0,0 +1,235 @@
+package template
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Certificate is the information of a PEM-encoded X.509 certificate that is
+// returned by the parseCert and parseCertChain functions.
+type Certificate struct {
+	// Subject and Issuer are the distinguished names of the certificate and
+	// its issuer, such as "CN=web.service.consul,O=HashiCorp".
+	Subject string
+	Issuer  string
+
+	// CommonName and IssuerCommonName are the common names of the subject and
+	// the issuer.
+	CommonName       string
+	IssuerCommonName string
+
+	// DNSNames, IPAddresses, EmailAddresses and URIs are the subject
+	// alternative names.
+	DNSNames       []string
+	IPAddresses    []string
+	EmailAddresses []string
+	URIs           []string
+
+	// SerialNumber is the serial number as colon-separated hex bytes, the
+	// format Vault uses.
+	SerialNumber string
+
+	// NotBefore and NotAfter are the validity period.
+	NotBefore time.Time
+	NotAfter  time.Time
+
+	// IsCA is true if the certificate may sign other certificates.
+	IsCA bool
+
+	// Fingerprint is the hex-encoded SHA-256 hash of the certificate.
+	Fingerprint string
+
+	// PublicKeyFingerprint is the hex-encoded SHA-256 hash of the public key,
+	// which is the same as the one of the matching private key.
+	PublicKeyFingerprint string
+
+	// PEM is the PEM encoding of the certificate alone.
+	PEM string
+}
+
+// PrivateKey is the information of a PEM-encoded private key that is returned
+// by the parsePrivateKey function.
+type PrivateKey struct {
+	// Type is the type of the key: "RSA", "ECDSA" or "Ed25519".
+	Type string
+
+	// Bits is the size of the key in bits.
+	Bits int
+
+	// PublicKeyFingerprint is the hex-encoded SHA-256 hash of the public key,
+	// which is the same as the one of the matching certificate.
+	PublicKeyFingerprint string
+}
+
+// parseCert parses the first PEM-encoded certificate in the given string. An
+// empty string returns nil, since data is empty until it is fetched.
+func parseCert(s string) (*Certificate, error) {
+	certs, err := parseCertChain(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "parseCert")
+	}
+	if len(certs) == 0 {
+		if strings.TrimSpace(s) == "" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("parseCert: no certificate found")
+	}
+	return certs[0], nil
+}
+
+// parseCertChain parses all PEM-encoded certificates in the given string, in
+// order. Other PEM blocks, such as private keys, are skipped.
+func parseCertChain(s string) ([]*Certificate, error) {
+	result := []*Certificate{}
+
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "parseCertChain")
+		}
+
+		c, err := newCertificate(cert)
+		if err != nil {
+			return nil, errors.Wrap(err, "parseCertChain")
+		}
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+// newCertificate returns the information of the given certificate.
+func newCertificate(cert *x509.Certificate) (*Certificate, error) {
+	pubFingerprint, err := publicKeyFingerprint(cert.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Certificate{
+		Subject:              cert.Subject.String(),
+		Issuer:               cert.Issuer.String(),
+		CommonName:           cert.Subject.CommonName,
+		IssuerCommonName:     cert.Issuer.CommonName,
+		DNSNames:             append([]string{}, cert.DNSNames...),
+		IPAddresses:          make([]string, len(cert.IPAddresses)),
+		EmailAddresses:       append([]string{}, cert.EmailAddresses...),
+		URIs:                 make([]string, len(cert.URIs)),
+		NotBefore:            cert.NotBefore,
+		NotAfter:             cert.NotAfter,
+		IsCA:                 cert.IsCA,
+		PublicKeyFingerprint: pubFingerprint,
+		PEM: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: cert.Raw,
+		})),
+	}
+
+	for i, ip := range cert.IPAddresses {
+		c.IPAddresses[i] = ip.String()
+	}
+	for i, uri := range cert.URIs {
+		c.URIs[i] = uri.String()
+	}
+
+	serial := cert.SerialNumber.Bytes()
+	parts := make([]string, len(serial))
+	for i, b := range serial {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	c.SerialNumber = strings.Join(parts, ":")
+
+	sum := sha256.Sum256(cert.Raw)
+	c.Fingerprint = hex.EncodeToString(sum[:])
+
+	return c, nil
+}
+
+// parsePrivateKey parses the first PEM-encoded private key in the given
+// string, which may be a PKCS #1, PKCS #8 or EC key. An empty string returns
+// nil, since data is empty until it is fetched.
+func parsePrivateKey(s string) (*PrivateKey, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("parsePrivateKey: no private key found")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+
+		key, err := parsePrivateKeyBlock(block)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsePrivateKey")
+		}
+
+		var k PrivateKey
+		var pub crypto.PublicKey
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			k.Type, k.Bits, pub = "RSA", key.N.BitLen(), key.Public()
+		case *ecdsa.PrivateKey:
+			k.Type, k.Bits, pub = "ECDSA", key.Curve.Params().BitSize, key.Public()
+		case ed25519.PrivateKey:
+			k.Type, k.Bits, pub = "Ed25519", 256, key.Public()
+		default:
+			return nil, fmt.Errorf("parsePrivateKey: unsupported key type %T", key)
+		}
+
+		if k.PublicKeyFingerprint, err = publicKeyFingerprint(pub); err != nil {
+			return nil, errors.Wrap(err, "parsePrivateKey")
+		}
+		return &k, nil
+	}
+}
+
+// parsePrivateKeyBlock parses the private key of the given PEM block.
+func parsePrivateKeyBlock(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// publicKeyFingerprint returns the hex-encoded SHA-256 hash of the PKIX
+// encoding of the given public key.
+func publicKeyFingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
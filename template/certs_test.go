@@ -0,0 +1,290 @@
+package template
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCert creates a certificate for the given key, signed by the parent
+// certificate and key, or self-signed if parent is nil.
+func testCert(t *testing.T, tmpl *x509.Certificate, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, string) {
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// testChain returns a PEM-encoded leaf certificate, the CA that signed it, and
+// the private key of the leaf.
+func testChain(t *testing.T) (leafPEM, caPEM string, leafKey *ecdsa.PrivateKey) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, caPEM := testCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, caKey, nil, nil)
+
+	leafKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, leafPEM = testCert(t, &x509.Certificate{
+		SerialNumber:   big.NewInt(0x1a2b3c),
+		Subject:        pkix.Name{CommonName: "web.service.consul", Organization: []string{"HashiCorp"}},
+		NotBefore:      time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:       time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		DNSNames:       []string{"web.service.consul", "web.example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		EmailAddresses: []string{"ops@example.com"},
+		URIs:           []*url.URL{{Scheme: "spiffe", Host: "example.consul", Path: "/svc/web"}},
+	}, leafKey, ca, caKey)
+
+	return leafPEM, caPEM, leafKey
+}
+
+func Test_parseCert(t *testing.T) {
+	leafPEM, caPEM, _ := testChain(t)
+
+	t.Run("leaf", func(t *testing.T) {
+		c, err := parseCert(leafPEM + caPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "CN=web.service.consul,O=HashiCorp", c.Subject)
+		assert.Equal(t, "CN=Test CA", c.Issuer)
+		assert.Equal(t, "web.service.consul", c.CommonName)
+		assert.Equal(t, "Test CA", c.IssuerCommonName)
+		assert.Equal(t, []string{"web.service.consul", "web.example.com"}, c.DNSNames)
+		assert.Equal(t, []string{"10.0.0.1"}, c.IPAddresses)
+		assert.Equal(t, []string{"ops@example.com"}, c.EmailAddresses)
+		assert.Equal(t, []string{"spiffe://example.consul/svc/web"}, c.URIs)
+		assert.Equal(t, "1a:2b:3c", c.SerialNumber)
+		assert.Equal(t, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), c.NotAfter)
+		assert.False(t, c.IsCA)
+		assert.Len(t, c.Fingerprint, 64)
+		assert.Equal(t, leafPEM, c.PEM)
+	})
+
+	cases := []struct {
+		name string
+		i    string
+		err  bool
+	}{
+		{
+			"empty",
+			"",
+			false,
+		},
+		{
+			"no_certificate",
+			"not a certificate",
+			true,
+		},
+		{
+			"invalid_certificate",
+			"-----BEGIN CERTIFICATE-----\nYWJj\n-----END CERTIFICATE-----\n",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			c, err := parseCert(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if c != nil {
+				t.Errorf("expected no certificate, got %#v", c)
+			}
+		})
+	}
+}
+
+func Test_parseCertChain(t *testing.T) {
+	leafPEM, caPEM, leafKey := testChain(t)
+
+	der, err := x509.MarshalECPrivateKey(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+
+	certs, err := parseCertChain(keyPEM + leafPEM + caPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("expected 2 certificates, got %d", len(certs))
+	}
+	assert.Equal(t, "web.service.consul", certs[0].CommonName)
+	assert.Equal(t, "Test CA", certs[1].CommonName)
+	assert.True(t, certs[1].IsCA)
+	assert.Equal(t, caPEM, certs[1].PEM)
+
+	empty, err := parseCertChain("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []*Certificate{}, empty)
+}
+
+func Test_parsePrivateKey(t *testing.T) {
+	leafPEM, _, leafKey := testChain(t)
+
+	leaf, err := parseCert(leafPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecDER, err := x509.MarshalECPrivateKey(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8DER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(typ string, der []byte) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+	}
+
+	cases := []struct {
+		name string
+		i    string
+		typ  string
+		bits int
+		err  bool
+	}{
+		{
+			"ec",
+			encode("EC PRIVATE KEY", ecDER),
+			"ECDSA",
+			256,
+			false,
+		},
+		{
+			"pkcs1",
+			encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+			"RSA",
+			1024,
+			false,
+		},
+		{
+			"pkcs8",
+			encode("PRIVATE KEY", pkcs8DER),
+			"Ed25519",
+			256,
+			false,
+		},
+		{
+			"after_certificate",
+			leafPEM + encode("EC PRIVATE KEY", ecDER),
+			"ECDSA",
+			256,
+			false,
+		},
+		{
+			"empty",
+			"",
+			"",
+			0,
+			false,
+		},
+		{
+			"no_key",
+			leafPEM,
+			"",
+			0,
+			true,
+		},
+		{
+			"encrypted",
+			encode("ENCRYPTED PRIVATE KEY", []byte("abc")),
+			"",
+			0,
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			k, err := parsePrivateKey(tc.i)
+			if (err != nil) != tc.err {
+				t.Fatal(err)
+			}
+			if tc.typ == "" {
+				if k != nil {
+					t.Errorf("expected no key, got %#v", k)
+				}
+				return
+			}
+			assert.Equal(t, tc.typ, k.Type)
+			assert.Equal(t, tc.bits, k.Bits)
+			if tc.typ == "ECDSA" {
+				assert.Equal(t, leaf.PublicKeyFingerprint, k.PublicKeyFingerprint)
+			} else {
+				assert.NotEqual(t, leaf.PublicKeyFingerprint, k.PublicKeyFingerprint)
+			}
+		})
+	}
+}
+
+func TestTemplate_Execute_certs(t *testing.T) {
+	leafPEM, caPEM, _ := testChain(t)
+
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: fmt.Sprintf(
+			`{{ with parseCert %q }}{{ .CommonName }} {{ .NotAfter.Format "2006-01-02" }}{{ end }}`+
+				`{{ range parseCertChain %q }} {{ .CommonName }}{{ end }}`,
+			leafPEM, leafPEM+caPEM),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := tpl.Execute(&ExecuteInput{Brain: NewBrain()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "web.service.consul 2021-06-01 web.service.consul Test CA", string(a.Output))
+}
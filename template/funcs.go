@@ -159,6 +159,83 @@ func fileFunc(b *Brain, used, missing *dep.Set, sandboxPath string) func(string)
 	}
 }
 
+// envFileFunc returns or accumulates env file dependencies.
+func envFileFunc(b *Brain, used, missing *dep.Set, sandboxPath string) func(string) (map[string]string, error) {
+	return func(s string) (map[string]string, error) {
+		result := map[string]string{}
+
+		if len(s) == 0 {
+			return result, nil
+		}
+		err := pathInSandbox(sandboxPath, s)
+		if err != nil {
+			return result, err
+		}
+		d, err := dep.NewEnvFileQuery(s)
+		if err != nil {
+			return result, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(map[string]string), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// filesFunc returns or accumulates files dependencies for a glob pattern.
+func filesFunc(b *Brain, used, missing *dep.Set, sandboxPath string) func(string) ([]*dep.File, error) {
+	return func(s string) ([]*dep.File, error) {
+		if len(s) == 0 {
+			return nil, nil
+		}
+		d, err := dep.NewFilesQuery(s)
+		if err != nil {
+			return nil, err
+		}
+		return recallFiles(b, used, missing, sandboxPath, s, d)
+	}
+}
+
+// dirFunc returns or accumulates files dependencies for a directory.
+func dirFunc(b *Brain, used, missing *dep.Set, sandboxPath string) func(string) ([]*dep.File, error) {
+	return func(s string) ([]*dep.File, error) {
+		if len(s) == 0 {
+			return nil, nil
+		}
+		d, err := dep.NewDirQuery(s)
+		if err != nil {
+			return nil, err
+		}
+		return recallFiles(b, used, missing, sandboxPath, s, d)
+	}
+}
+
+// recallFiles returns the files of the dependency, whose pattern must be within
+// the sandbox path. The dependency checks each match against the sandbox path
+// when it lists the files.
+func recallFiles(b *Brain, used, missing *dep.Set, sandboxPath, pattern string, d *dep.FilesQuery) ([]*dep.File, error) {
+	if err := patternInSandbox(sandboxPath, pattern); err != nil {
+		return nil, err
+	}
+	d.SetSandboxPath(sandboxPath)
+
+	used.Add(d)
+
+	if value, ok := b.Recall(d); ok {
+		return value.([]*dep.File), nil
+	}
+
+	missing.Add(d)
+
+	return nil, nil
+}
+
 // keyFunc returns or accumulates key dependencies.
 func keyFunc(b *Brain, used, missing *dep.Set) func(string) (string, error) {
 	return func(s string) (string, error) {
@@ -239,6 +316,36 @@ func keyWithDefaultFunc(b *Brain, used, missing *dep.Set) func(string, string) (
 	}
 }
 
+// keyPutIfAbsentFunc returns or accumulates key dependencies that write the
+// given value to Consul if the key does not already exist. Once written, the
+// key is watched like any other key and whichever value is stored in Consul is
+// returned.
+func keyPutIfAbsentFunc(b *Brain, used, missing *dep.Set) func(string, string) (string, error) {
+	return func(s, v string) (string, error) {
+		if len(s) == 0 {
+			return "", nil
+		}
+
+		d, err := dep.NewKVPutIfAbsentQuery(s, v)
+		if err != nil {
+			return "", err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			if value == nil {
+				return "", nil
+			}
+			return value.(string), nil
+		}
+
+		missing.Add(d)
+
+		return "", nil
+	}
+}
+
 func safeLsFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.KeyPair, error) {
 	// call lsFunc but explicitly mark that empty data set returned on monitored KV prefix is NOT safe
 	return lsFunc(b, used, missing, false)
@@ -607,6 +714,96 @@ func treeFunc(b *Brain, used, missing *dep.Set, emptyIsSafe bool) func(string) (
 	}
 }
 
+// dnsStringsFunc returns or accumulates the A, AAAA or TXT records of a name,
+// which are strings.
+func dnsStringsFunc(b *Brain, used, missing *dep.Set, newQuery func(string) (*dep.DNSQuery, error)) func(string) ([]string, error) {
+	return func(s string) ([]string, error) {
+		result := []string{}
+
+		d, err := newQuery(s)
+		if err != nil {
+			return result, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]string), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// dnsSRVFunc returns or accumulates the SRV records of a name.
+func dnsSRVFunc(b *Brain, used, missing *dep.Set) func(string) ([]*dep.DNSSRV, error) {
+	return func(s string) ([]*dep.DNSSRV, error) {
+		result := []*dep.DNSSRV{}
+
+		d, err := dep.NewDNSSRVQuery(s)
+		if err != nil {
+			return result, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.([]*dep.DNSSRV), nil
+		}
+
+		missing.Add(d)
+
+		return result, nil
+	}
+}
+
+// httpFunc returns or accumulates the response of an HTTP endpoint. Until
+// there is a response, it is empty in the format of the response.
+func httpFunc(b *Brain, used, missing *dep.Set) func(string, ...string) (interface{}, error) {
+	return func(s string, opts ...string) (interface{}, error) {
+		d, err := dep.NewHTTPQuery(s, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value, nil
+		}
+
+		missing.Add(d)
+
+		if d.Format() == dep.HTTPFormatText {
+			return "", nil
+		}
+		return map[string]interface{}{}, nil
+	}
+}
+
+// timerFunc returns or accumulates timer dependencies. It returns the current
+// time, which is updated on every tick of the interval or cron expression.
+func timerFunc(b *Brain, used, missing *dep.Set) func(string) (time.Time, error) {
+	return func(s string) (time.Time, error) {
+		d, err := dep.NewTimerQuery(s)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(time.Time), nil
+		}
+
+		missing.Add(d)
+
+		return time.Time{}, nil
+	}
+}
+
 // base64Decode decodes the given string as a base64 string, returning an error
 // if it fails.
 func base64Decode(s string) (string, error) {
@@ -968,6 +1165,24 @@ func parseBool(s string) (bool, error) {
 	return result, nil
 }
 
+// parseTime parses a string into a time with the given layout. The layout is
+// either a Go time layout or "unix" for a UNIX timestamp.
+func parseTime(layout, s string) (time.Time, error) {
+	if layout == "unix" {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, errors.Wrap(err, "parseTime")
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+
+	result, err := time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "parseTime")
+	}
+	return result, nil
+}
+
 // parseFloat parses a string into a base 10 float
 func parseFloat(s string) (float64, error) {
 	if s == "" {
@@ -1033,6 +1248,60 @@ func parseYAML(s string) (interface{}, error) {
 	return data, nil
 }
 
+// pluginFunc returns or accumulates the results of calls to the long-running
+// plugins, which are watched like other dependencies. Names that are not
+// long-running plugins are executed as commands.
+func pluginFunc(b *Brain, used, missing *dep.Set, plugins []string) func(string, ...string) (string, error) {
+	return func(name string, args ...string) (string, error) {
+		var declared bool
+		for _, p := range plugins {
+			if p == name {
+				declared = true
+				break
+			}
+		}
+		if !declared {
+			return plugin(name, args...)
+		}
+
+		d, err := dep.NewPluginQuery(name, args)
+		if err != nil {
+			return "", err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value.(string), nil
+		}
+
+		missing.Add(d)
+
+		return "", nil
+	}
+}
+
+// pluginFunctionFunc returns or accumulates the data of a template function
+// provided by a long-running plugin.
+func pluginFunctionFunc(b *Brain, used, missing *dep.Set, plugin, function string) func(...string) (interface{}, error) {
+	return func(args ...string) (interface{}, error) {
+		d, err := dep.NewPluginFunctionQuery(plugin, function, args)
+		if err != nil {
+			return nil, err
+		}
+
+		used.Add(d)
+
+		if value, ok := b.Recall(d); ok {
+			return value, nil
+		}
+
+		missing.Add(d)
+
+		return nil, nil
+	}
+}
+
 // plugin executes a subprocess as the given command string. It is assumed the
 // resulting command returns JSON which is then parsed and returned as the
 // value for use in the template.
@@ -1581,13 +1850,36 @@ func pathInSandbox(sandbox, path string) error {
 		if err != nil {
 			return err
 		}
-		if strings.HasPrefix(s, "..") {
+		if outsideSandbox(s) {
 			return fmt.Errorf("'%s' is outside of sandbox", path)
 		}
 	}
 	return nil
 }
 
+// patternInSandbox checks that a glob pattern cannot match paths outside of
+// the sandbox. Since the matches may not exist yet, the pattern is checked
+// lexically; the files dependency checks each match once it is found.
+func patternInSandbox(sandbox, pattern string) error {
+	if sandbox != "" {
+		s, err := filepath.Rel(sandbox, filepath.Clean(pattern))
+		if err != nil {
+			return err
+		}
+		if outsideSandbox(s) {
+			return fmt.Errorf("'%s' is outside of sandbox", pattern)
+		}
+	}
+	return nil
+}
+
+// outsideSandbox returns true if the path relative to the sandbox leaves it.
+// Names that merely start with "..", like the "..data" directory of
+// Kubernetes volumes, are within the sandbox.
+func outsideSandbox(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // sockaddr wraps go-sockaddr templating
 func sockaddr(args ...string) (string, error) {
 	t := fmt.Sprintf("{{ %s }}", strings.Join(args, " "))
@@ -1734,12 +2026,20 @@ func spewSprintf(format string, args ...interface{}) (string, error) {
 	return spewLib.Sprintf(format, args...), nil
 }
 
-func spewDump(args ...interface{}) (string, error) {
-	spewLib.Dump(args...)
-	return "", nil
+// spewDumpFunc returns a function that dumps the arguments to stdout, with
+// sensitive values in the brain redacted.
+func spewDumpFunc(b *Brain) func(...interface{}) (string, error) {
+	return func(args ...interface{}) (string, error) {
+		fmt.Print(b.Redact(spewLib.Sdump(args...)))
+		return "", nil
+	}
 }
 
-func spewPrintf(format string, args ...interface{}) (string, error) {
-	spewLib.Printf(format, args...)
-	return "", nil
+// spewPrintfFunc returns a function that prints the formatted arguments to
+// stdout, with sensitive values in the brain redacted.
+func spewPrintfFunc(b *Brain) func(string, ...interface{}) (string, error) {
+	return func(format string, args ...interface{}) (string, error) {
+		fmt.Print(b.Redact(spewLib.Sprintf(format, args...)))
+		return "", nil
+	}
 }
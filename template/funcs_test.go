@@ -54,6 +54,12 @@ func TestFileSandbox(t *testing.T) {
 			filepath.Join(sandboxDir, "path/to/ok-symlink"),
 			nil,
 		},
+		{
+			"symlink_to_dotdot_name_in_sandbox",
+			sandboxDir,
+			filepath.Join(sandboxDir, "path/to/data-symlink"),
+			nil,
+		},
 		{
 			"relative_path_escaping_sandbox",
 			sandboxDir,
@@ -84,6 +90,55 @@ func TestFileSandbox(t *testing.T) {
 	}
 }
 
+func TestPatternSandbox(t *testing.T) {
+	cases := []struct {
+		name    string
+		sandbox string
+		pattern string
+		err     bool
+	}{
+		{
+			"no_sandbox",
+			"",
+			"/path/to/*.json",
+			false,
+		},
+		{
+			"in_sandbox",
+			"/sandbox",
+			"/sandbox/conf.d/*.json",
+			false,
+		},
+		{
+			"dotdot_name_in_sandbox",
+			"/sandbox",
+			"/sandbox/..data/*",
+			false,
+		},
+		{
+			"escaping_sandbox",
+			"/sandbox",
+			"/sandbox/conf.d/../../*",
+			true,
+		},
+		{
+			"outside_sandbox",
+			"/sandbox",
+			"/etc/*",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d_%s", i, tc.name), func(t *testing.T) {
+			err := patternInSandbox(tc.sandbox, tc.pattern)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %t, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
 func Test_byMeta(t *testing.T) {
 	svcA := &dep.HealthService{
 		ServiceMeta: map[string]string{
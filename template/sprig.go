@@ -0,0 +1,172 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+)
+
+// sprigPrefix is the prefix the Sprig functions are always available under.
+const sprigPrefix = "sprig_"
+
+// sprigNondeterministic are the Sprig functions that return a different result
+// on every call, given the same arguments, whose results are remembered across
+// renders. Time functions, such as now and ago, and getHostByName are not
+// remembered, since their results are expected to change; templates that need
+// to render on a schedule use the timer function instead.
+var sprigNondeterministic = map[string]bool{
+	"encryptAES":        true,
+	"genCA":             true,
+	"genPrivateKey":     true,
+	"genSelfSignedCert": true,
+	"genSignedCert":     true,
+	"randAlpha":         true,
+	"randAlphaNum":      true,
+	"randAscii":         true,
+	"randNumeric":       true,
+	"shuffle":           true,
+	"uuidv4":            true,
+}
+
+// sprigCache remembers the results of the nondeterministic Sprig functions of
+// a template from one render to the next. Otherwise every render would produce
+// different contents, which would be written to disk and trigger the
+// template's command even though none of its dependencies changed.
+type sprigCache struct {
+	sync.Mutex
+
+	// results are the results of the calls of the last successful render,
+	// keyed by a hash of the function, the call number and its arguments.
+	results map[string][]reflect.Value
+}
+
+// newSprigCache creates an empty cache.
+func newSprigCache() *sprigCache {
+	return &sprigCache{
+		results: make(map[string][]reflect.Value),
+	}
+}
+
+// sprigRender is a render of a template that uses a sprigCache. It records
+// the results of the calls of the render, which replace the results in the
+// cache if the render succeeds, so the cache only holds the results used by
+// the last render.
+type sprigRender struct {
+	cache *sprigCache
+
+	// calls counts the calls of each nondeterministic function during this
+	// render, so that separate calls with the same arguments return separate
+	// results.
+	calls   map[string]int
+	results map[string][]reflect.Value
+}
+
+// newRender starts a render that uses the cache, or returns nil if the cache
+// is nil.
+func (c *sprigCache) newRender() *sprigRender {
+	if c == nil {
+		return nil
+	}
+	return &sprigRender{
+		cache:   c,
+		calls:   make(map[string]int),
+		results: make(map[string][]reflect.Value),
+	}
+}
+
+// commit replaces the results in the cache with the results of the render.
+func (r *sprigRender) commit() {
+	if r == nil {
+		return
+	}
+	r.cache.Lock()
+	defer r.cache.Unlock()
+	r.cache.results = r.results
+}
+
+// addSprigFuncs adds the Sprig functions to the funcmap with the "sprig_"
+// prefix. If render is not nil, they are also added without the prefix, unless
+// a function of the same name exists, and the results of the nondeterministic
+// functions without the prefix are remembered across renders. It returns the
+// functions that were added without the prefix.
+func addSprigFuncs(r template.FuncMap, render *sprigRender) map[string]bool {
+	aliases := make(map[string]bool)
+
+	for k, v := range sprig.FuncMap() {
+		r[sprigPrefix+k] = v
+
+		if render == nil {
+			continue
+		}
+		if _, ok := r[k]; ok {
+			continue
+		}
+		if sprigNondeterministic[k] {
+			v = render.wrap(k, v)
+		}
+		r[k] = v
+		aliases[k] = true
+	}
+
+	return aliases
+}
+
+// wrap returns a function that returns the result remembered for the call of
+// the named function, calling fn only if there is none. Results with an error
+// are not remembered. The arguments are hashed in the key, so values such as
+// the plaintext given to encryptAES are not kept in memory.
+func (r *sprigRender) wrap(name string, fn interface{}) interface{} {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		h := sha256.New()
+		fmt.Fprintf(h, "%s#%d", name, r.calls[name])
+		for _, arg := range in {
+			fmt.Fprintf(h, ", %#v", arg.Interface())
+		}
+		key := hex.EncodeToString(h.Sum(nil))
+		r.calls[name]++
+
+		r.cache.Lock()
+		out, ok := r.cache.results[key]
+		r.cache.Unlock()
+
+		if !ok {
+			if t.IsVariadic() {
+				out = v.CallSlice(in)
+			} else {
+				out = v.Call(in)
+			}
+
+			if n := t.NumOut(); n > 0 && t.Out(n-1) == reflect.TypeOf((*error)(nil)).Elem() {
+				if !out[n-1].IsNil() {
+					return out
+				}
+			}
+		}
+		r.results[key] = out
+		return out
+	}).Interface()
+}
+
+// denySprigAlias returns the other name of the denied Sprig function, so it is
+// denied under both names, or "" if there is none.
+func denySprigAlias(name string, aliases map[string]bool) string {
+	if strings.HasPrefix(name, sprigPrefix) {
+		if alias := strings.TrimPrefix(name, sprigPrefix); aliases[alias] {
+			return alias
+		}
+		return ""
+	}
+	if aliases[name] {
+		return sprigPrefix + name
+	}
+	return ""
+}
@@ -4,10 +4,10 @@ import (
 	"bytes"
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
 	"io/ioutil"
 	"text/template"
 
-	"github.com/Masterminds/sprig"
 	dep "github.com/hashicorp/consul-template/dependency"
 	"github.com/pkg/errors"
 )
@@ -58,6 +58,17 @@ type Template struct {
 	// and causes an error if a relative path tries to traverse outside that
 	// prefix.
 	sandboxPath string
+
+	// plugins are the names of the long-running plugins.
+	plugins []string
+
+	// pluginFunctions maps the template functions provided by plugins to the
+	// names of the plugins.
+	pluginFunctions map[string]string
+
+	// sprig is the cache of the nondeterministic Sprig functions. It is only
+	// set if the Sprig functions are available without their prefix.
+	sprig *sprigCache
 }
 
 // NewTemplateInput is used as input when creating the template.
@@ -88,6 +99,19 @@ type NewTemplateInput struct {
 	// and causes an error if a relative path tries to traverse outside that
 	// prefix.
 	SandboxPath string
+
+	// Plugins are the names of the long-running plugins. The `plugin` function
+	// calls them instead of executing a command of the same name.
+	Plugins []string
+
+	// PluginFunctions maps the template functions provided by long-running
+	// plugins to the names of the plugins. They may not have the same name as
+	// a built-in function.
+	PluginFunctions map[string]string
+
+	// Sprig makes the Sprig functions available without their "sprig_"
+	// prefix. Built-in functions of the same name take precedence.
+	Sprig bool
 }
 
 // NewTemplate creates and parses a new Consul Template template at the given
@@ -106,6 +130,21 @@ func NewTemplate(i *NewTemplateInput) (*Template, error) {
 		return nil, ErrTemplateMissingContentsAndSource
 	}
 
+	var sprig *sprigCache
+	if i.Sprig {
+		sprig = newSprigCache()
+	}
+
+	if len(i.PluginFunctions) > 0 {
+		builtin := funcMap(&funcMapInput{sprig: sprig.newRender()})
+		for name, plugin := range i.PluginFunctions {
+			if _, ok := builtin[name]; ok {
+				return nil, fmt.Errorf("template: function %q of plugin %q "+
+					"conflicts with a built-in function", name, plugin)
+			}
+		}
+	}
+
 	var t Template
 	t.source = i.Source
 	t.contents = i.Contents
@@ -115,6 +154,9 @@ func NewTemplate(i *NewTemplateInput) (*Template, error) {
 	t.errFatal = i.ErrFatal
 	t.functionDenylist = i.FunctionDenylist
 	t.sandboxPath = i.SandboxPath
+	t.plugins = i.Plugins
+	t.pluginFunctions = i.PluginFunctions
+	t.sprig = sprig
 
 	if i.Source != "" {
 		contents, err := ioutil.ReadFile(i.Source)
@@ -188,6 +230,8 @@ func (t *Template) Execute(i *ExecuteInput) (*ExecuteResult, error) {
 	tmpl := template.New("")
 	tmpl.Delims(t.leftDelim, t.rightDelim)
 
+	sprig := t.sprig.newRender()
+
 	tmpl.Funcs(funcMap(&funcMapInput{
 		t:                tmpl,
 		brain:            i.Brain,
@@ -196,6 +240,9 @@ func (t *Template) Execute(i *ExecuteInput) (*ExecuteResult, error) {
 		missing:          &missing,
 		functionDenylist: t.functionDenylist,
 		sandboxPath:      t.sandboxPath,
+		plugins:          t.plugins,
+		pluginFunctions:  t.pluginFunctions,
+		sprig:            sprig,
 	}))
 
 	if t.errMissingKey {
@@ -215,6 +262,13 @@ func (t *Template) Execute(i *ExecuteInput) (*ExecuteResult, error) {
 		return nil, errors.Wrap(err, "execute")
 	}
 
+	// The results of the Sprig functions are only remembered once the
+	// template has all of its data, since a template missing data may skip
+	// some calls.
+	if missing.Len() == 0 {
+		sprig.commit()
+	}
+
 	return &ExecuteResult{
 		Used:    &used,
 		Missing: &missing,
@@ -229,6 +283,9 @@ type funcMapInput struct {
 	env              []string
 	functionDenylist []string
 	sandboxPath      string
+	plugins          []string
+	pluginFunctions  map[string]string
+	sprig            *sprigRender
 	used             *dep.Set
 	missing          *dep.Set
 }
@@ -239,24 +296,34 @@ func funcMap(i *funcMapInput) template.FuncMap {
 
 	r := template.FuncMap{
 		// API functions
-		"datacenters":  datacentersFunc(i.brain, i.used, i.missing),
-		"file":         fileFunc(i.brain, i.used, i.missing, i.sandboxPath),
-		"key":          keyFunc(i.brain, i.used, i.missing),
-		"keyExists":    keyExistsFunc(i.brain, i.used, i.missing),
-		"keyOrDefault": keyWithDefaultFunc(i.brain, i.used, i.missing),
-		"ls":           lsFunc(i.brain, i.used, i.missing, true),
-		"safeLs":       safeLsFunc(i.brain, i.used, i.missing),
-		"node":         nodeFunc(i.brain, i.used, i.missing),
-		"nodes":        nodesFunc(i.brain, i.used, i.missing),
-		"secret":       secretFunc(i.brain, i.used, i.missing),
-		"secrets":      secretsFunc(i.brain, i.used, i.missing),
-		"service":      serviceFunc(i.brain, i.used, i.missing),
-		"connect":      connectFunc(i.brain, i.used, i.missing),
-		"services":     servicesFunc(i.brain, i.used, i.missing),
-		"tree":         treeFunc(i.brain, i.used, i.missing, true),
-		"safeTree":     safeTreeFunc(i.brain, i.used, i.missing),
-		"caRoots":      connectCARootsFunc(i.brain, i.used, i.missing),
-		"caLeaf":       connectLeafFunc(i.brain, i.used, i.missing),
+		"datacenters":    datacentersFunc(i.brain, i.used, i.missing),
+		"dir":            dirFunc(i.brain, i.used, i.missing, i.sandboxPath),
+		"dnsA":           dnsStringsFunc(i.brain, i.used, i.missing, dep.NewDNSAQuery),
+		"dnsAAAA":        dnsStringsFunc(i.brain, i.used, i.missing, dep.NewDNSAAAAQuery),
+		"dnsSRV":         dnsSRVFunc(i.brain, i.used, i.missing),
+		"dnsTXT":         dnsStringsFunc(i.brain, i.used, i.missing, dep.NewDNSTXTQuery),
+		"envFile":        envFileFunc(i.brain, i.used, i.missing, i.sandboxPath),
+		"file":           fileFunc(i.brain, i.used, i.missing, i.sandboxPath),
+		"files":          filesFunc(i.brain, i.used, i.missing, i.sandboxPath),
+		"http":           httpFunc(i.brain, i.used, i.missing),
+		"key":            keyFunc(i.brain, i.used, i.missing),
+		"keyExists":      keyExistsFunc(i.brain, i.used, i.missing),
+		"keyOrDefault":   keyWithDefaultFunc(i.brain, i.used, i.missing),
+		"keyPutIfAbsent": keyPutIfAbsentFunc(i.brain, i.used, i.missing),
+		"ls":             lsFunc(i.brain, i.used, i.missing, true),
+		"safeLs":         safeLsFunc(i.brain, i.used, i.missing),
+		"node":           nodeFunc(i.brain, i.used, i.missing),
+		"nodes":          nodesFunc(i.brain, i.used, i.missing),
+		"secret":         secretFunc(i.brain, i.used, i.missing),
+		"secrets":        secretsFunc(i.brain, i.used, i.missing),
+		"service":        serviceFunc(i.brain, i.used, i.missing),
+		"connect":        connectFunc(i.brain, i.used, i.missing),
+		"services":       servicesFunc(i.brain, i.used, i.missing),
+		"tree":           treeFunc(i.brain, i.used, i.missing, true),
+		"safeTree":       safeTreeFunc(i.brain, i.used, i.missing),
+		"caRoots":        connectCARootsFunc(i.brain, i.used, i.missing),
+		"caLeaf":         connectLeafFunc(i.brain, i.used, i.missing),
+		"timer":          timerFunc(i.brain, i.used, i.missing),
 
 		// Scratch
 		"scratch": func() *Scratch { return &scratch },
@@ -286,12 +353,16 @@ func funcMap(i *funcMapInput) template.FuncMap {
 		"join":                  join,
 		"trimSpace":             trimSpace,
 		"parseBool":             parseBool,
+		"parseCert":             parseCert,
+		"parseCertChain":        parseCertChain,
 		"parseFloat":            parseFloat,
 		"parseInt":              parseInt,
 		"parseJSON":             parseJSON,
+		"parsePrivateKey":       parsePrivateKey,
+		"parseTime":             parseTime,
 		"parseUint":             parseUint,
 		"parseYAML":             parseYAML,
-		"plugin":                plugin,
+		"plugin":                pluginFunc(i.brain, i.used, i.missing, i.plugins),
 		"regexReplaceAll":       regexReplaceAll,
 		"regexMatch":            regexMatch,
 		"replaceAll":            replaceAll,
@@ -321,22 +392,27 @@ func funcMap(i *funcMapInput) template.FuncMap {
 		"minimum":  minimum,
 		"maximum":  maximum,
 		// Debug functions
-		"spew_dump":    spewDump,
-		"spew_printf":  spewPrintf,
+		"spew_dump":    spewDumpFunc(i.brain),
+		"spew_printf":  spewPrintfFunc(i.brain),
 		"spew_sdump":   spewSdump,
 		"spew_sprintf": spewSprintf,
 	}
 
 	// Add the Sprig functions to the funcmap
-	for k, v := range sprig.FuncMap() {
-		target := "sprig_" + k
-		r[target] = v
+	aliases := addSprigFuncs(r, i.sprig)
+
+	// Add the functions provided by plugins
+	for name, plugin := range i.pluginFunctions {
+		r[name] = pluginFunctionFunc(i.brain, i.used, i.missing, plugin, name)
 	}
 
 	for _, bf := range i.functionDenylist {
 		if _, ok := r[bf]; ok {
 			r[bf] = denied
 		}
+		if alias := denySprigAlias(bf, aliases); alias != "" {
+			r[alias] = denied
+		}
 	}
 
 	return r
@@ -59,6 +59,25 @@ func TestNewTemplate(t *testing.T) {
 			nil,
 			true,
 		},
+		{
+			"plugin_function_conflict",
+			&NewTemplateInput{
+				Contents:        "test",
+				PluginFunctions: map[string]string{"key": "etcd"},
+			},
+			nil,
+			true,
+		},
+		{
+			"plugin_function_sprig_conflict",
+			&NewTemplateInput{
+				Contents:        "test",
+				PluginFunctions: map[string]string{"default": "etcd"},
+				Sprig:           true,
+			},
+			nil,
+			true,
+		},
 		{
 			"sets_contents_from_source",
 			&NewTemplateInput{
@@ -323,6 +342,109 @@ func TestTemplate_Execute(t *testing.T) {
 			"content",
 			false,
 		},
+		{
+			"func_envFile",
+			&NewTemplateInput{
+				Contents: `{{ with envFile "/run/app.env" }}{{ .DB_HOST }}:{{ index . "DB_PORT" }}{{ end }}`,
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewEnvFileQuery("/run/app.env")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, map[string]string{"DB_HOST": "db", "DB_PORT": "5432"})
+					return b
+				}(),
+			},
+			"db:5432",
+			false,
+		},
+		{
+			"func_files",
+			&NewTemplateInput{
+				Contents: `{{ range files "/etc/app/conf.d/*.json" }}{{ .Name }}={{ .Contents }};{{ end }}`,
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewFilesQuery("/etc/app/conf.d/*.json")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.File{
+						{Name: "a.json", Path: "/etc/app/conf.d/a.json", Contents: "{}"},
+						{Name: "b.json", Path: "/etc/app/conf.d/b.json", Contents: "[]"},
+					})
+					return b
+				}(),
+			},
+			"a.json={};b.json=[];",
+			false,
+		},
+		{
+			"func_files_sandbox_removed",
+			&NewTemplateInput{
+				Contents:    `{{ range files "/sandbox/*.json" }}{{ .Name }};{{ end }}`,
+				SandboxPath: "/sandbox",
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewFilesQuery("/sandbox/*.json")
+					if err != nil {
+						t.Fatal(err)
+					}
+					d.SetSandboxPath("/sandbox")
+					// The file no longer exists, which is not an error
+					// until the dependency lists the files again.
+					b.Remember(d, []*dep.File{
+						{Name: "a.json", Path: "/sandbox/a.json", Contents: "{}"},
+					})
+					return b
+				}(),
+			},
+			"a.json;",
+			false,
+		},
+		{
+			"func_files_outside_sandbox",
+			&NewTemplateInput{
+				Contents:    `{{ files "/etc/*" }}`,
+				SandboxPath: "/sandbox",
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"func_dir",
+			&NewTemplateInput{
+				Contents: `{{ range dir "/etc/app/conf.d/" }}{{ .Path }} {{ .ModTime.Year }}{{ end }}`,
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewDirQuery("/etc/app/conf.d")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.File{
+						{
+							Name:    "a.conf",
+							Path:    "/etc/app/conf.d/a.conf",
+							ModTime: time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC),
+						},
+					})
+					return b
+				}(),
+			},
+			"/etc/app/conf.d/a.conf 2021",
+			false,
+		},
 		{
 			"func_key",
 			&NewTemplateInput{
@@ -381,6 +503,37 @@ func TestTemplate_Execute(t *testing.T) {
 			"150 200",
 			false,
 		},
+		{
+			"func_keyPutIfAbsent",
+			&NewTemplateInput{
+				Contents: `{{ keyPutIfAbsent "key" "generated" }}`,
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewKVPutIfAbsentQuery("key", "ignored")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, "existing")
+					return b
+				}(),
+			},
+			"existing",
+			false,
+		},
+		{
+			"func_keyPutIfAbsent_disabled",
+			&NewTemplateInput{
+				Contents:         `{{ keyPutIfAbsent "key" "generated" }}`,
+				FunctionDenylist: []string{"keyPutIfAbsent"},
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
 		{
 			"func_ls",
 			&NewTemplateInput{
@@ -1510,6 +1663,17 @@ func TestTemplate_Execute(t *testing.T) {
 			"map[foo:bar]",
 			false,
 		},
+		{
+			"helper_parseTime",
+			&NewTemplateInput{
+				Contents: `{{ (parseTime "2006-01-02" "2021-03-10").Weekday }} {{ (parseTime "unix" "86400").Format "2006-01-02" }}`,
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"Wednesday 1970-01-02",
+			false,
+		},
 		{
 			"helper_parseUint",
 			&NewTemplateInput{
@@ -1565,6 +1729,59 @@ func TestTemplate_Execute(t *testing.T) {
 			"1",
 			false,
 		},
+		{
+			"helper_plugin_long_running",
+			&NewTemplateInput{
+				Contents: `{{ "1" | plugin "lookup" "a" }}`,
+				Plugins:  []string{"lookup"},
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewPluginQuery("lookup", []string{"a", "1"})
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, "result")
+					return b
+				}(),
+			},
+			"result",
+			false,
+		},
+		{
+			"helper_plugin_function",
+			&NewTemplateInput{
+				Contents:        `{{ with etcdKey "app/db" }}{{ .host }}:{{ .port }}{{ end }}`,
+				PluginFunctions: map[string]string{"etcdKey": "etcd"},
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewPluginFunctionQuery("etcd", "etcdKey", []string{"app/db"})
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, map[string]interface{}{"host": "db", "port": 5432.0})
+					return b
+				}(),
+			},
+			"db:5432",
+			false,
+		},
+		{
+			"helper_plugin_function_disabled",
+			&NewTemplateInput{
+				Contents:         `{{ etcdKey "app/db" }}`,
+				PluginFunctions:  map[string]string{"etcdKey": "etcd"},
+				FunctionDenylist: []string{"etcdKey"},
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
 		{
 			"helper_plugin_disabled",
 			&NewTemplateInput{
@@ -1900,6 +2117,148 @@ func TestTemplate_Execute(t *testing.T) {
 			"PEM",
 			false,
 		},
+		{
+			"func_timer",
+			&NewTemplateInput{
+				Contents: `{{ with timer "1h" }}{{ if lt .Hour 4 }}maintenance{{ else }}{{ .Hour }}{{ end }}{{ end }}`,
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewTimerQuery("1h")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, time.Date(2021, 3, 10, 2, 0, 0, 0, time.UTC))
+					return b
+				}(),
+			},
+			"maintenance",
+			false,
+		},
+		{
+			"func_timer_invalid",
+			&NewTemplateInput{
+				Contents: `{{ timer "sometimes" }}`,
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"func_dnsA",
+			&NewTemplateInput{
+				Contents: `{{ range dnsA "db.example.com" }}{{ . }},{{ end }}{{ dnsAAAA "db.example.com" }}`,
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewDNSAQuery("db.example.com")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []string{"10.0.0.1", "10.0.0.2"})
+					return b
+				}(),
+			},
+			"10.0.0.1,10.0.0.2,[]",
+			false,
+		},
+		{
+			"func_dnsSRV",
+			&NewTemplateInput{
+				Contents: `{{ range dnsSRV "_ldap._tcp.example.com" }}{{ .Target }}:{{ .Port }},{{ end }}`,
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewDNSSRVQuery("_ldap._tcp.example.com")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []*dep.DNSSRV{
+						{Target: "ldap1.example.com.", Port: 389, Priority: 10, Weight: 10},
+						{Target: "ldap2.example.com.", Port: 636, Priority: 20, Weight: 10},
+					})
+					return b
+				}(),
+			},
+			"ldap1.example.com.:389,ldap2.example.com.:636,",
+			false,
+		},
+		{
+			"func_dnsTXT",
+			&NewTemplateInput{
+				Contents: `{{ range dnsTXT "example.com" }}{{ . }};{{ end }}`,
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewDNSTXTQuery("example.com")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, []string{"v=spf1 -all"})
+					return b
+				}(),
+			},
+			"v=spf1 -all;",
+			false,
+		},
+		{
+			"func_dns_invalid",
+			&NewTemplateInput{
+				Contents: `{{ dnsA "a..example.com" }}`,
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"func_http",
+			&NewTemplateInput{
+				Contents: `{{ with http "https://flags.example.com/v1" "format=json" }}{{ if .beta }}beta{{ end }}{{ end }}`,
+			},
+			&ExecuteInput{
+				Brain: func() *Brain {
+					b := NewBrain()
+					d, err := dep.NewHTTPQuery("https://flags.example.com/v1", "format=json")
+					if err != nil {
+						t.Fatal(err)
+					}
+					b.Remember(d, map[string]interface{}{"beta": true})
+					return b
+				}(),
+			},
+			"beta",
+			false,
+		},
+		{
+			"func_http_missing",
+			&NewTemplateInput{
+				Contents: `{{ (http "https://flags.example.com/v1" "format=json").beta }}{{ http "https://example.com" }}`,
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"<no value>",
+			false,
+		},
+		{
+			"func_http_invalid",
+			&NewTemplateInput{
+				Contents: `{{ http "flags.example.com" }}`,
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
 		{
 			"func_connect",
 			&NewTemplateInput{
@@ -1928,6 +2287,67 @@ func TestTemplate_Execute(t *testing.T) {
 			"1.2.3.45.6.7.8",
 			false,
 		},
+		{
+			"sprig",
+			&NewTemplateInput{
+				Contents: `{{ list "b" "a" "b" | uniq | sortAlpha | sprig_join "," }} {{ "" | default "x" }} {{ "v1" | trimPrefix "v" }}`,
+				Sprig:    true,
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"a,b x 1",
+			false,
+		},
+		{
+			"sprig_builtin_precedence",
+			&NewTemplateInput{
+				Contents: `{{ "a,b" | split "," | join ";" }}`,
+				Sprig:    true,
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"a;b",
+			false,
+		},
+		{
+			"sprig_disabled",
+			&NewTemplateInput{
+				Contents: `{{ "" | default "x" }}`,
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"sprig_denied",
+			&NewTemplateInput{
+				Contents:         `{{ "" | default "x" }}`,
+				Sprig:            true,
+				FunctionDenylist: []string{"sprig_default"},
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
+		{
+			"sprig_denied_prefixed",
+			&NewTemplateInput{
+				Contents:         `{{ "" | sprig_default "x" }}`,
+				Sprig:            true,
+				FunctionDenylist: []string{"default"},
+			},
+			&ExecuteInput{
+				Brain: NewBrain(),
+			},
+			"",
+			true,
+		},
 		{
 			"spew_sdump_simple_output",
 			&NewTemplateInput{
@@ -1978,6 +2398,46 @@ func TestTemplate_Execute(t *testing.T) {
 	}
 }
 
+func TestTemplate_Execute_sprigNondeterministic(t *testing.T) {
+	tpl, err := NewTemplate(&NewTemplateInput{
+		Contents: `{{ uuidv4 }} {{ uuidv4 }} {{ randAlpha 8 }} {{ sprig_randAlpha 8 }} {{ now.UnixNano }}`,
+		Sprig:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := tpl.Execute(&ExecuteInput{Brain: NewBrain()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := bytes.Fields(first.Output)
+	if bytes.Equal(parts[0], parts[1]) {
+		t.Errorf("expected separate calls to differ: %s", first.Output)
+	}
+
+	second, err := tpl.Execute(&ExecuteInput{Brain: NewBrain()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondParts := bytes.Fields(second.Output)
+	if !bytes.Equal(bytes.Join(parts[:3], nil), bytes.Join(secondParts[:3], nil)) {
+		t.Errorf("expected the same output across renders\nfirst:  %s\nsecond: %s",
+			first.Output, second.Output)
+	}
+	if bytes.Equal(parts[3], secondParts[3]) {
+		t.Errorf("expected prefixed functions not to be remembered: %s", second.Output)
+	}
+	if bytes.Equal(parts[4], secondParts[4]) {
+		t.Errorf("expected now not to be remembered: %s", second.Output)
+	}
+
+	// Only the results of the last render are kept.
+	if n := len(tpl.sprig.results); n != 3 {
+		t.Errorf("expected 3 remembered results, got %d", n)
+	}
+}
+
 func Test_writeToFile(t *testing.T) {
 	// Use current user and its primary group for input
 	currentUser, err := user.Current()